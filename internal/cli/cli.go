@@ -2,22 +2,41 @@ package cli
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"io"
 	"log"
+	"net"
+	"net/http"
 	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+	"time"
 
+	"github.com/d-iii-s/slsbench/internal/config"
+	"github.com/d-iii-s/slsbench/internal/service/activator"
 	"github.com/d-iii-s/slsbench/internal/service/bodyprobe"
+	"github.com/d-iii-s/slsbench/internal/service/compare"
 	"github.com/d-iii-s/slsbench/internal/service/dslvalidator"
 	"github.com/d-iii-s/slsbench/internal/service/harness"
+	"github.com/d-iii-s/slsbench/internal/service/report"
+	"github.com/d-iii-s/slsbench/internal/service/runstats"
 	"github.com/d-iii-s/slsbench/internal/utils"
 	"github.com/spf13/cobra"
-	"gopkg.in/yaml.v3"
+	"github.com/spf13/pflag"
 )
 
 var rootCmd = &cobra.Command{
 	Use:   "slsbench",
 	Short: "Serverless Benchmarking Tool",
-	Long:  `Serverless Benchmarking Tool - A comprehensive framework for scenario-based performance evaluation of serverless and containerized HTTP workloads.`,
+	Long: `Serverless Benchmarking Tool - A comprehensive framework for scenario-based performance evaluation of serverless and containerized HTTP workloads.
+
+Flags can also be set in a slsbench.yaml project file (keys are flag names,
+with optional named profiles) and through SLSBENCH_<FLAG_NAME> environment
+variables. Explicit flags win over environment variables, which win over the
+selected profile, which wins over top-level settings in the file.`,
+	PersistentPreRunE: applyProjectConfig,
 }
 
 var harnessCmd = &cobra.Command{
@@ -31,7 +50,33 @@ This command orchestrates the benchmark execution by:
 - Measuring first successful response time
 - Running wrk2-flow workloads per flow step
 - Copying an optional mounted path from the service container to results
-- Cleaning up resources`,
+- Cleaning up resources
+
+With --repetitions N (or any --variant) every variant is run N times, each
+time against a freshly created compose project, and aggregate.json in the
+result directory reports means, medians and bootstrap confidence intervals
+per stage metric and for time-to-first-response. --interleave alternates the
+variants within each round so slow drift of the host hits all of them alike.
+
+With --activator the stages reach the service through a scale-to-zero
+activator proxy that pauses (or stops) the service after
+--activator-idle-timeout without requests, holds new requests while it
+resumes it and caps forwarded requests at --activator-max-concurrency.
+Stage and run summaries then report scale-from-zero latency and queueing
+delay.
+
+Docker events of the compose containers are recorded in docker-events.jsonl.
+An oom, die (non-zero exit) or unhealthy event marks the stage it happened
+in invalid; --event-policy <event>=<ignore|invalidate|abort> changes that,
+e.g. --event-policy oom=abort stops the run on the first OOM kill.
+
+--executor native replaces the wrk2-flow container with an open-loop Go
+executor in the harness process. It replays the same iterations at the
+rate of the stage load profile, corrects latency for coordinated omission
+and writes a wrk2-style report, which makes replay problems easier to debug.
+Rate segments and a warmup in a load block need it. Only the native executor
+writes operations.json, the per-node breakdown of each stage; wrk2-flow
+reports stage totals.`,
 	Example: `  slsbench harness \
     --flow-path ./flow.yaml \
     --probe-bodies-path ./probe-bodies-result-2026-04-03T14-45-00 \
@@ -41,7 +86,14 @@ This command orchestrates the benchmark execution by:
     --port 9966 \
     --result-path ./result \
     --service-mount-path /var/log/app \
-    --docker-socket-path /var/run/docker.sock`,
+    --docker-socket-path /var/run/docker.sock
+
+  slsbench harness -f ./flow.yaml -b ./probe-bodies -o ./openapi.yml -n petclinic \
+    --variant jit=./compose-jit.yml --variant native=./compose-native.yml \
+    --repetitions 5 --interleave
+
+  slsbench harness -f ./flow.yaml -b ./probe-bodies -o ./openapi.yml -d ./docker-compose.yml -n petclinic \
+    --activator --activator-idle-timeout 10s --activator-max-concurrency 4`,
 	RunE: runHarness,
 }
 
@@ -53,19 +105,208 @@ them against the running application, then persist accepted chain artifacts.`,
 	RunE: runProbeBodies,
 }
 
+var validateCmd = &cobra.Command{
+	Use:   "validate",
+	Short: "Validate a flow DSL file without starting Docker",
+	Long: `Validate a flow DSL file against the JSON Schema and run semantic checks
+on every stage graph:
+- edges pointing to unknown nodes
+- missing entry nodes, entryweight on non-entry nodes and non-positive entryweight
+- nodes unreachable from every entry node
+- cycles with no exit, exitweight, maxvisits or maxtraversals
+- non-positive edge weights
+- load profiles that set both load and wrk2params or send no requests
+
+When an OpenAPI spec is given, operationIds missing from the spec and edges
+without a matching OpenAPI Link are reported as well. Every finding carries
+the YAML line and column it refers to.`,
+	Example: `  slsbench validate --flow-path ./flow.yaml --openapi-spec-path ./openapi.yml
+  slsbench validate -f ./flow.yaml --format github`,
+	RunE: runValidate,
+}
+
+var runCmd = &cobra.Command{
+	Use:   "run",
+	Short: "Probe bodies and run the harness in one step",
+	Long: `Run probe-bodies and harness back to back and keep both outputs under one
+run directory (<result-path>/run-result-<timestamp>).
+
+The freshly written probe-bodies-result-* directory is located automatically
+and passed to the harness. By default each phase gets its own compose
+project, so the harness measures first-response time against a freshly
+started service. With --reuse-compose a single compose project is started
+once and shared by both phases.`,
+	Example: `  slsbench run \
+    --flow-path ./flow.yaml \
+    --openapi-spec-path ./openapi.yml \
+    --docker-compose-path ./docker-compose.yml \
+    --service-name petclinic \
+    --port 9966 \
+    --result-path ./results`,
+	RunE: runRun,
+}
+
+var reportCmd = &cobra.Command{
+	Use:   "report <harness-result-dir>",
+	Short: "Render a harness result directory into a self-contained HTML report",
+	Long: `Render one offline HTML file from a harness result directory: run
+parameters, time to first response, per-stage latency percentile curves and
+the service CPU, memory and network time series with stage boundaries marked.
+
+The argument may also be a run-result-* directory written by 'slsbench run';
+its harness result is used. The report is written to report.html inside the
+harness result directory unless --output is given.`,
+	Example: `  slsbench report ./results/harness-result-2026-04-10-14:30:00
+  slsbench report ./results/run-result-2026-04-10-14:29:12 -o ./petclinic-vs-quarkus.html`,
+	Args: cobra.ExactArgs(1),
+	RunE: runReport,
+}
+
+var compareCmd = &cobra.Command{
+	Use:   "compare <baseline-dir> <candidate-dir>",
+	Short: "Compare baseline and candidate harness results and gate on regressions",
+	Long: `Compare two sets of harness results. Each argument is searched for harness
+result directories, so a side can hold several repetitions (for example one
+run-result-* directory per repetition). Stages are lined up by name and the
+medians of latency percentiles, throughput, error rate, first-response time
+and service CPU/memory are compared.
+
+Threshold rules (--threshold) such as p99=+10% or rps=-5% decide the exit
+code. A rule only fails when the median moved past the limit and a two-sided
+Mann-Whitney U test finds the sides different at --alpha, so noise between
+repetitions is not reported as a regression. With too few repetitions for the
+test to ever reach --alpha, the threshold decides alone and the finding is
+marked regression-unverified.
+
+Exit codes: 0 no regression, 3 regression, 1 any other error.`,
+	Example: `  slsbench compare ./results/spring ./results/quarkus -t p99=+10% -t rps=-5%
+  slsbench compare ./baseline ./candidate -t first-response=+500ms --format json`,
+	Args: cobra.ExactArgs(2),
+	RunE: runCompare,
+}
+
+var coldStartCmd = &cobra.Command{
+	Use:   "cold-start",
+	Short: "Measure the distribution of service start-up and first-request latency",
+	Long: `Start the compose project once, then stop and start (or recreate) the
+service container --iterations times. Every cycle records:
+- create: removing and creating the container (--mode recreate only)
+- start: the container start call
+- first-2xx: from the started container to the first 2xx response on the
+  readiness path
+- total: from the first container operation to the first 2xx response
+- request-1 ... request-K: the latency of each of the first --first-requests
+  requests of the flow stage, replayed from the probe-bodies iterations
+
+cold-start.json holds every sample and a min/mean/p50/p90/p99/max summary
+per phase.`,
+	Example: `  slsbench cold-start \
+    --flow-path ./flow.yaml \
+    --probe-bodies-path ./probe-bodies-result-2026-04-03T14-45-00 \
+    --openapi-spec-path ./openapi.yml \
+    --docker-compose-path ./docker-compose.yml \
+    --service-name petclinic \
+    --port 9966 \
+    --iterations 30 --mode recreate --first-requests 5`,
+	RunE: runColdStart,
+}
+
+var activatorCmd = &cobra.Command{
+	Use:    "activator",
+	Short:  "Scale-to-zero proxy in front of a service container (started by harness --activator)",
+	Hidden: true,
+	Args:   cobra.NoArgs,
+	RunE:   runActivator,
+}
+
 var (
+	// Global flags
+	configPath    string
+	configProfile string
+
+	// Run flags
+	runFlowPath          string
+	runOpenAPISpecPath   string
+	runDockerComposePath string
+	runServiceName       string
+	runPort              int
+	runResultPath        string
+	runDockerSocketPath  string
+	runReadinessPath     string
+	runServiceMountPaths []string
+	runDebugNon2xx       bool
+	runDebug             bool
+	runNoRewriteLinked   bool
+	runMaxProbeTarget    int
+	runReuseCompose      bool
+	runExecutor          string
+	runSeriesInterval    time.Duration
+	runEventPolicies     []string
+
+	// Cold-start flags
+	coldStartFlowPath          string
+	coldStartProbeBodiesPath   string
+	coldStartOpenAPISpecPath   string
+	coldStartDockerComposePath string
+	coldStartServiceName       string
+	coldStartPort              int
+	coldStartResultPath        string
+	coldStartDockerSocketPath  string
+	coldStartReadinessPath     string
+	coldStartIterations        int
+	coldStartMode              string
+	coldStartFirstRequests     int
+	coldStartStage             string
+
+	// Report flags
+	reportOutputPath string
+
+	// Compare flags
+	compareThresholds []string
+	compareAlpha      float64
+	compareFormat     string
+
+	// Validate flags
+	validateFlowPath    string
+	validateOpenAPIPath string
+	validateFormat      string
+	validateStrict      bool
+
 	// Harness flags
-	harnessFlowPath          string
-	harnessProbeBodiesPath   string
-	openApiSpecPath          string
-	harnessPort              int
-	harnessResultPath        string
-	harnessDockerComposePath string
-	harnessServiceName       string
-	harnessServiceMountPaths []string
-	harnessDockerSocketPath  string
-	harnessDebugNon2xx       bool
-	harnessReadinessPath     string
+	harnessFlowPath           string
+	harnessProbeBodiesPath    string
+	openApiSpecPath           string
+	harnessPort               int
+	harnessResultPath         string
+	harnessDockerComposePath  string
+	harnessServiceName        string
+	harnessServiceMountPaths  []string
+	harnessDockerSocketPath   string
+	harnessDebugNon2xx        bool
+	harnessReadinessPath      string
+	harnessRepetitions        int
+	harnessVariants           []string
+	harnessInterleave         bool
+	harnessBootstrapResamples int
+	harnessActivator          bool
+	harnessActivatorImage     string
+	harnessActivatorMode      string
+	harnessActivatorIdle      time.Duration
+	harnessActivatorMaxConc   int
+	harnessEventPolicies      []string
+	harnessExecutor           string
+	harnessSeriesInterval     time.Duration
+
+	// Activator flags
+	activatorListen         string
+	activatorUpstream       string
+	activatorContainerID    string
+	activatorMode           string
+	activatorIdleTimeout    time.Duration
+	activatorMaxConcurrency int
+	activatorReadinessPath  string
+	activatorEventsPath     string
+	activatorDockerSocket   string
 
 	// Probe command flags
 	probeFlowPath          string
@@ -82,6 +323,10 @@ var (
 )
 
 func init() {
+	// Global flags
+	rootCmd.PersistentFlags().StringVar(&configPath, "config", "", "Path to the project config file (default ./"+config.DefaultFileName+" when present)")
+	rootCmd.PersistentFlags().StringVar(&configProfile, "profile", "", "Named profile from the project config file to apply")
+
 	// Harness flags
 	harnessCmd.Flags().StringVarP(&harnessFlowPath, "flow-path", "f", "", "Path to the flow DSL YAML file")
 	if err := harnessCmd.MarkFlagRequired("flow-path"); err != nil {
@@ -102,10 +347,7 @@ func init() {
 
 	harnessCmd.Flags().StringVarP(&harnessResultPath, "result-path", "r", "./result", "Path to save the results")
 
-	harnessCmd.Flags().StringVarP(&harnessDockerComposePath, "docker-compose-path", "d", "", "Path to the docker-compose.yml file")
-	if err := harnessCmd.MarkFlagRequired("docker-compose-path"); err != nil {
-		log.Fatalf("Failed to mark --docker-compose-path as required: %v", err)
-	}
+	harnessCmd.Flags().StringVarP(&harnessDockerComposePath, "docker-compose-path", "d", "", "Path to the docker-compose.yml file (required unless --variant is given)")
 
 	harnessCmd.Flags().StringVarP(&harnessServiceName, "service-name", "n", "", "Service name in the docker-compose file to benchmark (required)")
 	if err := harnessCmd.MarkFlagRequired("service-name"); err != nil {
@@ -116,6 +358,34 @@ func init() {
 	harnessCmd.Flags().StringVar(&harnessDockerSocketPath, "docker-socket-path", "/var/run/docker.sock", "Path to Docker socket for DooD mode")
 	harnessCmd.Flags().BoolVar(&harnessDebugNon2xx, "debug-non2xx", false, "Enable FLOW_DEBUG_NON2XX=1 in wrk2 container for non-2xx debug capture")
 	harnessCmd.Flags().StringVar(&harnessReadinessPath, "readiness-path", "", "Explicit readiness probe path (auto-derived from OpenAPI if empty)")
+	harnessCmd.Flags().IntVar(&harnessRepetitions, "repetitions", 1, "Number of runs per variant, each against a freshly created compose project")
+	harnessCmd.Flags().StringSliceVar(&harnessVariants, "variant", []string{}, "Variant under test as <name>=<docker-compose-path> (repeat flag for several)")
+	harnessCmd.Flags().BoolVar(&harnessInterleave, "interleave", false, "Alternate variants within each repetition round instead of running them one after another")
+	harnessCmd.Flags().IntVar(&harnessBootstrapResamples, "bootstrap-resamples", runstats.DefaultResamples, "Bootstrap resamples behind the aggregate confidence intervals")
+	harnessCmd.Flags().BoolVar(&harnessActivator, "activator", false, "Route the stages through the scale-to-zero activator proxy")
+	harnessCmd.Flags().StringVar(&harnessActivatorImage, "activator-image", "", "Image that runs the activator, built from this repository (required with --activator)")
+	harnessCmd.Flags().StringVar(&harnessActivatorMode, "activator-mode", activator.ModePause, "How the activator scales the service to zero: pause or stop")
+	harnessCmd.Flags().DurationVar(&harnessActivatorIdle, "activator-idle-timeout", activator.DefaultIdleTimeout, "Idle period after which the activator scales the service to zero")
+	harnessCmd.Flags().IntVar(&harnessActivatorMaxConc, "activator-max-concurrency", 0, "Requests the activator forwards at once; the excess is queued (0 = unlimited)")
+	harnessCmd.Flags().StringVar(&harnessExecutor, "executor", harness.ExecutorWrk2Flow, "Load generator of the stages: wrk2-flow (container on the compose network) or native (Go executor in this process; the only one writing the per-node operations.json)")
+	harnessCmd.Flags().DurationVar(&harnessSeriesInterval, "series-interval", 0, "Interval of the per-stage throughput and latency time series (series.jsonl, default 1s); only --executor native writes a series")
+	harnessCmd.Flags().StringSliceVar(&harnessEventPolicies, "event-policy", []string{}, "Reaction to a Docker event as <oom|die|unhealthy>=<ignore|invalidate|abort> (repeat flag for several; default invalidate)")
+
+	// Activator flags
+	activatorCmd.Flags().StringVar(&activatorListen, "listen", ":8080", "Address to accept requests on")
+	activatorCmd.Flags().StringVar(&activatorUpstream, "upstream", "", "Base URL of the service, e.g. http://petclinic:9966")
+	activatorCmd.Flags().StringVar(&activatorContainerID, "container-id", "", "ID of the service container to scale")
+	for _, name := range []string{"upstream", "container-id"} {
+		if err := activatorCmd.MarkFlagRequired(name); err != nil {
+			log.Fatalf("Failed to mark --%s as required: %v", name, err)
+		}
+	}
+	activatorCmd.Flags().StringVar(&activatorMode, "mode", activator.ModePause, "How to scale the service to zero: pause or stop")
+	activatorCmd.Flags().DurationVar(&activatorIdleTimeout, "idle-timeout", activator.DefaultIdleTimeout, "Idle period before scaling to zero")
+	activatorCmd.Flags().IntVar(&activatorMaxConcurrency, "max-concurrency", 0, "Requests forwarded at once (0 = unlimited)")
+	activatorCmd.Flags().StringVar(&activatorReadinessPath, "readiness-path", "/", "Path polled after a scale-up until it answers below 500")
+	activatorCmd.Flags().StringVar(&activatorEventsPath, "events-path", "", "JSON lines file to append events to (default: discard)")
+	activatorCmd.Flags().StringVar(&activatorDockerSocket, "docker-socket-path", "/var/run/docker.sock", "Path to Docker socket")
 
 	// Probe-bodies flags
 	probeBodiesCmd.Flags().StringVarP(&probeFlowPath, "flow-path", "f", "", "Path to flow DSL YAML file")
@@ -158,44 +428,262 @@ func init() {
 	probeBodiesCmd.Flags().StringVar(&probeReadinessPath, "readiness-path", "", "Explicit readiness probe path (auto-derived from OpenAPI if empty)")
 	probeBodiesCmd.Flags().IntVar(&probeMaxTarget, "max-probe-target", 0, "Cap the number of generated iterations per stage (0 = unlimited)")
 
+	// Validate flags
+	validateCmd.Flags().StringVarP(&validateFlowPath, "flow-path", "f", "", "Path to the flow DSL YAML file")
+	if err := validateCmd.MarkFlagRequired("flow-path"); err != nil {
+		log.Fatalf("Failed to mark --flow-path as required: %v", err)
+	}
+	validateCmd.Flags().StringVarP(&validateOpenAPIPath, "openapi-spec-path", "o", "", "Optional OpenAPI spec file or URL to check operationIds and links against")
+	validateCmd.Flags().StringVar(&validateFormat, "format", "text", "Output format: text, github (workflow annotations) or json")
+	validateCmd.Flags().BoolVar(&validateStrict, "strict", false, "Treat warnings as failures")
+
+	// Run flags
+	runCmd.Flags().StringVarP(&runFlowPath, "flow-path", "f", "", "Path to the flow DSL YAML file")
+	if err := runCmd.MarkFlagRequired("flow-path"); err != nil {
+		log.Fatalf("Failed to mark --flow-path as required: %v", err)
+	}
+	runCmd.Flags().StringVarP(&runOpenAPISpecPath, "openapi-spec-path", "o", "", "Path to the OpenAPI spec file")
+	if err := runCmd.MarkFlagRequired("openapi-spec-path"); err != nil {
+		log.Fatalf("Failed to mark --openapi-spec-path as required: %v", err)
+	}
+	runCmd.Flags().StringVarP(&runDockerComposePath, "docker-compose-path", "d", "", "Path to the docker-compose.yml file")
+	if err := runCmd.MarkFlagRequired("docker-compose-path"); err != nil {
+		log.Fatalf("Failed to mark --docker-compose-path as required: %v", err)
+	}
+	runCmd.Flags().StringVarP(&runServiceName, "service-name", "n", "", "Service name in the docker-compose file to benchmark")
+	if err := runCmd.MarkFlagRequired("service-name"); err != nil {
+		log.Fatalf("Failed to mark --service-name as required: %v", err)
+	}
+	runCmd.Flags().IntVarP(&runPort, "port", "p", 8080, "Application service port inside docker network")
+	runCmd.Flags().StringVarP(&runResultPath, "result-path", "r", "./result", "Base path for the run directory")
+	runCmd.Flags().StringVar(&runDockerSocketPath, "docker-socket-path", "/var/run/docker.sock", "Path to Docker socket for DooD mode")
+	runCmd.Flags().StringVar(&runReadinessPath, "readiness-path", "", "Explicit readiness probe path (auto-derived from OpenAPI if empty)")
+	runCmd.Flags().StringSliceVarP(&runServiceMountPaths, "service-mount-path", "m", []string{}, "Optional paths inside service container to copy to results (repeat flag or use comma-separated values)")
+	runCmd.Flags().BoolVar(&runDebugNon2xx, "debug-non2xx", false, "Enable FLOW_DEBUG_NON2XX=1 in wrk2 container for non-2xx debug capture")
+	runCmd.Flags().BoolVar(&runDebug, "debug", false, "Enable detailed probe debug logs")
+	runCmd.Flags().BoolVar(&runNoRewriteLinked, "no-rewrite-linked-values", false, "Disable replacing linked values with JSON pointers in generated output")
+	runCmd.Flags().IntVar(&runMaxProbeTarget, "max-probe-target", 0, "Cap the number of generated iterations per stage (0 = unlimited)")
+	runCmd.Flags().BoolVar(&runReuseCompose, "reuse-compose", false, "Share one compose project between probing and the harness instead of recreating it")
+	runCmd.Flags().StringVar(&runExecutor, "executor", harness.ExecutorWrk2Flow, "Load generator of the stages: wrk2-flow (container on the compose network) or native (Go executor in this process; the only one writing the per-node operations.json)")
+	runCmd.Flags().DurationVar(&runSeriesInterval, "series-interval", 0, "Interval of the per-stage throughput and latency time series (series.jsonl, default 1s); only --executor native writes a series")
+	runCmd.Flags().StringSliceVar(&runEventPolicies, "event-policy", []string{}, "Reaction to a Docker event as <oom|die|unhealthy>=<ignore|invalidate|abort> (repeat flag for several; default invalidate)")
+
+	// Cold-start flags
+	coldStartCmd.Flags().StringVarP(&coldStartFlowPath, "flow-path", "f", "", "Path to the flow DSL YAML file")
+	coldStartCmd.Flags().StringVarP(&coldStartProbeBodiesPath, "probe-bodies-path", "b", "", "Path to probe-bodies result root containing stage iteration files (required unless --first-requests is 0)")
+	coldStartCmd.Flags().StringVarP(&coldStartOpenAPISpecPath, "openapi-spec-path", "o", "", "Path to the OpenAPI spec file")
+	coldStartCmd.Flags().StringVarP(&coldStartDockerComposePath, "docker-compose-path", "d", "", "Path to the docker-compose.yml file")
+	coldStartCmd.Flags().StringVarP(&coldStartServiceName, "service-name", "n", "", "Service name in the docker-compose file to measure")
+	for _, name := range []string{"flow-path", "openapi-spec-path", "docker-compose-path", "service-name"} {
+		if err := coldStartCmd.MarkFlagRequired(name); err != nil {
+			log.Fatalf("Failed to mark --%s as required: %v", name, err)
+		}
+	}
+	coldStartCmd.Flags().IntVarP(&coldStartPort, "port", "p", 8080, "Application service port inside docker network")
+	coldStartCmd.Flags().StringVarP(&coldStartResultPath, "result-path", "r", "./result", "Path to save the results")
+	coldStartCmd.Flags().StringVar(&coldStartDockerSocketPath, "docker-socket-path", "/var/run/docker.sock", "Path to Docker socket for DooD mode")
+	coldStartCmd.Flags().StringVar(&coldStartReadinessPath, "readiness-path", "", "Explicit readiness probe path (auto-derived from OpenAPI if empty)")
+	coldStartCmd.Flags().IntVar(&coldStartIterations, "iterations", 20, "Number of stop/start or recreate cycles")
+	coldStartCmd.Flags().StringVar(&coldStartMode, "mode", harness.ColdStartRestart, "Cycle mode: restart (stop and start the same container) or recreate (new container each cycle)")
+	coldStartCmd.Flags().IntVar(&coldStartFirstRequests, "first-requests", 10, "Number of flow requests timed after the first 2xx response of each cycle")
+	coldStartCmd.Flags().StringVar(&coldStartStage, "stage", "", "Flow stage whose iterations are replayed (default: the first stage)")
+
+	// Report flags
+	reportCmd.Flags().StringVarP(&reportOutputPath, "output", "o", "", "Path of the HTML file to write (default <harness-result-dir>/"+report.DefaultFileName+")")
+
+	// Compare flags
+	compareCmd.Flags().StringSliceVarP(&compareThresholds, "threshold", "t", []string{}, "Regression rule <metric>=<+|-><limit>[%|unit], e.g. p99=+10% (repeatable, one rule per metric; metrics: "+strings.Join(runstats.MetricNames(), ", ")+")")
+	compareCmd.Flags().Float64Var(&compareAlpha, "alpha", compare.DefaultAlpha, "Significance level of the Mann-Whitney U test")
+	compareCmd.Flags().StringVar(&compareFormat, "format", "text", "Output format: text or json")
+
 	// Adding commands to root
+	rootCmd.AddCommand(validateCmd)
+	rootCmd.AddCommand(runCmd)
+	rootCmd.AddCommand(reportCmd)
+	rootCmd.AddCommand(compareCmd)
 	rootCmd.AddCommand(harnessCmd)
+	rootCmd.AddCommand(coldStartCmd)
 	rootCmd.AddCommand(probeBodiesCmd)
+	rootCmd.AddCommand(activatorCmd)
 }
 
+// exitCodeRegression is returned by compare when a threshold rule fails, so
+// CI can tell a regression from a broken invocation.
+const exitCodeRegression = 3
+
 func Execute() {
 	if err := rootCmd.Execute(); err != nil {
+		if errors.Is(err, compare.ErrRegression) {
+			os.Exit(exitCodeRegression)
+		}
 		os.Exit(1)
 	}
 }
 
+// applyProjectConfig fills every flag of the invoked command that was not set
+// explicitly from SLSBENCH_* environment variables and the project file.
+func applyProjectConfig(cmd *cobra.Command, args []string) error {
+	globalFlags := []string{"config", "profile"}
+	path := configPath
+	if !cmd.Flags().Changed("config") {
+		if env, ok := os.LookupEnv(config.EnvVarName("config")); ok {
+			path = env
+		} else if _, err := os.Stat(config.DefaultFileName); err == nil {
+			path = config.DefaultFileName
+		}
+	}
+	profile := configProfile
+	if !cmd.Flags().Changed("profile") {
+		if env, ok := os.LookupEnv(config.EnvVarName("profile")); ok {
+			profile = env
+		}
+	}
+
+	var values map[string]string
+	if strings.TrimSpace(path) != "" {
+		file, err := config.Load(path)
+		if err != nil {
+			return err
+		}
+		known := knownFlagNames(cmd.Root())
+		for _, key := range file.Keys() {
+			if _, ok := known[key]; !ok {
+				return fmt.Errorf("config file %s: unknown setting %q (keys must be flag names)", path, key)
+			}
+		}
+		values, err = file.Resolve(profile)
+		if err != nil {
+			return err
+		}
+		log.Printf("Using config file %s profile=%q", path, profile)
+	} else if profile != "" {
+		return fmt.Errorf("--profile %q requires a config file (--config or ./%s)", profile, config.DefaultFileName)
+	}
+
+	return config.Apply(cmd.Flags(), values, os.LookupEnv, globalFlags...)
+}
+
+func knownFlagNames(cmd *cobra.Command) map[string]struct{} {
+	names := map[string]struct{}{}
+	var walk func(c *cobra.Command)
+	walk = func(c *cobra.Command) {
+		c.LocalFlags().VisitAll(func(flag *pflag.Flag) {
+			names[flag.Name] = struct{}{}
+		})
+		for _, child := range c.Commands() {
+			walk(child)
+		}
+	}
+	walk(cmd)
+	return names
+}
+
 func runValidateDSL(dslPath string) error {
 	ctx := context.Background()
 	log.Println("Validating DSL file:", dslPath)
 
-	file, err := os.Open(dslPath)
+	issues, err := dslvalidator.ValidateFile(ctx, dslPath, "")
 	if err != nil {
-		return fmt.Errorf("failed to open DSL file %q: %w", dslPath, err)
+		return err
 	}
-	defer file.Close()
-
-	var doc any
-	decoder := yaml.NewDecoder(file)
-	if err := decoder.Decode(&doc); err != nil {
-		return fmt.Errorf("failed to parse DSL YAML %q: %w", dslPath, err)
+	for _, issue := range issues {
+		log.Printf("%s:%s", dslPath, issue)
 	}
-
-	if err := dslvalidator.ValidateDSL(ctx, doc); err != nil {
-		// Try to pretty-print jsonschema validation errors if possible.
+	if dslvalidator.HasErrors(issues) {
 		log.Printf("DSL validation failed for %s", dslPath)
-		utils.PrintJSON(err)
-		return fmt.Errorf("DSL validation failed: %w", err)
+		return fmt.Errorf("DSL validation failed with %d issue(s)", len(issues))
 	}
 
 	log.Printf("DSL validation passed for %s", dslPath)
 	return nil
 }
 
+func runValidate(cmd *cobra.Command, args []string) error {
+	ctx := context.Background()
+
+	issues, err := dslvalidator.ValidateFile(ctx, validateFlowPath, validateOpenAPIPath)
+	if err != nil {
+		return err
+	}
+
+	out := cmd.OutOrStdout()
+	switch validateFormat {
+	case "text":
+		for _, issue := range issues {
+			fmt.Fprintf(out, "%s:%s\n", validateFlowPath, issue)
+		}
+	case "github":
+		for _, issue := range issues {
+			level := "error"
+			if issue.Severity == dslvalidator.SeverityWarning {
+				level = "warning"
+			}
+			fmt.Fprintf(out, "::%s file=%s,line=%d,col=%d::%s\n", level, validateFlowPath, issue.Line, issue.Column, issue.Text())
+		}
+	case "json":
+		if issues == nil {
+			issues = []dslvalidator.Issue{}
+		}
+		fmt.Fprintln(out, utils.DumpJSON(issues))
+	default:
+		return fmt.Errorf("unknown --format %q (expected text, github or json)", validateFormat)
+	}
+
+	failed := dslvalidator.HasErrors(issues) || (validateStrict && len(issues) > 0)
+	if failed {
+		cmd.SilenceUsage = true
+		return fmt.Errorf("flow %s is invalid: %d issue(s)", validateFlowPath, len(issues))
+	}
+	if validateFormat == "text" {
+		fmt.Fprintf(out, "%s: OK (%d warning(s))\n", validateFlowPath, len(issues))
+	}
+	return nil
+}
+
+func runReport(cmd *cobra.Command, args []string) error {
+	path, err := report.Generate(args[0], reportOutputPath)
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(cmd.OutOrStdout(), "Report written to %s\n", path)
+	return nil
+}
+
+func runCompare(cmd *cobra.Command, args []string) error {
+	if compareAlpha <= 0 || compareAlpha >= 1 {
+		return fmt.Errorf("the --alpha flag must be between 0 and 1")
+	}
+	if compareFormat != "text" && compareFormat != "json" {
+		return fmt.Errorf("unknown --format %q (expected text or json)", compareFormat)
+	}
+	rules, err := compare.ParseRules(compareThresholds)
+	if err != nil {
+		return err
+	}
+
+	baseline, err := runstats.LoadRuns(args[0])
+	if err != nil {
+		return fmt.Errorf("failed to load baseline: %w", err)
+	}
+	candidate, err := runstats.LoadRuns(args[1])
+	if err != nil {
+		return fmt.Errorf("failed to load candidate: %w", err)
+	}
+	result := compare.Compare(baseline, candidate, compare.Options{Rules: rules, Alpha: compareAlpha})
+
+	out := cmd.OutOrStdout()
+	if compareFormat == "json" {
+		fmt.Fprintln(out, utils.DumpJSON(result))
+	} else if err := result.WriteText(out); err != nil {
+		return err
+	}
+	if err := result.Err(); err != nil {
+		cmd.SilenceUsage = true
+		return err
+	}
+	return nil
+}
+
 func runHarness(cmd *cobra.Command, args []string) error {
 	ctx := context.Background()
 
@@ -208,23 +696,154 @@ func runHarness(cmd *cobra.Command, args []string) error {
 		return fmt.Errorf("flow file validation failed: %w", err)
 	}
 
-	log.Printf("Running harness: flow=%s probe-bodies=%s openapi=%s result=%s docker-compose=%s service=%s port=%d docker-socket=%s service-mount-paths=%v debug-non2xx=%t readiness-path=%q",
-		harnessFlowPath, harnessProbeBodiesPath, openApiSpecPath, harnessResultPath, harnessDockerComposePath, harnessServiceName, harnessPort, harnessDockerSocketPath, harnessServiceMountPaths, harnessDebugNon2xx, harnessReadinessPath)
+	if harnessRepetitions <= 0 {
+		return fmt.Errorf("the --repetitions flag must be a positive integer")
+	}
+	variants := make([]harness.Variant, 0, len(harnessVariants))
+	for _, raw := range harnessVariants {
+		variant, err := harness.ParseVariant(raw)
+		if err != nil {
+			return err
+		}
+		variants = append(variants, variant)
+	}
+	if len(variants) == 0 && strings.TrimSpace(harnessDockerComposePath) == "" {
+		return fmt.Errorf("required flag \"docker-compose-path\" not set")
+	}
+	eventPolicies, err := harness.ParseEventPolicies(harnessEventPolicies)
+	if err != nil {
+		return err
+	}
 
-	return harness.Run(
-		ctx,
-		harnessFlowPath,
-		harnessResultPath,
-		openApiSpecPath,
-		harnessDockerComposePath,
-		harnessServiceName,
-		harnessPort,
-		harnessServiceMountPaths,
-		harnessProbeBodiesPath,
-		harnessDockerSocketPath,
-		harnessDebugNon2xx,
-		harnessReadinessPath,
-	)
+	log.Printf("Running harness: flow=%s probe-bodies=%s openapi=%s result=%s docker-compose=%s service=%s port=%d docker-socket=%s service-mount-paths=%v debug-non2xx=%t readiness-path=%q repetitions=%d variants=%v interleave=%t",
+		harnessFlowPath, harnessProbeBodiesPath, openApiSpecPath, harnessResultPath, harnessDockerComposePath, harnessServiceName, harnessPort, harnessDockerSocketPath, harnessServiceMountPaths, harnessDebugNon2xx, harnessReadinessPath, harnessRepetitions, harnessVariants, harnessInterleave)
+
+	opts := harness.Options{
+		FlowPath:          harnessFlowPath,
+		ResultPath:        harnessResultPath,
+		OpenAPISpecPath:   openApiSpecPath,
+		DockerComposePath: harnessDockerComposePath,
+		ServiceName:       harnessServiceName,
+		Port:              harnessPort,
+		ServiceMountPaths: harnessServiceMountPaths,
+		ProbeBodiesPath:   harnessProbeBodiesPath,
+		DockerSocketPath:  harnessDockerSocketPath,
+		DebugNon2xx:       harnessDebugNon2xx,
+		ReadinessPath:     harnessReadinessPath,
+		EventPolicies:     eventPolicies,
+		Executor:          harnessExecutor,
+		SeriesInterval:    harnessSeriesInterval,
+	}
+	if harnessActivator {
+		opts.Activator = &harness.ActivatorOptions{
+			Image:          harnessActivatorImage,
+			Mode:           harnessActivatorMode,
+			IdleTimeout:    harnessActivatorIdle,
+			MaxConcurrency: harnessActivatorMaxConc,
+		}
+	}
+	if harnessRepetitions == 1 && len(variants) == 0 {
+		return harness.Run(ctx, opts)
+	}
+	return harness.RunRepeated(ctx, harness.RepeatOptions{
+		Options:     opts,
+		Repetitions: harnessRepetitions,
+		Variants:    variants,
+		Interleave:  harnessInterleave,
+		Bootstrap:   runstats.Bootstrap{Resamples: harnessBootstrapResamples},
+	})
+}
+
+func runColdStart(cmd *cobra.Command, args []string) error {
+	ctx := context.Background()
+
+	if coldStartPort <= 0 {
+		return fmt.Errorf("the --port flag must be a positive integer")
+	}
+	if err := runValidateDSL(coldStartFlowPath); err != nil {
+		return fmt.Errorf("flow file validation failed: %w", err)
+	}
+
+	log.Printf("Running cold-start: flow=%s probe-bodies=%s docker-compose=%s service=%s port=%d mode=%s iterations=%d first-requests=%d stage=%q",
+		coldStartFlowPath, coldStartProbeBodiesPath, coldStartDockerComposePath, coldStartServiceName, coldStartPort, coldStartMode, coldStartIterations, coldStartFirstRequests, coldStartStage)
+
+	return harness.RunColdStart(ctx, harness.ColdStartOptions{
+		Options: harness.Options{
+			FlowPath:          coldStartFlowPath,
+			ResultPath:        coldStartResultPath,
+			OpenAPISpecPath:   coldStartOpenAPISpecPath,
+			DockerComposePath: coldStartDockerComposePath,
+			ServiceName:       coldStartServiceName,
+			Port:              coldStartPort,
+			ProbeBodiesPath:   coldStartProbeBodiesPath,
+			DockerSocketPath:  coldStartDockerSocketPath,
+			ReadinessPath:     coldStartReadinessPath,
+		},
+		Iterations:    coldStartIterations,
+		Mode:          coldStartMode,
+		FirstRequests: coldStartFirstRequests,
+		Stage:         coldStartStage,
+	})
+}
+
+func runActivator(cmd *cobra.Command, args []string) error {
+	dockerCli, _, err := harness.NewDockerClientWithSocket(activatorDockerSocket)
+	if err != nil {
+		return err
+	}
+	defer dockerCli.Close()
+
+	events := io.Writer(io.Discard)
+	if strings.TrimSpace(activatorEventsPath) != "" {
+		file, err := os.OpenFile(activatorEventsPath, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644)
+		if err != nil {
+			return fmt.Errorf("failed to open activator events file: %w", err)
+		}
+		defer file.Close()
+		events = file
+	}
+
+	act, err := activator.New(activator.Config{
+		Upstream:       activatorUpstream,
+		ReadinessPath:  activatorReadinessPath,
+		Mode:           activatorMode,
+		IdleTimeout:    activatorIdleTimeout,
+		MaxConcurrency: activatorMaxConcurrency,
+		Controller:     &activator.DockerController{Client: dockerCli, ContainerID: activatorContainerID, Mode: activatorMode},
+		Events:         events,
+	})
+	if err != nil {
+		return err
+	}
+
+	listener, err := net.Listen("tcp", activatorListen)
+	if err != nil {
+		return fmt.Errorf("failed to listen on %s: %w", activatorListen, err)
+	}
+	server := &http.Server{Handler: act}
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	serveErr := make(chan error, 1)
+	go func() { serveErr <- server.Serve(listener) }()
+	log.Printf("%s on %s upstream=%s mode=%s idle-timeout=%s max-concurrency=%d",
+		harness.ActivatorReadyLine, listener.Addr(), activatorUpstream, activatorMode, activatorIdleTimeout, activatorMaxConcurrency)
+
+	select {
+	case err := <-serveErr:
+		_ = act.Close(context.Background())
+		return fmt.Errorf("activator server failed: %w", err)
+	case <-ctx.Done():
+	}
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), 20*time.Second)
+	defer cancel()
+	if err := server.Shutdown(shutdownCtx); err != nil {
+		log.Printf("[activator] shutdown: %v", err)
+	}
+	if err := act.Close(shutdownCtx); err != nil {
+		return fmt.Errorf("failed to scale the service back up: %w", err)
+	}
+	log.Printf("[activator] stopped")
+	return nil
 }
 
 func runProbeBodies(cmd *cobra.Command, args []string) error {
@@ -263,3 +882,82 @@ func runProbeBodies(cmd *cobra.Command, args []string) error {
 	}
 	return nil
 }
+
+func runRun(cmd *cobra.Command, args []string) (runErr error) {
+	ctx := context.Background()
+
+	if runPort <= 0 {
+		return fmt.Errorf("the --port flag must be a positive integer")
+	}
+	if err := runValidateDSL(runFlowPath); err != nil {
+		return fmt.Errorf("flow file validation failed: %w", err)
+	}
+	eventPolicies, err := harness.ParseEventPolicies(runEventPolicies)
+	if err != nil {
+		return err
+	}
+
+	runDir, err := utils.CreateResultSubdirWithPrefix(runResultPath, "run-result")
+	if err != nil {
+		return fmt.Errorf("failed to create run directory: %w", err)
+	}
+	log.Printf("Running run: flow=%s openapi=%s run-dir=%s docker-compose=%s service=%s port=%d docker-socket=%s reuse-compose=%t executor=%s",
+		runFlowPath, runOpenAPISpecPath, runDir, runDockerComposePath, runServiceName, runPort, runDockerSocketPath, runReuseCompose, runExecutor)
+
+	var session *harness.ComposeSession
+	if runReuseCompose {
+		session, err = harness.StartComposeSession(
+			ctx,
+			harness.DockerHostFromSocketPath(runDockerSocketPath),
+			runDockerComposePath,
+			fmt.Sprintf("run-%d", time.Now().UnixNano()),
+			runServiceName,
+		)
+		if err != nil {
+			return err
+		}
+		defer func() {
+			reason := "success"
+			if runErr != nil {
+				reason = "failure"
+			}
+			if downErr := session.Down(context.Background(), reason); downErr != nil && runErr == nil {
+				runErr = fmt.Errorf("failed to tear down compose project: %w", downErr)
+			}
+		}()
+		err = bodyprobe.RunOnService(ctx, runFlowPath, runOpenAPISpecPath, runDir, runPort, runDebug, runNoRewriteLinked, runReadinessPath, runMaxProbeTarget)
+	} else {
+		err = bodyprobe.Run(ctx, runFlowPath, runOpenAPISpecPath, runDir, runDockerComposePath, runDockerSocketPath, runServiceName, runPort, runDebug, runNoRewriteLinked, runReadinessPath, runMaxProbeTarget)
+	}
+	if err != nil {
+		return fmt.Errorf("probe-bodies phase failed: %w", err)
+	}
+
+	probeDir, err := utils.LatestResultSubdir(runDir, "probe-bodies-result")
+	if err != nil {
+		return fmt.Errorf("failed to locate probe-bodies output: %w", err)
+	}
+	log.Printf("Probe-bodies output: %s", probeDir)
+
+	if err := harness.Run(ctx, harness.Options{
+		FlowPath:          runFlowPath,
+		ResultPath:        runDir,
+		OpenAPISpecPath:   runOpenAPISpecPath,
+		DockerComposePath: runDockerComposePath,
+		ServiceName:       runServiceName,
+		Port:              runPort,
+		ServiceMountPaths: runServiceMountPaths,
+		ProbeBodiesPath:   probeDir,
+		DockerSocketPath:  runDockerSocketPath,
+		DebugNon2xx:       runDebugNon2xx,
+		ReadinessPath:     runReadinessPath,
+		EventPolicies:     eventPolicies,
+		Executor:          runExecutor,
+		SeriesInterval:    runSeriesInterval,
+		Session:           session,
+	}); err != nil {
+		return fmt.Errorf("harness phase failed: %w", err)
+	}
+	log.Printf("Run completed: %s", runDir)
+	return nil
+}
@@ -0,0 +1,247 @@
+// Package config loads the slsbench.yaml project file and applies its
+// settings to CLI flags.
+//
+// Relative local paths in the file (see pathKeys) are resolved against the
+// directory of the file, so a project file works from any working directory.
+//
+// Keys in the file are flag names (for example flow-path or service-name), so
+// one file serves every command: each command picks up the keys matching its
+// own flags. Named profiles overlay the top-level settings. For every flag the
+// effective value is chosen in this order: explicit CLI flag, environment
+// variable (SLSBENCH_<FLAG_NAME>), selected profile, top-level setting, flag
+// default.
+package config
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/spf13/pflag"
+	"gopkg.in/yaml.v3"
+)
+
+// DefaultFileName is the project file looked up in the working directory when
+// no explicit path is given.
+const DefaultFileName = "slsbench.yaml"
+
+// EnvPrefix prefixes the environment variable derived from each flag name.
+const EnvPrefix = "SLSBENCH_"
+
+// aliases lists config keys that also feed differently named flags, so a
+// single openapi-spec-path entry serves probe-bodies (--openapi-link) too.
+var aliases = map[string]string{
+	"openapi-link": "openapi-spec-path",
+}
+
+// pathKeys lists the config keys holding local file-system paths. Their
+// relative values are resolved against the directory of the config file.
+// Paths inside containers (service-mount-path) and HTTP paths
+// (readiness-path) are left as written.
+var pathKeys = map[string]struct{}{
+	"flow-path":           {},
+	"openapi-spec-path":   {},
+	"docker-compose-path": {},
+	"result-path":         {},
+	"probe-bodies-path":   {},
+	"output-path":         {},
+	"events-path":         {},
+	"docker-socket-path":  {},
+}
+
+// File is a parsed project file.
+type File struct {
+	Path     string
+	Settings map[string]any
+	Profiles map[string]map[string]any
+}
+
+// Load reads and parses the project file at path.
+func Load(path string) (*File, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read config file %q: %w", path, err)
+	}
+	var raw map[string]any
+	if err := yaml.Unmarshal(data, &raw); err != nil {
+		return nil, fmt.Errorf("failed to parse config file %q: %w", path, err)
+	}
+	dir := filepath.Dir(path)
+	file := &File{Path: path, Settings: map[string]any{}, Profiles: map[string]map[string]any{}}
+	for key, value := range raw {
+		if key != "profiles" {
+			file.Settings[key] = resolvePath(dir, key, value)
+			continue
+		}
+		profiles, ok := value.(map[string]any)
+		if !ok {
+			return nil, fmt.Errorf("config file %q: profiles must be a mapping", path)
+		}
+		for name, rawProfile := range profiles {
+			profile, ok := rawProfile.(map[string]any)
+			if !ok && rawProfile != nil {
+				return nil, fmt.Errorf("config file %q: profile %q must be a mapping", path, name)
+			}
+			if profile == nil {
+				profile = map[string]any{}
+			}
+			for key, value := range profile {
+				profile[key] = resolvePath(dir, key, value)
+			}
+			file.Profiles[name] = profile
+		}
+	}
+	return file, nil
+}
+
+// resolvePath joins a relative string value of a path key onto dir. Other
+// keys, absolute paths, URLs and empty values are returned unchanged.
+func resolvePath(dir, key string, value any) any {
+	if _, ok := pathKeys[key]; !ok {
+		return value
+	}
+	path, ok := value.(string)
+	if !ok || path == "" || filepath.IsAbs(path) || strings.Contains(path, "://") {
+		return value
+	}
+	return filepath.Join(dir, path)
+}
+
+// Resolve merges the named profile over the top-level settings and renders
+// every value as a flag string. An empty profile selects the top-level
+// settings only.
+func (f *File) Resolve(profile string) (map[string]string, error) {
+	merged := make(map[string]any, len(f.Settings))
+	for key, value := range f.Settings {
+		merged[key] = value
+	}
+	if profile != "" {
+		overlay, ok := f.Profiles[profile]
+		if !ok {
+			return nil, fmt.Errorf("profile %q is not defined in %s (available: %s)", profile, f.Path, strings.Join(f.ProfileNames(), ", "))
+		}
+		for key, value := range overlay {
+			merged[key] = value
+		}
+	}
+
+	values := make(map[string]string, len(merged))
+	for key, value := range merged {
+		rendered, err := renderValue(value)
+		if err != nil {
+			return nil, fmt.Errorf("config key %q: %w", key, err)
+		}
+		values[key] = rendered
+	}
+	return values, nil
+}
+
+// ProfileNames returns the defined profile names in sorted order.
+func (f *File) ProfileNames() []string {
+	names := make([]string, 0, len(f.Profiles))
+	for name := range f.Profiles {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Keys returns every setting key used at the top level or in any profile.
+func (f *File) Keys() []string {
+	seen := map[string]struct{}{}
+	for key := range f.Settings {
+		seen[key] = struct{}{}
+	}
+	for _, profile := range f.Profiles {
+		for key := range profile {
+			seen[key] = struct{}{}
+		}
+	}
+	keys := make([]string, 0, len(seen))
+	for key := range seen {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func renderValue(value any) (string, error) {
+	switch v := value.(type) {
+	case nil:
+		return "", nil
+	case string:
+		return v, nil
+	case bool:
+		return strconv.FormatBool(v), nil
+	case int:
+		return strconv.Itoa(v), nil
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64), nil
+	case []any:
+		parts := make([]string, 0, len(v))
+		for _, item := range v {
+			rendered, err := renderValue(item)
+			if err != nil {
+				return "", err
+			}
+			parts = append(parts, rendered)
+		}
+		return strings.Join(parts, ","), nil
+	default:
+		return "", fmt.Errorf("unsupported value type %T", value)
+	}
+}
+
+// EnvVarName returns the environment variable that overrides flag name,
+// for example SLSBENCH_SERVICE_NAME for service-name.
+func EnvVarName(name string) string {
+	return EnvPrefix + strings.ToUpper(strings.ReplaceAll(name, "-", "_"))
+}
+
+// Apply sets every flag in flags that was not given on the command line from
+// the environment or, failing that, from values. Flags named in skip are left
+// untouched. lookupEnv is normally os.LookupEnv.
+func Apply(flags *pflag.FlagSet, values map[string]string, lookupEnv func(string) (string, bool), skip ...string) error {
+	skipped := make(map[string]struct{}, len(skip))
+	for _, name := range skip {
+		skipped[name] = struct{}{}
+	}
+
+	var applyErr error
+	flags.VisitAll(func(flag *pflag.Flag) {
+		if applyErr != nil || flag.Changed {
+			return
+		}
+		if _, ok := skipped[flag.Name]; ok {
+			return
+		}
+		value, source, ok := lookupValue(flag.Name, values, lookupEnv)
+		if !ok {
+			return
+		}
+		if err := flags.Set(flag.Name, value); err != nil {
+			applyErr = fmt.Errorf("invalid value %q for --%s from %s: %w", value, flag.Name, source, err)
+		}
+	})
+	return applyErr
+}
+
+func lookupValue(name string, values map[string]string, lookupEnv func(string) (string, bool)) (string, string, bool) {
+	if lookupEnv != nil {
+		if value, ok := lookupEnv(EnvVarName(name)); ok {
+			return value, EnvVarName(name), true
+		}
+	}
+	if value, ok := values[name]; ok {
+		return value, "config key " + name, true
+	}
+	if alias, ok := aliases[name]; ok {
+		if value, ok := values[alias]; ok {
+			return value, "config key " + alias, true
+		}
+	}
+	return "", "", false
+}
@@ -0,0 +1,141 @@
+package config
+
+import (
+	"path/filepath"
+	"slices"
+	"testing"
+
+	"github.com/spf13/pflag"
+)
+
+func newTestFlagSet() (*pflag.FlagSet, *string, *string, *int, *[]string, *string) {
+	flags := pflag.NewFlagSet("test", pflag.ContinueOnError)
+	flowPath := flags.String("flow-path", "", "")
+	openAPILink := flags.String("openapi-link", "", "")
+	port := flags.Int("port", 8080, "")
+	mounts := flags.StringSlice("service-mount-path", []string{}, "")
+	readiness := flags.String("readiness-path", "", "")
+	return flags, flowPath, openAPILink, port, mounts, readiness
+}
+
+func noEnv(string) (string, bool) { return "", false }
+
+func TestResolve_ProfileOverridesTopLevel(t *testing.T) {
+	file, err := Load(filepath.Join("testdata", "slsbench.yaml"))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	values, err := file.Resolve("ci")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if values["port"] != "8080" {
+		t.Errorf("expected profile port 8080, got %q", values["port"])
+	}
+	if values["service-name"] != "petclinic" {
+		t.Errorf("expected top-level service-name, got %q", values["service-name"])
+	}
+	if values["service-mount-path"] != "/var/log/app,/tmp/metrics" {
+		t.Errorf("unexpected list rendering %q", values["service-mount-path"])
+	}
+	if _, ok := values["readiness-path"]; ok {
+		t.Errorf("readiness-path from profile local leaked into ci")
+	}
+}
+
+func TestLoad_ResolvesPathsAgainstConfigDir(t *testing.T) {
+	dir := "testdata"
+	file, err := Load(filepath.Join(dir, "slsbench.yaml"))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	values, err := file.Resolve("ci")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	for key, want := range map[string]string{
+		"flow-path":          filepath.Join(dir, "flow.yaml"),
+		"openapi-spec-path":  filepath.Join(dir, "openapi.yml"),
+		"result-path":        filepath.Join(dir, "results", "ci"),
+		"docker-socket-path": "/var/run/docker.sock",
+		"service-mount-path": "/var/log/app,/tmp/metrics",
+	} {
+		if values[key] != want {
+			t.Errorf("%s = %q, want %q", key, values[key], want)
+		}
+	}
+	local, err := file.Resolve("local")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if local["readiness-path"] != "/petclinic/api/owners" {
+		t.Errorf("readiness-path is an HTTP path and must stay as written, got %q", local["readiness-path"])
+	}
+}
+
+func TestResolve_UnknownProfile(t *testing.T) {
+	file, err := Load(filepath.Join("testdata", "slsbench.yaml"))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if _, err := file.Resolve("staging"); err == nil {
+		t.Fatal("expected error for undefined profile")
+	}
+	if got := file.ProfileNames(); !slices.Equal(got, []string{"ci", "local"}) {
+		t.Errorf("unexpected profile names %v", got)
+	}
+}
+
+func TestApply_Precedence(t *testing.T) {
+	flags, flowPath, openAPILink, port, mounts, readiness := newTestFlagSet()
+	if err := flags.Parse([]string{"--readiness-path", "/cli"}); err != nil {
+		t.Fatal(err)
+	}
+	values := map[string]string{
+		"flow-path":          "./config-flow.yaml",
+		"openapi-spec-path":  "./openapi.yml",
+		"port":               "9966",
+		"service-mount-path": "/a,/b",
+		"readiness-path":     "/config",
+	}
+	env := func(key string) (string, bool) {
+		if key == "SLSBENCH_PORT" {
+			return "7000", true
+		}
+		return "", false
+	}
+	if err := Apply(flags, values, env); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if *readiness != "/cli" {
+		t.Errorf("explicit flag should win, got %q", *readiness)
+	}
+	if *port != 7000 {
+		t.Errorf("env should win over config, got %d", *port)
+	}
+	if *flowPath != "./config-flow.yaml" {
+		t.Errorf("config should fill unset flag, got %q", *flowPath)
+	}
+	if *openAPILink != "./openapi.yml" {
+		t.Errorf("openapi-link should fall back to openapi-spec-path, got %q", *openAPILink)
+	}
+	if !slices.Equal(*mounts, []string{"/a", "/b"}) {
+		t.Errorf("unexpected slice value %v", *mounts)
+	}
+	if !flags.Changed("flow-path") {
+		t.Error("flags set from config must count as changed for required-flag checks")
+	}
+}
+
+func TestApply_InvalidValue(t *testing.T) {
+	flags, _, _, _, _, _ := newTestFlagSet()
+	if err := Apply(flags, map[string]string{"port": "not-a-number"}, noEnv); err == nil {
+		t.Fatal("expected error for invalid port value")
+	}
+}
+
+func TestEnvVarName(t *testing.T) {
+	if got := EnvVarName("docker-compose-path"); got != "SLSBENCH_DOCKER_COMPOSE_PATH" {
+		t.Fatalf("unexpected env var name %q", got)
+	}
+}
@@ -0,0 +1,366 @@
+// Package activator is a scale-to-zero reverse proxy in front of one service
+// container, emulating the activator of serverless platforms such as Knative
+// or the Lambda front end on a local compose network.
+//
+// After IdleTimeout without requests the service is scaled down (paused or
+// stopped). The next request is held while the service scales up and its
+// readiness path answers again. MaxConcurrency caps the requests forwarded
+// at once; the excess waits in a queue. Every request, scale-up and
+// scale-down is written as one JSON line to the events stream.
+package activator
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/http/httputil"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Scale-down modes.
+const (
+	// ModePause freezes the container with docker pause; scaling up only
+	// thaws it.
+	ModePause = "pause"
+	// ModeStop stops the container; scaling up starts it again, so the
+	// application boots from scratch.
+	ModeStop = "stop"
+)
+
+const (
+	// EventsFile is the JSON lines file the activator writes its events to.
+	EventsFile = "activator-events.jsonl"
+	// DefaultIdleTimeout is the idle period before scaling down.
+	DefaultIdleTimeout = 30 * time.Second
+
+	readinessTimeout  = 120 * time.Second
+	readinessInterval = 5 * time.Millisecond
+)
+
+// Controller scales the service container down and up.
+type Controller interface {
+	ScaleDown(ctx context.Context) error
+	ScaleUp(ctx context.Context) error
+}
+
+// Config configures an Activator.
+type Config struct {
+	// Upstream is the service base URL, e.g. http://petclinic:9966.
+	Upstream string
+	// ReadinessPath is polled after a scale-up until it answers below 500.
+	ReadinessPath  string
+	Mode           string
+	IdleTimeout    time.Duration
+	MaxConcurrency int
+	Controller     Controller
+	// Events receives one JSON line per event; nil discards them.
+	Events io.Writer
+}
+
+const (
+	stateUp = iota
+	stateActivating
+	stateDown
+)
+
+// Activator is an http.Handler proxying to the upstream service.
+type Activator struct {
+	cfg       Config
+	proxy     *httputil.ReverseProxy
+	readyURL  string
+	client    *http.Client
+	slots     chan struct{}
+	eventsMu  sync.Mutex
+	events    *json.Encoder
+	controlMu sync.Mutex // serializes Controller calls
+
+	mu        sync.Mutex
+	state     int
+	ready     chan struct{}
+	readyErr  error
+	inflight  int
+	lastDone  time.Time
+	idleTimer *time.Timer
+	closed    bool
+}
+
+// New returns an Activator for a running upstream.
+func New(cfg Config) (*Activator, error) {
+	target, err := url.Parse(strings.TrimSpace(cfg.Upstream))
+	if err != nil || target.Scheme == "" || target.Host == "" {
+		return nil, fmt.Errorf("invalid upstream URL %q", cfg.Upstream)
+	}
+	if cfg.Mode != ModePause && cfg.Mode != ModeStop {
+		return nil, fmt.Errorf("unknown activator mode %q (expected %s or %s)", cfg.Mode, ModePause, ModeStop)
+	}
+	if cfg.Controller == nil {
+		return nil, fmt.Errorf("activator needs a controller")
+	}
+	if cfg.IdleTimeout <= 0 {
+		cfg.IdleTimeout = DefaultIdleTimeout
+	}
+	if cfg.MaxConcurrency < 0 {
+		return nil, fmt.Errorf("max concurrency must not be negative, got %d", cfg.MaxConcurrency)
+	}
+	readyPath := strings.TrimSpace(cfg.ReadinessPath)
+	if !strings.HasPrefix(readyPath, "/") {
+		readyPath = "/" + readyPath
+	}
+	if cfg.Events == nil {
+		cfg.Events = io.Discard
+	}
+
+	a := &Activator{
+		cfg:      cfg,
+		proxy:    httputil.NewSingleHostReverseProxy(target),
+		readyURL: strings.TrimSuffix(target.String(), "/") + readyPath,
+		client:   &http.Client{Timeout: 5 * time.Second},
+		events:   json.NewEncoder(cfg.Events),
+		lastDone: time.Now(),
+	}
+	a.proxy.ErrorHandler = func(w http.ResponseWriter, r *http.Request, err error) {
+		w.WriteHeader(http.StatusBadGateway)
+	}
+	if cfg.MaxConcurrency > 0 {
+		a.slots = make(chan struct{}, cfg.MaxConcurrency)
+	}
+	a.mu.Lock()
+	a.armIdleTimerLocked()
+	a.mu.Unlock()
+	return a, nil
+}
+
+// ServeHTTP holds the request while the service scales up, waits for a
+// concurrency slot and forwards it.
+func (a *Activator) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	event := Event{Type: EventRequest, Time: time.Now().UTC(), Method: r.Method, Path: r.URL.Path}
+	defer a.record(&event)
+
+	activationWait, cold, err := a.acquire(r.Context())
+	defer a.release()
+	event.ActivationMillis = millis(activationWait)
+	event.Cold = cold
+	if err != nil {
+		event.Error = err.Error()
+		event.StatusCode = http.StatusServiceUnavailable
+		http.Error(w, "activator: "+err.Error(), http.StatusServiceUnavailable)
+		return
+	}
+
+	if a.slots != nil {
+		select {
+		case a.slots <- struct{}{}:
+		default:
+			queuedAt := time.Now()
+			select {
+			case a.slots <- struct{}{}:
+				event.QueueMillis = millis(time.Since(queuedAt))
+			case <-r.Context().Done():
+				event.QueueMillis = millis(time.Since(queuedAt))
+				event.Error = "client gave up while queued"
+				return
+			}
+		}
+		defer func() { <-a.slots }()
+	}
+
+	recorder := &statusRecorder{ResponseWriter: w, status: http.StatusOK}
+	forwardedAt := time.Now()
+	a.proxy.ServeHTTP(recorder, r)
+	event.UpstreamMillis = millis(time.Since(forwardedAt))
+	event.StatusCode = recorder.status
+}
+
+// acquire registers an in-flight request and scales the service up if it is
+// down. It reports how long the request was held and whether it was.
+func (a *Activator) acquire(ctx context.Context) (time.Duration, bool, error) {
+	a.mu.Lock()
+	a.inflight++
+	if a.idleTimer != nil {
+		a.idleTimer.Stop()
+		a.idleTimer = nil
+	}
+	switch a.state {
+	case stateUp:
+		a.mu.Unlock()
+		return 0, false, nil
+	case stateDown:
+		a.state = stateActivating
+		a.ready = make(chan struct{})
+		a.readyErr = nil
+		go a.activate(a.ready)
+	}
+	ready := a.ready
+	a.mu.Unlock()
+
+	heldAt := time.Now()
+	select {
+	case <-ready:
+	case <-ctx.Done():
+		return time.Since(heldAt), true, ctx.Err()
+	}
+	a.mu.Lock()
+	err := a.readyErr
+	a.mu.Unlock()
+	return time.Since(heldAt), true, err
+}
+
+// release ends an in-flight request and arms the idle timer when it was the
+// last one.
+func (a *Activator) release() {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.inflight--
+	a.lastDone = time.Now()
+	if a.inflight == 0 {
+		a.armIdleTimerLocked()
+	}
+}
+
+func (a *Activator) armIdleTimerLocked() {
+	if a.closed || a.state != stateUp {
+		return
+	}
+	if a.idleTimer != nil {
+		a.idleTimer.Stop()
+	}
+	a.idleTimer = time.AfterFunc(a.cfg.IdleTimeout, a.scaleDown)
+}
+
+func (a *Activator) scaleDown() {
+	a.mu.Lock()
+	// A request may have arrived between the timer firing and this lock.
+	if a.closed || a.state != stateUp || a.inflight > 0 || time.Since(a.lastDone) < a.cfg.IdleTimeout {
+		a.mu.Unlock()
+		return
+	}
+	a.state = stateDown
+	a.idleTimer = nil
+	a.mu.Unlock()
+
+	a.controlMu.Lock()
+	startedAt := time.Now()
+	err := a.cfg.Controller.ScaleDown(context.Background())
+	a.controlMu.Unlock()
+	event := Event{Type: EventScaleDown, Time: startedAt.UTC(), DurationMillis: millis(time.Since(startedAt))}
+	if err != nil {
+		// The service may still be running; treat it as up and retry after
+		// the next idle period.
+		log.Printf("[activator] scale-down failed: %v", err)
+		event.Error = err.Error()
+		a.mu.Lock()
+		if a.state == stateDown {
+			a.state = stateUp
+			a.armIdleTimerLocked()
+		}
+		a.mu.Unlock()
+	}
+	a.record(&event)
+}
+
+func (a *Activator) activate(ready chan struct{}) {
+	a.controlMu.Lock()
+	startedAt := time.Now()
+	err := a.cfg.Controller.ScaleUp(context.Background())
+	if err == nil {
+		err = a.waitReady()
+	}
+	a.controlMu.Unlock()
+
+	event := Event{Type: EventScaleUp, Time: startedAt.UTC(), DurationMillis: millis(time.Since(startedAt))}
+	a.mu.Lock()
+	if err != nil {
+		event.Error = err.Error()
+		a.state = stateDown
+	} else {
+		a.state = stateUp
+	}
+	a.readyErr = err
+	close(ready)
+	if a.state == stateUp && a.inflight == 0 {
+		a.armIdleTimerLocked()
+	}
+	a.mu.Unlock()
+	a.record(&event)
+}
+
+func (a *Activator) waitReady() error {
+	ctx, cancel := context.WithTimeout(context.Background(), readinessTimeout)
+	defer cancel()
+	for {
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, a.readyURL, nil)
+		if err != nil {
+			return err
+		}
+		resp, err := a.client.Do(req)
+		if err == nil {
+			_ = resp.Body.Close()
+			if resp.StatusCode < 500 {
+				return nil
+			}
+		}
+		select {
+		case <-ctx.Done():
+			return fmt.Errorf("service not ready at %s after scale-up", a.readyURL)
+		case <-time.After(readinessInterval):
+		}
+	}
+}
+
+// Close stops scaling and leaves the service scaled up, so it can be torn
+// down or inspected normally.
+func (a *Activator) Close(ctx context.Context) error {
+	a.mu.Lock()
+	a.closed = true
+	if a.idleTimer != nil {
+		a.idleTimer.Stop()
+		a.idleTimer = nil
+	}
+	state, ready := a.state, a.ready
+	a.mu.Unlock()
+
+	switch state {
+	case stateActivating:
+		<-ready
+		return nil
+	case stateDown:
+		a.controlMu.Lock()
+		defer a.controlMu.Unlock()
+		return a.cfg.Controller.ScaleUp(ctx)
+	}
+	return nil
+}
+
+func (a *Activator) record(event *Event) {
+	a.eventsMu.Lock()
+	defer a.eventsMu.Unlock()
+	if err := a.events.Encode(event); err != nil {
+		log.Printf("[activator] failed to write event: %v", err)
+	}
+}
+
+type statusRecorder struct {
+	http.ResponseWriter
+	status int
+}
+
+func (r *statusRecorder) WriteHeader(status int) {
+	r.status = status
+	r.ResponseWriter.WriteHeader(status)
+}
+
+func (r *statusRecorder) Flush() {
+	if flusher, ok := r.ResponseWriter.(http.Flusher); ok {
+		flusher.Flush()
+	}
+}
+
+func millis(d time.Duration) float64 {
+	return float64(d) / float64(time.Millisecond)
+}
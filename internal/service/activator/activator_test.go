@@ -0,0 +1,157 @@
+package activator
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+type fakeController struct {
+	downs, ups atomic.Int32
+	upDelay    time.Duration
+}
+
+func (c *fakeController) ScaleDown(context.Context) error {
+	c.downs.Add(1)
+	return nil
+}
+
+func (c *fakeController) ScaleUp(context.Context) error {
+	time.Sleep(c.upDelay)
+	c.ups.Add(1)
+	return nil
+}
+
+type syncBuffer struct {
+	mu  sync.Mutex
+	buf bytes.Buffer
+}
+
+func (b *syncBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.Write(p)
+}
+
+func newTestActivator(t *testing.T, handler http.HandlerFunc, cfg Config) (*Activator, *httptest.Server, *fakeController) {
+	t.Helper()
+	upstream := httptest.NewServer(handler)
+	t.Cleanup(upstream.Close)
+	controller := &fakeController{upDelay: 20 * time.Millisecond}
+	cfg.Upstream = upstream.URL
+	cfg.Controller = controller
+	if cfg.Mode == "" {
+		cfg.Mode = ModePause
+	}
+	a, err := New(cfg)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	proxy := httptest.NewServer(a)
+	t.Cleanup(proxy.Close)
+	t.Cleanup(func() { _ = a.Close(context.Background()) })
+	return a, proxy, controller
+}
+
+func get(t *testing.T, url string) {
+	t.Helper()
+	resp, err := http.Get(url)
+	if err != nil {
+		t.Errorf("request failed: %v", err)
+		return
+	}
+	_ = resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Errorf("unexpected status %d", resp.StatusCode)
+	}
+}
+
+func TestActivator_ScalesToZeroAndHoldsRequests(t *testing.T) {
+	events := &syncBuffer{}
+	_, proxy, controller := newTestActivator(t, func(w http.ResponseWriter, r *http.Request) {}, Config{IdleTimeout: 40 * time.Millisecond, Events: events})
+
+	get(t, proxy.URL+"/owners")
+	if controller.downs.Load() != 0 {
+		t.Fatalf("scaled down while busy")
+	}
+	deadline := time.Now().Add(2 * time.Second)
+	for controller.downs.Load() == 0 && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+	if controller.downs.Load() != 1 {
+		t.Fatalf("expected a scale-down after the idle timeout")
+	}
+
+	var wg sync.WaitGroup
+	for i := 0; i < 3; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			get(t, proxy.URL+"/owners")
+		}()
+	}
+	wg.Wait()
+	if controller.ups.Load() != 1 {
+		t.Fatalf("expected one scale-up for concurrent cold requests, got %d", controller.ups.Load())
+	}
+
+	parsed := parseEvents(t, events)
+	result := Summarize(parsed, Settings{Mode: ModePause}, time.Time{}, time.Time{})
+	if result.Requests != 4 || result.ColdRequests != 3 || result.ScaleUps != 1 || result.ScaleDowns != 1 {
+		t.Fatalf("unexpected summary: %+v", result)
+	}
+	if result.ScaleFromZero == nil || result.ScaleFromZero.MinMillis < 10 {
+		t.Fatalf("cold requests should have waited for the scale-up: %+v", result.ScaleFromZero)
+	}
+}
+
+func TestActivator_QueuesBeyondMaxConcurrency(t *testing.T) {
+	events := &syncBuffer{}
+	_, proxy, _ := newTestActivator(t, func(w http.ResponseWriter, r *http.Request) {
+		time.Sleep(30 * time.Millisecond)
+	}, Config{IdleTimeout: time.Minute, MaxConcurrency: 1, Events: events})
+
+	var wg sync.WaitGroup
+	for i := 0; i < 3; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			get(t, proxy.URL+"/")
+		}()
+	}
+	wg.Wait()
+
+	result := Summarize(parseEvents(t, events), Settings{MaxConcurrency: 1}, time.Time{}, time.Time{})
+	if result.QueuedRequests != 2 || result.QueueDelay.MaxMillis < 40 {
+		t.Fatalf("expected two queued requests, got %+v (queue %+v)", result, result.QueueDelay)
+	}
+	if result.ColdRequests != 0 {
+		t.Fatalf("no request should be cold: %+v", result)
+	}
+}
+
+func parseEvents(t *testing.T, events *syncBuffer) []Event {
+	t.Helper()
+	path := t.TempDir() + "/" + EventsFile
+	events.mu.Lock()
+	raw := append([]byte(nil), events.buf.Bytes()...)
+	events.mu.Unlock()
+	if err := writeFile(path, raw); err != nil {
+		t.Fatalf("failed to write events: %v", err)
+	}
+	parsed, err := LoadEvents(path)
+	if err != nil {
+		t.Fatalf("failed to load events: %v", err)
+	}
+	return parsed
+}
+
+func writeFile(path string, raw []byte) error {
+	return os.WriteFile(path, raw, 0o644)
+}
@@ -0,0 +1,44 @@
+package activator
+
+import (
+	"context"
+	"fmt"
+
+	dockertypes "github.com/docker/docker/api/types/container"
+	"github.com/docker/docker/client"
+)
+
+// DockerController scales one container through the Docker API.
+type DockerController struct {
+	Client      *client.Client
+	ContainerID string
+	Mode        string
+}
+
+// ScaleDown pauses or stops the container.
+func (c *DockerController) ScaleDown(ctx context.Context) error {
+	if c.Mode == ModeStop {
+		if err := c.Client.ContainerStop(ctx, c.ContainerID, dockertypes.StopOptions{}); err != nil {
+			return fmt.Errorf("failed to stop container %s: %w", c.ContainerID, err)
+		}
+		return nil
+	}
+	if err := c.Client.ContainerPause(ctx, c.ContainerID); err != nil {
+		return fmt.Errorf("failed to pause container %s: %w", c.ContainerID, err)
+	}
+	return nil
+}
+
+// ScaleUp unpauses or starts the container.
+func (c *DockerController) ScaleUp(ctx context.Context) error {
+	if c.Mode == ModeStop {
+		if err := c.Client.ContainerStart(ctx, c.ContainerID, dockertypes.StartOptions{}); err != nil {
+			return fmt.Errorf("failed to start container %s: %w", c.ContainerID, err)
+		}
+		return nil
+	}
+	if err := c.Client.ContainerUnpause(ctx, c.ContainerID); err != nil {
+		return fmt.Errorf("failed to unpause container %s: %w", c.ContainerID, err)
+	}
+	return nil
+}
@@ -0,0 +1,114 @@
+package activator
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/d-iii-s/slsbench/internal/service/summary"
+)
+
+// Event types.
+const (
+	EventRequest   = "request"
+	EventScaleUp   = "scale-up"
+	EventScaleDown = "scale-down"
+)
+
+// Event is one line of the events file. Request events carry the request
+// fields; scale events carry DurationMillis.
+type Event struct {
+	Type string    `json:"type"`
+	Time time.Time `json:"time"`
+	// Request events.
+	Method           string  `json:"method,omitempty"`
+	Path             string  `json:"path,omitempty"`
+	Cold             bool    `json:"cold,omitempty"`
+	ActivationMillis float64 `json:"activationMillis,omitempty"`
+	QueueMillis      float64 `json:"queueMillis,omitempty"`
+	UpstreamMillis   float64 `json:"upstreamMillis,omitempty"`
+	StatusCode       int     `json:"statusCode,omitempty"`
+	// Scale events.
+	DurationMillis float64 `json:"durationMillis,omitempty"`
+	Error          string  `json:"error,omitempty"`
+}
+
+// LoadEvents reads an events file. A missing file yields no events, and a
+// truncated last line is skipped.
+func LoadEvents(path string) ([]Event, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to open activator events %q: %w", path, err)
+	}
+	defer file.Close()
+
+	var events []Event
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		var event Event
+		if err := json.Unmarshal(scanner.Bytes(), &event); err != nil {
+			continue
+		}
+		events = append(events, event)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read activator events %q: %w", path, err)
+	}
+	return events, nil
+}
+
+// Settings are the activator parameters copied into every summary.
+type Settings struct {
+	Mode           string
+	IdleTimeout    time.Duration
+	MaxConcurrency int
+}
+
+// Summarize aggregates the events that happened in [from, to]. Zero bounds
+// are open.
+func Summarize(events []Event, settings Settings, from, to time.Time) *summary.ActivatorSummary {
+	out := &summary.ActivatorSummary{
+		Mode:               settings.Mode,
+		IdleTimeoutSeconds: settings.IdleTimeout.Seconds(),
+		MaxConcurrency:     settings.MaxConcurrency,
+	}
+	var activation, queue, upstream []float64
+	for _, event := range events {
+		if (!from.IsZero() && event.Time.Before(from)) || (!to.IsZero() && event.Time.After(to)) {
+			continue
+		}
+		switch event.Type {
+		case EventScaleUp:
+			out.ScaleUps++
+		case EventScaleDown:
+			out.ScaleDowns++
+		case EventRequest:
+			out.Requests++
+			if event.Cold {
+				out.ColdRequests++
+				activation = append(activation, event.ActivationMillis)
+			}
+			if event.QueueMillis > 0 {
+				out.QueuedRequests++
+			}
+			queue = append(queue, event.QueueMillis)
+			if event.Error != "" || event.StatusCode >= 500 {
+				out.FailedRequests++
+			}
+			if event.Error == "" {
+				upstream = append(upstream, event.UpstreamMillis)
+			}
+		}
+	}
+	out.ScaleFromZero = summary.SummarizeValues("scale-from-zero", activation)
+	out.QueueDelay = summary.SummarizeValues("queue-delay", queue)
+	out.Upstream = summary.SummarizeValues("upstream", upstream)
+	return out
+}
@@ -57,24 +57,56 @@ func Run(
 	maxProbeTarget int,
 ) error {
 	return runWithManagedDocker(ctx, dockerComposePath, dockerSocketPath, serviceName, port, openAPILink, readinessPath, debug, func(runCtx context.Context) error {
-		generateFn := func(
-			generateCtx context.Context,
-			generateOpenAPILink, chain, baseURL string,
-			generateDebug bool,
-		) ([]datagen.StatefulChain, error) {
-			return datagen.GenerateStatefulChainsData(
-				generateCtx,
-				generateOpenAPILink,
-				chain,
-				baseURL,
-				generateDebug,
-				noRewriteLinkedValues,
-			)
-		}
-		return runWithGeneratorAndWorkdir(runCtx, flowPath, openAPILink, outputPath, port, generateFn, debug, maxProbeTarget)
+		return runProbe(runCtx, flowPath, openAPILink, outputPath, port, debug, noRewriteLinkedValues, maxProbeTarget)
 	})
 }
 
+// RunOnService is Run against a service that is already up, for example a
+// compose project owned by the caller. It waits for readiness but never
+// starts or stops containers.
+func RunOnService(
+	ctx context.Context,
+	flowPath, openAPILink, outputPath string,
+	port int,
+	debug bool,
+	noRewriteLinkedValues bool,
+	readinessPath string,
+	maxProbeTarget int,
+) error {
+	if port <= 0 {
+		return fmt.Errorf("port must be positive, got %d", port)
+	}
+	if err := waitForServiceReady(ctx, port, openAPILink, readinessPath); err != nil {
+		return err
+	}
+	return runProbe(ctx, flowPath, openAPILink, outputPath, port, debug, noRewriteLinkedValues, maxProbeTarget)
+}
+
+func runProbe(
+	ctx context.Context,
+	flowPath, openAPILink, outputPath string,
+	port int,
+	debug bool,
+	noRewriteLinkedValues bool,
+	maxProbeTarget int,
+) error {
+	generateFn := func(
+		generateCtx context.Context,
+		generateOpenAPILink, chain, baseURL string,
+		generateDebug bool,
+	) ([]datagen.StatefulChain, error) {
+		return datagen.GenerateStatefulChainsData(
+			generateCtx,
+			generateOpenAPILink,
+			chain,
+			baseURL,
+			generateDebug,
+			noRewriteLinkedValues,
+		)
+	}
+	return runWithGeneratorAndWorkdir(ctx, flowPath, openAPILink, outputPath, port, generateFn, debug, maxProbeTarget)
+}
+
 func runWithManagedDocker(
 	ctx context.Context,
 	dockerComposePath, dockerSocketPath, serviceName string,
@@ -107,6 +139,26 @@ func runWithManagedDocker(
 		return err
 	}
 
+	if err := waitForServiceReady(ctx, port, openAPISpecPath, readinessPathOverride); err != nil {
+		_ = teardown(context.Background())
+		return err
+	}
+
+	runErr := runFn(ctx)
+	downErr := teardown(context.Background())
+	if runErr != nil {
+		if downErr != nil {
+			return fmt.Errorf("probe failed: %w; additionally failed to tear down compose project: %v", runErr, downErr)
+		}
+		return runErr
+	}
+	if downErr != nil {
+		return fmt.Errorf("failed to tear down compose project: %w", downErr)
+	}
+	return nil
+}
+
+func waitForServiceReady(ctx context.Context, port int, openAPISpecPath, readinessPathOverride string) error {
 	var readyPath string
 	if rp := strings.TrimSpace(readinessPathOverride); rp != "" {
 		readyPath = rp
@@ -121,21 +173,8 @@ func runWithManagedDocker(
 	}
 	readinessURL := fmt.Sprintf("http://localhost:%d%s", port, readyPath)
 	if err := waitForHTTPReadyFn(ctx, readinessURL, defaultReadyTimeout, defaultReadyInterval); err != nil {
-		_ = teardown(context.Background())
 		return fmt.Errorf("service did not become ready at %s: %w", readinessURL, err)
 	}
-
-	runErr := runFn(ctx)
-	downErr := teardown(context.Background())
-	if runErr != nil {
-		if downErr != nil {
-			return fmt.Errorf("probe failed: %w; additionally failed to tear down compose project: %v", runErr, downErr)
-		}
-		return runErr
-	}
-	if downErr != nil {
-		return fmt.Errorf("failed to tear down compose project: %w", downErr)
-	}
 	return nil
 }
 
@@ -315,11 +354,11 @@ func runStageProbe(
 	debug bool,
 	maxProbeTarget int,
 ) error {
-	cfg, err := flowgen.ParseWrk2Params(stage.Wrk2Params)
+	load, err := stage.LoadProfile()
 	if err != nil {
-		return fmt.Errorf("stage %q: invalid wrk2params: %w", stageName, err)
+		return fmt.Errorf("stage %q: invalid load: %w", stageName, err)
 	}
-	target := requestTargetWithMargin(cfg.TotalRequests())
+	target := requestTargetWithMargin(load.TotalRequests())
 	if maxProbeTarget > 0 && target > maxProbeTarget {
 		target = maxProbeTarget
 	}
@@ -350,9 +389,14 @@ func runStageProbe(
 			if debug {
 				fmt.Printf("[probe-bodies] stage=%s generation error: %v\n", stageName, err)
 			}
+			traverser.Rejected()
 			continue
 		}
+		labelChainNodes(generatedChains, traverser.lastNodes, operationIDs)
 		acceptedChains, stats := filterAcceptedChains(generatedChains)
+		if len(acceptedChains) == 0 {
+			traverser.Rejected()
+		}
 		if debug {
 			logChainsDebug(stageName, acceptedChains, generatedChains, stats)
 		}
@@ -416,9 +460,14 @@ func requestTargetWithMargin(total int) int {
 type stageTraverser struct {
 	stageName string
 	nodes     map[string]flowgen.FlowNode
-	entryName string
-	choosers  map[string]*weightedRoundRobinChooser
-	maxDepth  int
+	entries   *flowgen.WeightedRoundRobin
+	choosers  map[string]*flowgen.WeightedRoundRobin
+	// retryEntry is the entry of a rejected chain, started again so that
+	// the accepted iterations keep the entry weights.
+	retryEntry string
+	lastEntry  string
+	// lastNodes are the node names of the last chain, in step order.
+	lastNodes []string
 }
 
 func newStageTraverser(stageName string, stage flowgen.Stage) (*stageTraverser, error) {
@@ -426,24 +475,22 @@ func newStageTraverser(stageName string, stage flowgen.Stage) (*stageTraverser,
 		return nil, fmt.Errorf("stage %q: flow is empty", stageName)
 	}
 	nodes := make(map[string]flowgen.FlowNode, len(stage.Flow))
-	entryName := ""
 	for _, node := range stage.Flow {
 		if node.Name == "" {
 			return nil, fmt.Errorf("stage %q: flow node has empty name", stageName)
 		}
 		nodes[node.Name] = node
-		if node.EntryNode {
-			if entryName != "" {
-				return nil, fmt.Errorf("stage %q: multiple entry nodes are not supported", stageName)
-			}
-			entryName = node.Name
-		}
 	}
-	if entryName == "" {
+	entryEdges := stage.Entries()
+	if len(entryEdges) == 0 {
 		return nil, fmt.Errorf("stage %q: no entry node", stageName)
 	}
+	entries, err := flowgen.NewWeightedRoundRobin(entryEdges)
+	if err != nil {
+		return nil, fmt.Errorf("stage %q entry nodes: %w", stageName, err)
+	}
 
-	choosers := make(map[string]*weightedRoundRobinChooser)
+	choosers := make(map[string]*flowgen.WeightedRoundRobin)
 	for name, node := range nodes {
 		if len(node.Edges) == 0 {
 			continue
@@ -453,7 +500,7 @@ func newStageTraverser(stageName string, stage flowgen.Stage) (*stageTraverser,
 				return nil, fmt.Errorf("stage %q: node %q has edge to unknown node %q", stageName, name, edge.To)
 			}
 		}
-		chooser, err := newWeightedRoundRobinChooser(node.Edges)
+		chooser, err := flowgen.NewWeightedRoundRobin(node.Choices())
 		if err != nil {
 			return nil, fmt.Errorf("stage %q node %q: %w", stageName, name, err)
 		}
@@ -462,16 +509,31 @@ func newStageTraverser(stageName string, stage flowgen.Stage) (*stageTraverser,
 	return &stageTraverser{
 		stageName: stageName,
 		nodes:     nodes,
-		entryName: entryName,
+		entries:   entries,
 		choosers:  choosers,
-		maxDepth:  len(nodes)*4 + 4,
 	}, nil
 }
 
+// maxChainSteps stops a traversal that loops far longer than any chain the
+// generator could probe; the validator rejects cycles that never end.
+const maxChainSteps = 1000
+
+// NextChainOperationIDs walks one chain from an entry node picked by entry
+// weight, or from the entry of the last rejected chain. Edges whose
+// maxtraversals or whose target's maxvisits is used up are skipped; the chain
+// ends when the node's exit is picked or no edge is left.
 func (t *stageTraverser) NextChainOperationIDs() ([]string, error) {
-	current := t.entryName
-	ops := make([]string, 0, t.maxDepth)
-	for depth := 0; depth < t.maxDepth; depth++ {
+	current := t.retryEntry
+	if current == "" {
+		current = t.entries.Next()
+	}
+	t.retryEntry = ""
+	t.lastEntry = current
+	visits := make(map[string]int)
+	traversals := make(map[string][]int)
+	var ops []string
+	t.lastNodes = nil
+	for step := 0; step < maxChainSteps; step++ {
 		node, ok := t.nodes[current]
 		if !ok {
 			return nil, fmt.Errorf("stage %q: traversal reached unknown node %q", t.stageName, current)
@@ -480,6 +542,8 @@ func (t *stageTraverser) NextChainOperationIDs() ([]string, error) {
 			return nil, fmt.Errorf("stage %q: node %q missing operationId", t.stageName, node.Name)
 		}
 		ops = append(ops, node.OperationID)
+		t.lastNodes = append(t.lastNodes, node.Name)
+		visits[node.Name]++
 		if len(node.Edges) == 0 {
 			return ops, nil
 		}
@@ -487,45 +551,43 @@ func (t *stageTraverser) NextChainOperationIDs() ([]string, error) {
 		if chooser == nil {
 			return nil, fmt.Errorf("stage %q: missing chooser for node %q", t.stageName, node.Name)
 		}
-		current = chooser.Next()
+		taken := traversals[node.Name]
+		if taken == nil {
+			taken = make([]int, len(node.Edges))
+			traversals[node.Name] = taken
+		}
+		next, ok := chooser.NextEligible(func(i int) bool {
+			if i == len(node.Edges) {
+				return true // the exit
+			}
+			edge := node.Edges[i]
+			return edge.TraversalsLeft(taken[i]) && t.nodes[edge.To].VisitsLeft(visits[edge.To])
+		})
+		if !ok || next == len(node.Edges) {
+			return ops, nil
+		}
+		taken[next]++
+		current = node.Edges[next].To
 	}
-	return nil, fmt.Errorf("stage %q: traversal exceeded max depth %d (possible cycle)", t.stageName, t.maxDepth)
+	return nil, fmt.Errorf("stage %q: traversal exceeded %d steps; bound the cycle with maxvisits or maxtraversals", t.stageName, maxChainSteps)
 }
 
-type weightedRoundRobinChooser struct {
-	edges   []flowgen.Edge
-	current []float64
-	total   float64
-}
-
-func newWeightedRoundRobinChooser(edges []flowgen.Edge) (*weightedRoundRobinChooser, error) {
-	if len(edges) == 0 {
-		return nil, fmt.Errorf("cannot build weighted chooser with no edges")
-	}
-	total := 0.0
-	for _, edge := range edges {
-		if edge.Weight <= 0 {
-			return nil, fmt.Errorf("edge to %q has non-positive weight %v", edge.To, edge.Weight)
+// labelChainNodes sets the flow node of every generated step. The generator
+// only knows operationIds, and its steps follow the chain in order.
+func labelChainNodes(chains []datagen.StatefulChain, nodes, operationIDs []string) {
+	for _, chain := range chains {
+		for idx := range chain.Steps {
+			step := &chain.Steps[idx]
+			if idx < len(nodes) && step.FlowID == operationIDs[idx] {
+				step.Node = nodes[idx]
+			}
 		}
-		total += edge.Weight
 	}
-	return &weightedRoundRobinChooser{
-		edges:   edges,
-		current: make([]float64, len(edges)),
-		total:   total,
-	}, nil
 }
 
-func (w *weightedRoundRobinChooser) Next() string {
-	best := 0
-	for i := range w.edges {
-		w.current[i] += w.edges[i].Weight
-		if w.current[i] > w.current[best] {
-			best = i
-		}
-	}
-	w.current[best] -= w.total
-	return w.edges[best].To
+// Rejected makes the next chain start at the entry of the last one again.
+func (t *stageTraverser) Rejected() {
+	t.retryEntry = t.lastEntry
 }
 
 func maxInt(a, b int) int {
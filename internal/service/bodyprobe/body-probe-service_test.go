@@ -233,6 +233,33 @@ func TestRunWithManagedDocker_FallbackReadinessPath(t *testing.T) {
 	}
 }
 
+func TestRunOnService_ReadinessErrorSkipsProbe(t *testing.T) {
+	origWait := waitForHTTPReadyFn
+	defer func() { waitForHTTPReadyFn = origWait }()
+
+	var capturedURL string
+	waitForHTTPReadyFn = func(ctx context.Context, url string, timeout, interval time.Duration) error {
+		capturedURL = url
+		return errors.New("timeout")
+	}
+
+	outDir := t.TempDir()
+	err := RunOnService(context.Background(), "/nonexistent/flow.yaml", "", outDir, 8080, false, false, "/ready", 0)
+	if err == nil || !strings.Contains(err.Error(), "did not become ready") {
+		t.Fatalf("expected readiness error, got %v", err)
+	}
+	if capturedURL != "http://localhost:8080/ready" {
+		t.Fatalf("unexpected readiness URL %q", capturedURL)
+	}
+	entries, err := os.ReadDir(outDir)
+	if err != nil {
+		t.Fatalf("failed to list output dir: %v", err)
+	}
+	if len(entries) != 0 {
+		t.Fatalf("expected no probe output after readiness failure, got %d entries", len(entries))
+	}
+}
+
 func TestRequestTargetWithMargin(t *testing.T) {
 	if got := requestTargetWithMargin(100); got != 111 {
 		t.Fatalf("expected 111, got %d", got)
@@ -281,6 +308,118 @@ func TestStageTraverser_WeightedRoundRobin(t *testing.T) {
 	}
 }
 
+func TestStageTraverser_WeightedEntriesAndRetry(t *testing.T) {
+	browseWeight := 3.0
+	stage := flowgen.Stage{
+		Flow: []flowgen.FlowNode{
+			{Name: "browse", OperationID: "listOwners", EntryNode: true, EntryWeight: &browseWeight},
+			{Name: "checkout", OperationID: "addOwner", EntryNode: true},
+		},
+	}
+	traverser, err := newStageTraverser("mix", stage)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	next := func() string {
+		ops, err := traverser.NextChainOperationIDs()
+		if err != nil {
+			t.Fatalf("traversal failed: %v", err)
+		}
+		return ops[0]
+	}
+	var firstOps []string
+	for i := 0; i < 4; i++ {
+		firstOps = append(firstOps, next())
+	}
+	if !slices.Equal(firstOps, []string{"listOwners", "listOwners", "addOwner", "listOwners"}) {
+		t.Fatalf("unexpected entry sequence: %#v", firstOps)
+	}
+	// A rejected chain starts at the same entry again.
+	if op := next(); op != "listOwners" {
+		t.Fatalf("expected listOwners, got %s", op)
+	}
+	traverser.Rejected()
+	if op := next(); op != "listOwners" {
+		t.Fatalf("rejected chain not retried from its entry, got %s", op)
+	}
+	var rest []string
+	for i := 0; i < 2; i++ {
+		rest = append(rest, next())
+	}
+	if !slices.Equal(rest, []string{"listOwners", "addOwner"}) {
+		t.Fatalf("expected the mix to continue, got %#v", rest)
+	}
+}
+
+func TestStageTraverser_BoundedLoop(t *testing.T) {
+	stage := flowgen.Stage{
+		Flow: []flowgen.FlowNode{
+			{
+				Name:        "add",
+				OperationID: "addToCart",
+				EntryNode:   true,
+				MaxVisits:   3,
+				Edges: []flowgen.Edge{
+					{To: "add", Weight: 3},
+					{To: "checkout", Weight: 1},
+				},
+			},
+			{Name: "checkout", OperationID: "checkout"},
+		},
+	}
+	traverser, err := newStageTraverser("cart", stage)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	ops, err := traverser.NextChainOperationIDs()
+	if err != nil {
+		t.Fatalf("traversal failed: %v", err)
+	}
+	if !slices.Equal(ops, []string{"addToCart", "addToCart", "addToCart", "checkout"}) {
+		t.Fatalf("maxvisits not applied: %#v", ops)
+	}
+
+	stage.Flow[0].MaxVisits = 0
+	stage.Flow[0].Edges = stage.Flow[0].Edges[:1]
+	traverser, err = newStageTraverser("cart", stage)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if _, err := traverser.NextChainOperationIDs(); err == nil || !strings.Contains(err.Error(), "maxvisits") {
+		t.Fatalf("expected an unbounded loop to fail, got %v", err)
+	}
+}
+
+func TestStageTraverser_ExitWeight(t *testing.T) {
+	stage := flowgen.Stage{
+		Flow: []flowgen.FlowNode{
+			{
+				Name:        "list",
+				OperationID: "listItems",
+				EntryNode:   true,
+				ExitWeight:  3,
+				Edges:       []flowgen.Edge{{To: "detail", Weight: 2}},
+			},
+			{Name: "detail", OperationID: "getItem"},
+		},
+	}
+	traverser, err := newStageTraverser("browse", stage)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	var lengths []int
+	for range 5 {
+		ops, err := traverser.NextChainOperationIDs()
+		if err != nil {
+			t.Fatalf("traversal failed: %v", err)
+		}
+		lengths = append(lengths, len(ops))
+	}
+	if !slices.Equal(lengths, []int{1, 2, 1, 2, 1}) {
+		t.Fatalf("expected 3 of 5 chains to exit at list, got lengths %v", lengths)
+	}
+}
+
 func TestRunWithGenerator_WritesPerStageIterations(t *testing.T) {
 	flowPath := writeTempFlow(t, `
 stages:
@@ -481,6 +620,22 @@ stages:
 	}
 }
 
+func TestLabelChainNodes_TellsNodesOfOneOperationApart(t *testing.T) {
+	chains := []datagen.StatefulChain{{Steps: []datagen.StatefulStep{
+		{FlowID: "listOwners"}, {FlowID: "getOwner"}, {FlowID: "listOwners"},
+	}}}
+	labelChainNodes(chains, []string{"browse", "detail", "back"}, []string{"listOwners", "getOwner", "listOwners"})
+	for i, want := range []string{"browse", "detail", "back"} {
+		if got := chains[0].Steps[i].Node; got != want {
+			t.Fatalf("step %d node = %q, want %q", i, got, want)
+		}
+	}
+	iterations := datagen.ProjectMinimalIterations(chains)
+	if got := iterations[0].Steps[2].Node; got != "back" {
+		t.Fatalf("projected node = %q, want back", got)
+	}
+}
+
 func TestFilterAcceptedChains_RejectsNon2xxChains(t *testing.T) {
 	accepted, stats := filterAcceptedChains([]datagen.StatefulChain{
 		{
@@ -0,0 +1,312 @@
+// Package compare lines up the stages of baseline and candidate harness runs
+// and decides, metric by metric, whether the candidate regressed.
+//
+// Each side may hold several repetitions. A threshold rule only fails the
+// comparison when the median moved past the limit and a two-sided
+// Mann-Whitney U test finds the two sides different at the chosen
+// significance level. When the sample sizes are too small for the test to
+// ever reach that level, the threshold alone decides and the finding is
+// marked unverified.
+package compare
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/d-iii-s/slsbench/internal/service/runstats"
+)
+
+// ErrRegression is returned by Result.Err when at least one threshold rule
+// failed.
+var ErrRegression = errors.New("performance regression detected")
+
+// DefaultAlpha is the default significance level.
+const DefaultAlpha = 0.05
+
+// Verdicts of a compared metric.
+const (
+	// VerdictOK: no rule exceeded and no significant change.
+	VerdictOK = "ok"
+	// VerdictRegression: a rule was exceeded and the change is significant.
+	VerdictRegression = "regression"
+	// VerdictUnverified: a rule was exceeded but the samples are too small to
+	// test significance. Fails like a regression.
+	VerdictUnverified = "regression-unverified"
+	// VerdictNotSignificant: a rule was exceeded but the test attributes the
+	// change to noise.
+	VerdictNotSignificant = "not-significant"
+	// VerdictImproved: significant change in the better direction.
+	VerdictImproved = "improved"
+	// VerdictWorse: significant change in the worse direction that no rule
+	// covers.
+	VerdictWorse = "worse"
+	// VerdictMissing: one side has no value for this metric.
+	VerdictMissing = "missing"
+)
+
+// Rule fails the comparison when a metric moves past Limit. A positive limit
+// bounds increases, a negative one decreases. Relative limits ("%" suffix)
+// are fractions of the baseline median; absolute ones are in the metric's
+// unit, so "errors=+1" allows one more percentage point of failed requests.
+type Rule struct {
+	Metric   string  `json:"metric"`
+	Limit    float64 `json:"limit"`
+	Relative bool    `json:"relative"`
+}
+
+// ParseRule parses a threshold such as "p99=+10%", "rps=-5%" or
+// "first-response=+500ms".
+func ParseRule(text string) (Rule, error) {
+	name, rawLimit, ok := strings.Cut(strings.TrimSpace(text), "=")
+	if !ok {
+		return Rule{}, fmt.Errorf("invalid threshold %q: expected <metric>=<+|-><limit>[%%|unit]", text)
+	}
+	metric, ok := runstats.LookupMetric(strings.TrimSpace(name))
+	if !ok {
+		return Rule{}, fmt.Errorf("invalid threshold %q: unknown metric %q (known: %s)", text, name, strings.Join(runstats.MetricNames(), ", "))
+	}
+	rawLimit = strings.TrimSpace(rawLimit)
+	if !strings.HasPrefix(rawLimit, "+") && !strings.HasPrefix(rawLimit, "-") {
+		return Rule{}, fmt.Errorf("invalid threshold %q: the limit needs a sign (+ bounds increases, - decreases)", text)
+	}
+	rule := Rule{Metric: metric.Name}
+	number := rawLimit
+	if trimmed, ok := strings.CutSuffix(number, "%"); ok {
+		number, rule.Relative = trimmed, true
+	} else if metric.Unit != "%" {
+		number = strings.TrimSuffix(number, metric.Unit)
+	}
+	limit, err := strconv.ParseFloat(number, 64)
+	if err != nil || limit == 0 {
+		return Rule{}, fmt.Errorf("invalid threshold %q: limit %q is not a non-zero number", text, rawLimit)
+	}
+	if rule.Relative {
+		limit /= 100
+	}
+	rule.Limit = limit
+	return rule, nil
+}
+
+// ParseRules parses every threshold with ParseRule. A metric may carry only
+// one rule, so a second threshold for the same metric is rejected.
+func ParseRules(texts []string) ([]Rule, error) {
+	rules := make([]Rule, 0, len(texts))
+	seen := map[string]string{}
+	for _, text := range texts {
+		rule, err := ParseRule(text)
+		if err != nil {
+			return nil, err
+		}
+		if previous, ok := seen[rule.Metric]; ok {
+			return nil, fmt.Errorf("invalid threshold %q: metric %s already has threshold %q", text, rule.Metric, previous)
+		}
+		seen[rule.Metric] = text
+		rules = append(rules, rule)
+	}
+	return rules, nil
+}
+
+// String renders the rule in the syntax ParseRule accepts.
+func (r Rule) String() string {
+	if r.Relative {
+		return fmt.Sprintf("%s=%+g%%", r.Metric, r.Limit*100)
+	}
+	metric, _ := runstats.LookupMetric(r.Metric)
+	if metric.Unit == "%" {
+		return fmt.Sprintf("%s=%+g", r.Metric, r.Limit)
+	}
+	return fmt.Sprintf("%s=%+g%s", r.Metric, r.Limit, metric.Unit)
+}
+
+func (r Rule) exceeded(baseline, candidate float64) bool {
+	delta := candidate - baseline
+	if r.Relative {
+		if baseline == 0 {
+			return (r.Limit > 0 && delta > 0) || (r.Limit < 0 && delta < 0)
+		}
+		delta /= math.Abs(baseline)
+	}
+	if r.Limit > 0 {
+		return delta > r.Limit
+	}
+	return delta < r.Limit
+}
+
+// Options controls a comparison.
+type Options struct {
+	Rules []Rule
+	// Alpha is the significance level; zero means DefaultAlpha.
+	Alpha float64
+}
+
+// Row is the comparison of one metric of one stage. Stage is empty for
+// run-level metrics.
+type Row struct {
+	Stage           string    `json:"stage"`
+	Metric          string    `json:"metric"`
+	Unit            string    `json:"unit"`
+	Baseline        []float64 `json:"baseline"`
+	Candidate       []float64 `json:"candidate"`
+	BaselineMedian  float64   `json:"baselineMedian"`
+	CandidateMedian float64   `json:"candidateMedian"`
+	// DeltaPercent is nil when the baseline median is zero.
+	DeltaPercent *float64 `json:"deltaPercent,omitempty"`
+	// PValue is nil when either side has no value.
+	PValue  *float64 `json:"pValue,omitempty"`
+	Rule    string   `json:"rule,omitempty"`
+	Verdict string   `json:"verdict"`
+}
+
+// Failed reports whether the row fails the comparison.
+func (r Row) Failed() bool {
+	return r.Verdict == VerdictRegression || r.Verdict == VerdictUnverified
+}
+
+// Result is a full comparison.
+type Result struct {
+	BaselineRuns  []string `json:"baselineRuns"`
+	CandidateRuns []string `json:"candidateRuns"`
+	Alpha         float64  `json:"alpha"`
+	Rules         []string `json:"rules"`
+	Rows          []Row    `json:"rows"`
+	Notes         []string `json:"notes,omitempty"`
+}
+
+// Regressions returns the rows that fail the comparison.
+func (r *Result) Regressions() []Row {
+	var failed []Row
+	for _, row := range r.Rows {
+		if row.Failed() {
+			failed = append(failed, row)
+		}
+	}
+	return failed
+}
+
+// Err returns an error wrapping ErrRegression when any rule failed.
+func (r *Result) Err() error {
+	failed := r.Regressions()
+	if len(failed) == 0 {
+		return nil
+	}
+	names := make([]string, 0, len(failed))
+	for _, row := range failed {
+		names = append(names, rowLabel(row))
+	}
+	return fmt.Errorf("%w: %s", ErrRegression, strings.Join(names, ", "))
+}
+
+func rowLabel(row Row) string {
+	if row.Stage == "" {
+		return row.Metric
+	}
+	return row.Stage + "/" + row.Metric
+}
+
+// Compare compares every metric of every stage present on both sides.
+func Compare(baseline, candidate []*runstats.Run, opts Options) *Result {
+	alpha := opts.Alpha
+	if alpha <= 0 {
+		alpha = DefaultAlpha
+	}
+	rules := map[string]Rule{}
+	result := &Result{Alpha: alpha, Rules: []string{}, Rows: []Row{}}
+	for _, rule := range opts.Rules {
+		rules[rule.Metric] = rule
+		result.Rules = append(result.Rules, rule.String())
+	}
+	for _, run := range baseline {
+		result.BaselineRuns = append(result.BaselineRuns, run.Dir)
+	}
+	for _, run := range candidate {
+		result.CandidateRuns = append(result.CandidateRuns, run.Dir)
+	}
+
+	minP := MinPValue(len(baseline), len(candidate))
+	if minP > alpha {
+		result.Notes = append(result.Notes, fmt.Sprintf(
+			"%d baseline and %d candidate run(s) cannot reach significance at alpha=%g (smallest possible p=%.3g); threshold rules decide alone and are marked %s. Add repetitions to filter noise.",
+			len(baseline), len(candidate), alpha, minP, VerdictUnverified))
+	}
+
+	baseStages, candStages := runstats.StageNames(baseline), runstats.StageNames(candidate)
+	var stages []string
+	for _, name := range baseStages {
+		if slices.Contains(candStages, name) {
+			stages = append(stages, name)
+		} else {
+			result.Notes = append(result.Notes, fmt.Sprintf("stage %q only exists in the baseline", name))
+		}
+	}
+	for _, name := range candStages {
+		if !slices.Contains(baseStages, name) {
+			result.Notes = append(result.Notes, fmt.Sprintf("stage %q only exists in the candidate", name))
+		}
+	}
+
+	for _, metric := range runstats.Metrics {
+		if metric.RunLevel {
+			result.Rows = append(result.Rows, compareMetric("", metric, baseline, candidate, rules, alpha))
+		}
+	}
+	for _, stage := range stages {
+		for _, metric := range runstats.Metrics {
+			if !metric.RunLevel {
+				result.Rows = append(result.Rows, compareMetric(stage, metric, baseline, candidate, rules, alpha))
+			}
+		}
+	}
+	return result
+}
+
+func compareMetric(stage string, metric runstats.Metric, baseline, candidate []*runstats.Run, rules map[string]Rule, alpha float64) Row {
+	row := Row{
+		Stage:     stage,
+		Metric:    metric.Name,
+		Unit:      metric.Unit,
+		Baseline:  runstats.Values(metric, stage, baseline),
+		Candidate: runstats.Values(metric, stage, candidate),
+		Verdict:   VerdictOK,
+	}
+	rule, hasRule := rules[metric.Name]
+	if hasRule {
+		row.Rule = rule.String()
+	}
+	if len(row.Baseline) == 0 || len(row.Candidate) == 0 {
+		row.Verdict = VerdictMissing
+		return row
+	}
+	row.BaselineMedian = runstats.Median(row.Baseline)
+	row.CandidateMedian = runstats.Median(row.Candidate)
+	if row.BaselineMedian != 0 {
+		delta := (row.CandidateMedian - row.BaselineMedian) / math.Abs(row.BaselineMedian) * 100
+		row.DeltaPercent = &delta
+	}
+	_, p := MannWhitneyU(row.Baseline, row.Candidate)
+	row.PValue = &p
+
+	testable := MinPValue(len(row.Baseline), len(row.Candidate)) <= alpha
+	significant := testable && p < alpha
+	worse := (row.CandidateMedian > row.BaselineMedian) == metric.HigherIsWorse && row.CandidateMedian != row.BaselineMedian
+
+	switch {
+	case hasRule && rule.exceeded(row.BaselineMedian, row.CandidateMedian):
+		switch {
+		case !testable:
+			row.Verdict = VerdictUnverified
+		case significant:
+			row.Verdict = VerdictRegression
+		default:
+			row.Verdict = VerdictNotSignificant
+		}
+	case significant && worse:
+		row.Verdict = VerdictWorse
+	case significant:
+		row.Verdict = VerdictImproved
+	}
+	return row
+}
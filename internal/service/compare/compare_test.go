@@ -0,0 +1,135 @@
+package compare
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/d-iii-s/slsbench/internal/service/runstats"
+	"github.com/d-iii-s/slsbench/internal/service/summary"
+)
+
+func makeRun(dir string, p99, rps float64, stages ...string) *runstats.Run {
+	if len(stages) == 0 {
+		stages = []string{"browse"}
+	}
+	run := &runstats.Run{Dir: dir, Summary: &summary.RunSummary{
+		SchemaVersion: summary.RunSummarySchema,
+		FirstResponse: &summary.FirstResponse{DurationMillis: 1500},
+	}}
+	for _, name := range stages {
+		run.Summary.Stages = append(run.Summary.Stages, summary.StageSummary{
+			Stage: name,
+			Wrk2: &summary.Wrk2Result{
+				Requests:          1000,
+				RequestsPerSecond: rps,
+				Latency:           summary.LatencySummary{Percentiles: []summary.Percentile{{Percentile: 99, LatencyMillis: p99}}},
+			},
+		})
+	}
+	return run
+}
+
+func findRow(t *testing.T, result *Result, stage, metric string) Row {
+	t.Helper()
+	for _, row := range result.Rows {
+		if row.Stage == stage && row.Metric == metric {
+			return row
+		}
+	}
+	t.Fatalf("no row for %s/%s", stage, metric)
+	return Row{}
+}
+
+func TestParseRule(t *testing.T) {
+	cases := []struct {
+		input string
+		want  Rule
+	}{
+		{"p99=+10%", Rule{Metric: "p99", Limit: 0.1, Relative: true}},
+		{"rps=-5%", Rule{Metric: "rps", Limit: -0.05, Relative: true}},
+		{"first-response=+500ms", Rule{Metric: "first-response", Limit: 500}},
+		{"errors=+1", Rule{Metric: "errors", Limit: 1}},
+	}
+	for _, c := range cases {
+		got, err := ParseRule(c.input)
+		if err != nil || got != c.want {
+			t.Fatalf("ParseRule(%q) = %+v, %v; want %+v", c.input, got, err, c.want)
+		}
+		if got.String() != c.input {
+			t.Fatalf("String() = %q; want %q", got.String(), c.input)
+		}
+	}
+	for _, invalid := range []string{"p99", "p42=+10%", "p99=10%", "p99=+abc"} {
+		if _, err := ParseRule(invalid); err == nil {
+			t.Fatalf("expected an error for %q", invalid)
+		}
+	}
+}
+
+func TestParseRules_RejectsDuplicateMetric(t *testing.T) {
+	rules, err := ParseRules([]string{"p99=+10%", "rps=-5%"})
+	if err != nil || len(rules) != 2 {
+		t.Fatalf("ParseRules = %+v, %v; want two rules", rules, err)
+	}
+	if _, err := ParseRules([]string{"p99=+10%", "p99=+50ms"}); err == nil {
+		t.Fatalf("expected an error for a second p99 threshold")
+	}
+}
+
+func TestCompare_SignificantRegressionFails(t *testing.T) {
+	var baseline, candidate []*runstats.Run
+	for i, jitter := range []float64{0, 0.4, -0.3, 0.2, -0.1} {
+		baseline = append(baseline, makeRun("b"+string(rune('0'+i)), 10+jitter, 500))
+		candidate = append(candidate, makeRun("c"+string(rune('0'+i)), 13+jitter, 500))
+	}
+	result := Compare(baseline, candidate, Options{Rules: []Rule{{Metric: "p99", Limit: 0.1, Relative: true}}})
+
+	row := findRow(t, result, "browse", "p99")
+	if row.Verdict != VerdictRegression {
+		t.Fatalf("expected a regression, got %+v", row)
+	}
+	if err := result.Err(); !errors.Is(err, ErrRegression) || !strings.Contains(err.Error(), "browse/p99") {
+		t.Fatalf("expected ErrRegression naming browse/p99, got %v", err)
+	}
+	if findRow(t, result, "browse", "rps").Verdict != VerdictOK {
+		t.Fatalf("expected unchanged throughput to be ok")
+	}
+}
+
+func TestCompare_NoiseIsNotARegression(t *testing.T) {
+	var baseline, candidate []*runstats.Run
+	for i, p99 := range []float64{10, 14, 9, 13, 11} {
+		baseline = append(baseline, makeRun("b"+string(rune('0'+i)), p99, 500))
+	}
+	for i, p99 := range []float64{12.5, 9.5, 14.5, 13, 10.5} {
+		candidate = append(candidate, makeRun("c"+string(rune('0'+i)), p99, 500))
+	}
+	result := Compare(baseline, candidate, Options{Rules: []Rule{{Metric: "p99", Limit: 0.1, Relative: true}}})
+	if row := findRow(t, result, "browse", "p99"); row.Verdict != VerdictNotSignificant {
+		t.Fatalf("expected the median shift to be attributed to noise, got %+v", row)
+	}
+	if err := result.Err(); err != nil {
+		t.Fatalf("expected no regression, got %v", err)
+	}
+}
+
+func TestCompare_SingleRunsAreUnverified(t *testing.T) {
+	result := Compare(
+		[]*runstats.Run{makeRun("b", 10, 500, "browse", "checkout")},
+		[]*runstats.Run{makeRun("c", 10, 400, "browse", "search")},
+		Options{Rules: []Rule{{Metric: "rps", Limit: -0.05, Relative: true}}},
+	)
+	if row := findRow(t, result, "browse", "rps"); row.Verdict != VerdictUnverified {
+		t.Fatalf("expected an unverified regression, got %+v", row)
+	}
+	if !errors.Is(result.Err(), ErrRegression) {
+		t.Fatalf("expected unverified regressions to fail")
+	}
+	notes := strings.Join(result.Notes, "\n")
+	for _, want := range []string{"cannot reach significance", `"checkout" only exists in the baseline`, `"search" only exists in the candidate`} {
+		if !strings.Contains(notes, want) {
+			t.Fatalf("notes %q do not mention %q", notes, want)
+		}
+	}
+}
@@ -0,0 +1,66 @@
+package compare
+
+import (
+	"fmt"
+	"io"
+	"math"
+	"text/tabwriter"
+)
+
+// WriteText renders the comparison as an aligned table followed by notes and
+// the list of failed rules.
+func (r *Result) WriteText(w io.Writer) error {
+	fmt.Fprintf(w, "baseline:  %d run(s)\ncandidate: %d run(s)\nalpha:     %g\n", len(r.BaselineRuns), len(r.CandidateRuns), r.Alpha)
+	if len(r.Rules) > 0 {
+		fmt.Fprintf(w, "rules:     %v\n", r.Rules)
+	}
+	fmt.Fprintln(w)
+
+	table := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(table, "STAGE\tMETRIC\tBASELINE\tCANDIDATE\tDELTA\tP\tVERDICT\tRULE")
+	for _, row := range r.Rows {
+		stage := row.Stage
+		if stage == "" {
+			stage = "(run)"
+		}
+		delta, p := "—", "—"
+		if row.DeltaPercent != nil {
+			delta = fmt.Sprintf("%+.1f%%", *row.DeltaPercent)
+		}
+		if row.PValue != nil {
+			p = fmt.Sprintf("%.3g", *row.PValue)
+		}
+		fmt.Fprintf(table, "%s\t%s\t%s\t%s\t%s\t%s\t%s\t%s\n",
+			stage, row.Metric,
+			formatValue(row.BaselineMedian, row.Unit, len(row.Baseline)),
+			formatValue(row.CandidateMedian, row.Unit, len(row.Candidate)),
+			delta, p, row.Verdict, row.Rule)
+	}
+	if err := table.Flush(); err != nil {
+		return err
+	}
+
+	for _, note := range r.Notes {
+		fmt.Fprintf(w, "\nnote: %s", note)
+	}
+	if len(r.Notes) > 0 {
+		fmt.Fprintln(w)
+	}
+	failed := r.Regressions()
+	if len(failed) == 0 {
+		fmt.Fprintln(w, "\nresult: no regression")
+		return nil
+	}
+	fmt.Fprintf(w, "\nresult: %d regression(s)\n", len(failed))
+	for _, row := range failed {
+		fmt.Fprintf(w, "  %s violates %s (%s)\n", rowLabel(row), row.Rule, row.Verdict)
+	}
+	return nil
+}
+
+func formatValue(median float64, unit string, samples int) string {
+	if samples == 0 || math.IsNaN(median) {
+		return "—"
+	}
+	return fmt.Sprintf("%.2f %s", median, unit)
+}
@@ -0,0 +1,137 @@
+package compare
+
+import (
+	"math"
+	"sort"
+)
+
+// exactLimit bounds n1+n2 for the exact Mann-Whitney distribution; larger
+// samples use the normal approximation.
+const exactLimit = 40
+
+// MannWhitneyU runs a two-sided Mann-Whitney U test on two independent
+// samples and returns U (for a) and the p-value. Without ties and for small
+// samples the p-value is exact; otherwise it uses the normal approximation
+// with tie and continuity correction. Empty samples yield p = 1.
+func MannWhitneyU(a, b []float64) (u, p float64) {
+	n1, n2 := len(a), len(b)
+	if n1 == 0 || n2 == 0 {
+		return 0, 1
+	}
+
+	type obs struct {
+		value float64
+		fromA bool
+	}
+	all := make([]obs, 0, n1+n2)
+	for _, v := range a {
+		all = append(all, obs{v, true})
+	}
+	for _, v := range b {
+		all = append(all, obs{v, false})
+	}
+	sort.Slice(all, func(i, j int) bool { return all[i].value < all[j].value })
+
+	// Average ranks over ties and collect the tie correction term.
+	var rankSumA, tieTerm float64
+	hasTies := false
+	for i := 0; i < len(all); {
+		j := i
+		for j < len(all) && all[j].value == all[i].value {
+			j++
+		}
+		rank := float64(i+j+1) / 2
+		for k := i; k < j; k++ {
+			if all[k].fromA {
+				rankSumA += rank
+			}
+		}
+		if t := float64(j - i); t > 1 {
+			hasTies = true
+			tieTerm += t*t*t - t
+		}
+		i = j
+	}
+	u = rankSumA - float64(n1*(n1+1))/2
+
+	if !hasTies && n1+n2 <= exactLimit {
+		return u, exactPValue(n1, n2, u)
+	}
+
+	n := float64(n1 + n2)
+	mean := float64(n1*n2) / 2
+	variance := float64(n1*n2) / 12 * ((n + 1) - tieTerm/(n*(n-1)))
+	if variance <= 0 {
+		return u, 1
+	}
+	diff := math.Abs(u-mean) - 0.5
+	if diff < 0 {
+		diff = 0
+	}
+	z := diff / math.Sqrt(variance)
+	return u, math.Min(1, math.Erfc(z/math.Sqrt2))
+}
+
+// exactPValue returns the two-sided p-value of U under the null hypothesis,
+// counting the arrangements of n1+n2 ranks that give each U.
+func exactPValue(n1, n2 int, u float64) float64 {
+	dist := uDistribution(n1, n2)
+	total := 0.0
+	for _, count := range dist {
+		total += count
+	}
+	k := int(math.Round(u))
+	var lower, upper float64
+	for i, count := range dist {
+		if i <= k {
+			lower += count
+		}
+		if i >= k {
+			upper += count
+		}
+	}
+	return math.Min(1, 2*math.Min(lower, upper)/total)
+}
+
+// uDistribution returns the number of rank arrangements giving each U in
+// 0..n1*n2, via the recurrence f(m, n, u) = f(m-1, n, u-n) + f(m, n-1, u).
+func uDistribution(n1, n2 int) []float64 {
+	// prev[n][u] holds f(m-1, n, u) while row m is built.
+	prev := make([][]float64, n2+1)
+	for n := range prev {
+		prev[n] = []float64{1}
+	}
+	for m := 1; m <= n1; m++ {
+		cur := make([][]float64, n2+1)
+		cur[0] = []float64{1}
+		for n := 1; n <= n2; n++ {
+			row := make([]float64, m*n+1)
+			for v := range row {
+				if v-n >= 0 && v-n < len(prev[n]) {
+					row[v] += prev[n][v-n]
+				}
+				if v < len(cur[n-1]) {
+					row[v] += cur[n-1][v]
+				}
+			}
+			cur[n] = row
+		}
+		prev = cur
+	}
+	return prev[n2]
+}
+
+// MinPValue is the smallest two-sided p-value an exact test can produce for
+// the given sample sizes; if it exceeds alpha, no difference can be
+// significant.
+func MinPValue(n1, n2 int) float64 {
+	if n1 == 0 || n2 == 0 {
+		return 1
+	}
+	// 2 / C(n1+n2, n1)
+	combinations := 1.0
+	for i := 1; i <= n1; i++ {
+		combinations = combinations * float64(n2+i) / float64(i)
+	}
+	return math.Min(1, 2/combinations)
+}
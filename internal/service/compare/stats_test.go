@@ -0,0 +1,50 @@
+package compare
+
+import (
+	"math"
+	"testing"
+)
+
+func TestMannWhitneyU_ExactSmallSamples(t *testing.T) {
+	// Complete separation of 4 vs 4: p = 2/C(8,4) = 2/70.
+	u, p := MannWhitneyU([]float64{1, 2, 3, 4}, []float64{5, 6, 7, 8})
+	if u != 0 || math.Abs(p-2.0/70) > 1e-12 {
+		t.Fatalf("got U=%v p=%v; want U=0 p=%v", u, p, 2.0/70)
+	}
+	// Interleaved samples are not significant.
+	if _, p := MannWhitneyU([]float64{1, 3, 5, 7}, []float64{2, 4, 6, 8}); p < 0.5 {
+		t.Fatalf("expected a large p for interleaved samples, got %v", p)
+	}
+}
+
+func TestMannWhitneyU_NormalApproximationWithTies(t *testing.T) {
+	a := []float64{10, 10, 11, 12, 12, 13, 14, 14, 15, 15, 16, 16, 17, 18, 18, 19, 20, 20, 21, 22, 22, 23}
+	b := make([]float64, len(a))
+	for i, v := range a {
+		b[i] = v + 8
+	}
+	_, p := MannWhitneyU(a, b)
+	if p >= 0.001 {
+		t.Fatalf("expected a clear shift to be significant, got p=%v", p)
+	}
+	if _, p := MannWhitneyU(a, a); p < 0.99 {
+		t.Fatalf("expected identical samples to give p≈1, got %v", p)
+	}
+}
+
+func TestMinPValue(t *testing.T) {
+	cases := []struct {
+		n1, n2 int
+		want   float64
+	}{
+		{1, 1, 1},
+		{3, 3, 0.1},
+		{4, 4, 2.0 / 70},
+		{0, 5, 1},
+	}
+	for _, c := range cases {
+		if got := MinPValue(c.n1, c.n2); math.Abs(got-c.want) > 1e-12 {
+			t.Fatalf("MinPValue(%d, %d) = %v; want %v", c.n1, c.n2, got, c.want)
+		}
+	}
+}
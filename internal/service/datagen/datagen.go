@@ -24,6 +24,7 @@ var (
 type StatefulStep struct {
 	IterationID  int            `json:"iterationIndex"`
 	Stage        string         `json:"stage,omitempty"`
+	Node         string         `json:"node,omitempty"`
 	FlowID       string         `json:"flowId,omitempty"`
 	OperationID  string         `json:"operationId,omitempty"`
 	Method       string         `json:"method"`
@@ -44,6 +45,9 @@ type StatefulChain struct {
 }
 
 type MinimalIterationStep struct {
+	// Node is the flow node that sent the request. Several nodes may call
+	// the same operation, so it is not implied by FlowID.
+	Node         string         `json:"node,omitempty"`
 	FlowID       string         `json:"flowId"`
 	Method       string         `json:"method"`
 	PathTemplate string         `json:"pathTemplate"`
@@ -52,6 +56,19 @@ type MinimalIterationStep struct {
 	Query        map[string]any `json:"query"`
 	ResolvedPath string         `json:"resolvedPath"`
 	RequestBody  any            `json:"requestBody"`
+	// ThinkTime is the pause before the step, set by the harness from the
+	// flow DSL. The executor draws it anew every time it replays the step.
+	ThinkTime *StepThinkTime `json:"thinkTime,omitempty"`
+}
+
+// StepThinkTime is the think time distribution of a step in milliseconds:
+// a constant or exponential distribution with MeanMillis, or a uniform one
+// between MinMillis and MaxMillis.
+type StepThinkTime struct {
+	Distribution string  `json:"distribution"`
+	MeanMillis   float64 `json:"meanMillis,omitempty"`
+	MinMillis    float64 `json:"minMillis,omitempty"`
+	MaxMillis    float64 `json:"maxMillis,omitempty"`
 }
 
 type MinimalIteration struct {
@@ -82,6 +99,7 @@ func ProjectMinimalIterations(chains []StatefulChain) []MinimalIteration {
 				resolvedPath = stageScopedValue
 			}
 			steps = append(steps, MinimalIterationStep{
+				Node:         step.Node,
 				FlowID:       step.FlowID,
 				Method:       step.Method,
 				PathTemplate: step.PathTemplate,
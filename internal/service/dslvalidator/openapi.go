@@ -0,0 +1,135 @@
+package dslvalidator
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+var httpMethods = []string{"get", "put", "post", "delete", "options", "head", "patch", "trace"}
+
+// OpenAPIIndex is the subset of an OpenAPI document needed to check a flow:
+// which operationIds exist and which operations each one links to.
+type OpenAPIIndex struct {
+	operations map[string]struct{}
+	links      map[string]map[string]struct{}
+}
+
+// HasOperation reports whether operationID is defined in the spec.
+func (o *OpenAPIIndex) HasOperation(operationID string) bool {
+	_, ok := o.operations[operationID]
+	return ok
+}
+
+// HasLink reports whether any response of operation from declares an
+// OpenAPI Link whose target is operation to.
+func (o *OpenAPIIndex) HasLink(from, to string) bool {
+	_, ok := o.links[from][to]
+	return ok
+}
+
+// LoadOpenAPIIndex reads an OpenAPI document (YAML or JSON) from a file path
+// or an http(s) URL and indexes its operations and links.
+func LoadOpenAPIIndex(location string) (*OpenAPIIndex, error) {
+	raw, err := readOpenAPIDocument(location)
+	if err != nil {
+		return nil, err
+	}
+	var doc map[string]any
+	if err := yaml.Unmarshal(raw, &doc); err != nil {
+		return nil, fmt.Errorf("failed to parse OpenAPI spec %q: %w", location, err)
+	}
+	return newOpenAPIIndex(doc), nil
+}
+
+func readOpenAPIDocument(location string) ([]byte, error) {
+	lower := strings.ToLower(location)
+	if !strings.HasPrefix(lower, "http://") && !strings.HasPrefix(lower, "https://") {
+		raw, err := os.ReadFile(location)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read OpenAPI spec %q: %w", location, err)
+		}
+		return raw, nil
+	}
+	client := &http.Client{Timeout: 30 * time.Second}
+	resp, err := client.Get(location)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch OpenAPI spec %q: %w", location, err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return nil, fmt.Errorf("failed to fetch OpenAPI spec %q: status %d", location, resp.StatusCode)
+	}
+	return io.ReadAll(resp.Body)
+}
+
+func newOpenAPIIndex(doc map[string]any) *OpenAPIIndex {
+	index := &OpenAPIIndex{
+		operations: map[string]struct{}{},
+		links:      map[string]map[string]struct{}{},
+	}
+	componentLinks := asMap(asMap(doc["components"])["links"])
+	paths := asMap(doc["paths"])
+
+	// operationRef values point at "#/paths/<escaped path>/<method>", so the
+	// operationId of every path+method pair is needed to resolve them.
+	refToOperationID := map[string]string{}
+	for path, rawItem := range paths {
+		for _, method := range httpMethods {
+			operationID, _ := asMap(asMap(rawItem)[method])["operationId"].(string)
+			if operationID == "" {
+				continue
+			}
+			index.operations[operationID] = struct{}{}
+			refToOperationID["#/paths/"+escapeJSONPointerToken(path)+"/"+method] = operationID
+		}
+	}
+
+	for _, rawItem := range paths {
+		for _, method := range httpMethods {
+			operation := asMap(asMap(rawItem)[method])
+			operationID, _ := operation["operationId"].(string)
+			if operationID == "" {
+				continue
+			}
+			for _, rawResponse := range asMap(operation["responses"]) {
+				for _, rawLink := range asMap(asMap(rawResponse)["links"]) {
+					link := asMap(rawLink)
+					if ref, ok := link["$ref"].(string); ok {
+						name := strings.TrimPrefix(ref, "#/components/links/")
+						link = asMap(componentLinks[name])
+					}
+					target, _ := link["operationId"].(string)
+					if target == "" {
+						ref, _ := link["operationRef"].(string)
+						if i := strings.Index(ref, "#"); i >= 0 {
+							target = refToOperationID[ref[i:]]
+						}
+					}
+					if target == "" {
+						continue
+					}
+					if index.links[operationID] == nil {
+						index.links[operationID] = map[string]struct{}{}
+					}
+					index.links[operationID][target] = struct{}{}
+				}
+			}
+		}
+	}
+	return index
+}
+
+func asMap(v any) map[string]any {
+	m, _ := v.(map[string]any)
+	return m
+}
+
+func escapeJSONPointerToken(token string) string {
+	return strings.NewReplacer("~", "~0", "/", "~1").Replace(token)
+}
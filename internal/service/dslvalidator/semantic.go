@@ -0,0 +1,465 @@
+package dslvalidator
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/d-iii-s/slsbench/internal/service/flowgen"
+	jsonschema "github.com/santhosh-tekuri/jsonschema/v6"
+	"gopkg.in/yaml.v3"
+)
+
+// Severity ranks a validation Issue.
+type Severity string
+
+const (
+	SeverityError   Severity = "error"
+	SeverityWarning Severity = "warning"
+)
+
+// Issue is a single finding in a flow file, positioned at the YAML node it
+// refers to. Line and Column are 1-based; zero means the position is unknown.
+type Issue struct {
+	Severity Severity `json:"severity"`
+	Line     int      `json:"line"`
+	Column   int      `json:"column"`
+	Stage    string   `json:"stage,omitempty"`
+	Node     string   `json:"node,omitempty"`
+	Message  string   `json:"message"`
+}
+
+func (i Issue) String() string {
+	return fmt.Sprintf("%d:%d: %s: %s", i.Line, i.Column, i.Severity, i.Text())
+}
+
+// Text returns the message prefixed with the stage, if the issue has one.
+func (i Issue) Text() string {
+	if i.Stage == "" {
+		return i.Message
+	}
+	return fmt.Sprintf("stage %q: %s", i.Stage, i.Message)
+}
+
+// HasErrors reports whether any issue has error severity.
+func HasErrors(issues []Issue) bool {
+	for _, issue := range issues {
+		if issue.Severity == SeverityError {
+			return true
+		}
+	}
+	return false
+}
+
+// ValidateFile runs the JSON Schema and the semantic flow checks against the
+// DSL file at flowPath. When openAPISpecPath is non-empty the flow is also
+// checked against that spec. The returned error is reserved for failures to
+// read or parse the inputs; validation findings are returned as issues,
+// sorted by position.
+func ValidateFile(ctx context.Context, flowPath, openAPISpecPath string) ([]Issue, error) {
+	data, err := os.ReadFile(flowPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open DSL file %q: %w", flowPath, err)
+	}
+	var spec *OpenAPIIndex
+	if strings.TrimSpace(openAPISpecPath) != "" {
+		spec, err = LoadOpenAPIIndex(openAPISpecPath)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return ValidateBytes(ctx, data, spec)
+}
+
+// ValidateBytes is ValidateFile for in-memory DSL content. spec may be nil.
+func ValidateBytes(ctx context.Context, data []byte, spec *OpenAPIIndex) ([]Issue, error) {
+	var root yaml.Node
+	if err := yaml.NewDecoder(bytes.NewReader(data)).Decode(&root); err != nil {
+		return nil, fmt.Errorf("failed to parse DSL YAML: %w", err)
+	}
+	var doc any
+	if err := root.Decode(&doc); err != nil {
+		return nil, fmt.Errorf("failed to parse DSL YAML: %w", err)
+	}
+
+	issues := schemaIssues(ValidateDSL(ctx, doc), &root)
+
+	dsl, err := flowgen.ParseDSLBytes(data)
+	if err != nil {
+		// Schema findings usually explain why the flow could not be read;
+		// only surface the parse error on its own when there are none.
+		if len(issues) == 0 {
+			issues = append(issues, Issue{Severity: SeverityError, Line: 1, Column: 1, Message: err.Error()})
+		}
+	} else {
+		issues = append(issues, CheckSemantics(dsl, spec)...)
+	}
+
+	sort.SliceStable(issues, func(i, j int) bool {
+		if issues[i].Line != issues[j].Line {
+			return issues[i].Line < issues[j].Line
+		}
+		return issues[i].Column < issues[j].Column
+	})
+	return issues, nil
+}
+
+// schemaIssues flattens a jsonschema validation error into positioned issues.
+func schemaIssues(err error, root *yaml.Node) []Issue {
+	if err == nil {
+		return nil
+	}
+	var validationErr *jsonschema.ValidationError
+	if !errors.As(err, &validationErr) {
+		return []Issue{{Severity: SeverityError, Line: 1, Column: 1, Message: err.Error()}}
+	}
+	var issues []Issue
+	for _, unit := range validationErr.BasicOutput().Errors {
+		if unit.Error == nil {
+			continue
+		}
+		message := unit.Error.String()
+		// The basic output repeats umbrella errors for every failing
+		// subschema; only the leaves carry actionable messages.
+		if strings.HasPrefix(message, "validation failed") {
+			continue
+		}
+		pos := yamlPosition(root, unit.InstanceLocation)
+		if unit.InstanceLocation != "" {
+			message = fmt.Sprintf("at %s: %s", unit.InstanceLocation, message)
+		}
+		issues = append(issues, Issue{
+			Severity: SeverityError,
+			Line:     pos.Line,
+			Column:   pos.Column,
+			Message:  message,
+		})
+	}
+	return issues
+}
+
+// yamlPosition resolves a JSON pointer against the YAML tree and returns the
+// position of the deepest node it reaches.
+func yamlPosition(root *yaml.Node, pointer string) flowgen.Position {
+	node := root
+	if node.Kind == yaml.DocumentNode && len(node.Content) > 0 {
+		node = node.Content[0]
+	}
+	pos := flowgen.Position{Line: node.Line, Column: node.Column}
+	if pointer == "" || pointer == "/" {
+		return pos
+	}
+	for _, raw := range strings.Split(strings.TrimPrefix(pointer, "/"), "/") {
+		token := strings.NewReplacer("~1", "/", "~0", "~").Replace(raw)
+		var next *yaml.Node
+		switch node.Kind {
+		case yaml.MappingNode:
+			for i := 0; i+1 < len(node.Content); i += 2 {
+				if node.Content[i].Value == token {
+					// Point at the key so the annotation lands on the
+					// offending property rather than its value.
+					pos = flowgen.Position{Line: node.Content[i].Line, Column: node.Content[i].Column}
+					next = node.Content[i+1]
+					break
+				}
+			}
+		case yaml.SequenceNode:
+			idx, err := strconv.Atoi(token)
+			if err == nil && idx >= 0 && idx < len(node.Content) {
+				next = node.Content[idx]
+				pos = flowgen.Position{Line: next.Line, Column: next.Column}
+			}
+		}
+		if next == nil {
+			return pos
+		}
+		node = next
+	}
+	return pos
+}
+
+// CheckSemantics checks the load and the flow graph of every stage: edge
+// targets, entry nodes, reachability, closed cycles, edge weights and think
+// times. When spec is non-nil, operationIds and edges are also checked
+// against the OpenAPI document.
+func CheckSemantics(dsl *flowgen.DSL, spec *OpenAPIIndex) []Issue {
+	stageNames := make([]string, 0, len(dsl.Stages))
+	for name := range dsl.Stages {
+		stageNames = append(stageNames, name)
+	}
+	sort.Strings(stageNames)
+
+	var issues []Issue
+	for _, stageName := range stageNames {
+		issues = append(issues, checkStage(stageName, dsl.Stages[stageName], spec)...)
+	}
+	return issues
+}
+
+func checkStage(stageName string, stage flowgen.Stage, spec *OpenAPIIndex) []Issue {
+	var issues []Issue
+	report := func(severity Severity, pos flowgen.Position, node, format string, args ...any) {
+		issues = append(issues, Issue{
+			Severity: severity,
+			Line:     pos.Line,
+			Column:   pos.Column,
+			Stage:    stageName,
+			Node:     node,
+			Message:  fmt.Sprintf(format, args...),
+		})
+	}
+
+	if len(stage.Flow) == 0 {
+		report(SeverityError, stage.Pos, "", "flow is empty")
+		return issues
+	}
+
+	if _, err := stage.LoadProfile(); err != nil {
+		report(SeverityError, stage.Pos, "", "invalid load: %v", err)
+	}
+	if stage.ThinkTime != nil {
+		if err := stage.ThinkTime.Validate(); err != nil {
+			report(SeverityError, stage.Pos, "", "%v", err)
+		}
+	}
+
+	nodes := make(map[string]flowgen.FlowNode, len(stage.Flow))
+	var entries []flowgen.FlowNode
+	for _, node := range stage.Flow {
+		if _, dup := nodes[node.Name]; dup {
+			report(SeverityError, node.Pos, node.Name, "duplicate node name %q", node.Name)
+			continue
+		}
+		nodes[node.Name] = node
+		if node.EntryNode {
+			entries = append(entries, node)
+		}
+	}
+
+	if len(entries) == 0 {
+		report(SeverityError, stage.Pos, "", "no entry node (set entrynode: true on at least one node)")
+	}
+	for _, node := range stage.Flow {
+		switch {
+		case node.EntryWeight != nil && !node.EntryNode:
+			report(SeverityError, node.Pos, node.Name, "node %q has an entryweight but is not an entry node", node.Name)
+		case node.EntryWeight != nil && *node.EntryWeight <= 0:
+			report(SeverityError, node.Pos, node.Name, "entry node %q has non-positive entryweight %v", node.Name, *node.EntryWeight)
+		}
+		if node.MaxVisits < 0 {
+			report(SeverityError, node.Pos, node.Name, "node %q has negative maxvisits %d", node.Name, node.MaxVisits)
+		}
+		if node.ExitWeight < 0 {
+			report(SeverityError, node.Pos, node.Name, "node %q has negative exitweight %v", node.Name, node.ExitWeight)
+		}
+	}
+
+	for _, node := range stage.Flow {
+		for _, edge := range node.Edges {
+			if _, ok := nodes[edge.To]; !ok {
+				report(SeverityError, edge.Pos, node.Name, "node %q has edge to unknown node %q", node.Name, edge.To)
+			}
+			if edge.Weight <= 0 {
+				report(SeverityError, edge.Pos, node.Name, "edge %q -> %q has non-positive weight %v", node.Name, edge.To, edge.Weight)
+			}
+			if edge.MaxTraversals < 0 {
+				report(SeverityError, edge.Pos, node.Name, "edge %q -> %q has negative maxtraversals %d", node.Name, edge.To, edge.MaxTraversals)
+			}
+			if edge.ThinkTime != nil {
+				if err := edge.ThinkTime.Validate(); err != nil {
+					report(SeverityError, edge.Pos, node.Name, "edge %q -> %q: %v", node.Name, edge.To, err)
+				}
+				if edge.ThinkTime.Seed != 0 {
+					report(SeverityWarning, edge.Pos, node.Name, "edge %q -> %q: the seed of an edge thinktime is ignored; set it on the stage thinktime", node.Name, edge.To)
+				}
+			}
+		}
+	}
+
+	if len(entries) > 0 {
+		reachable := reachableNodes(nodes, entries)
+		for _, node := range stage.Flow {
+			if !reachable[node.Name] {
+				report(SeverityWarning, node.Pos, node.Name, "node %q is not reachable from any entry node", node.Name)
+			}
+		}
+	}
+
+	for _, component := range closedCycles(stage.Flow, nodes) {
+		first := nodes[component[0]]
+		report(SeverityError, first.Pos, first.Name, "cycle %s has no exit; iterations starting in it never terminate without an exitweight, maxvisits or maxtraversals", strings.Join(component, " -> "))
+	}
+
+	if spec == nil {
+		return issues
+	}
+	for _, node := range stage.Flow {
+		if !spec.HasOperation(node.OperationID) {
+			report(SeverityError, node.Pos, node.Name, "operationId %q of node %q is not defined in the OpenAPI spec", node.OperationID, node.Name)
+			continue
+		}
+		for _, edge := range node.Edges {
+			target, ok := nodes[edge.To]
+			if !ok || !spec.HasOperation(target.OperationID) {
+				continue
+			}
+			if !spec.HasLink(node.OperationID, target.OperationID) {
+				report(SeverityError, edge.Pos, node.Name, "edge %q -> %q has no OpenAPI Link from %q to %q", node.Name, edge.To, node.OperationID, target.OperationID)
+			}
+		}
+	}
+	return issues
+}
+
+func reachableNodes(nodes map[string]flowgen.FlowNode, entries []flowgen.FlowNode) map[string]bool {
+	seen := make(map[string]bool, len(nodes))
+	queue := make([]string, 0, len(nodes))
+	for _, entry := range entries {
+		seen[entry.Name] = true
+		queue = append(queue, entry.Name)
+	}
+	for len(queue) > 0 {
+		current := queue[0]
+		queue = queue[1:]
+		for _, edge := range nodes[current].Edges {
+			if _, ok := nodes[edge.To]; ok && !seen[edge.To] {
+				seen[edge.To] = true
+				queue = append(queue, edge.To)
+			}
+		}
+	}
+	return seen
+}
+
+// closedCycles returns the strongly connected components that contain a cycle
+// and have neither an edge leaving them nor a node with an exitweight, each
+// listed in flow order. A component is
+// left out when maxvisits and maxtraversals bound every cycle in it, because
+// an iteration then ends once no edge is left to take.
+func closedCycles(flow []flowgen.FlowNode, nodes map[string]flowgen.FlowNode) [][]string {
+	order := make(map[string]int, len(flow))
+	for i, node := range flow {
+		if _, seen := order[node.Name]; !seen {
+			order[node.Name] = i
+		}
+	}
+
+	// Tarjan's algorithm.
+	index := 0
+	indices := map[string]int{}
+	lowlink := map[string]int{}
+	onStack := map[string]bool{}
+	var stack []string
+	var components [][]string
+	var strongConnect func(name string)
+	strongConnect = func(name string) {
+		indices[name] = index
+		lowlink[name] = index
+		index++
+		stack = append(stack, name)
+		onStack[name] = true
+		for _, edge := range nodes[name].Edges {
+			if _, ok := nodes[edge.To]; !ok {
+				continue
+			}
+			if _, visited := indices[edge.To]; !visited {
+				strongConnect(edge.To)
+				lowlink[name] = min(lowlink[name], lowlink[edge.To])
+			} else if onStack[edge.To] {
+				lowlink[name] = min(lowlink[name], indices[edge.To])
+			}
+		}
+		if lowlink[name] != indices[name] {
+			return
+		}
+		var component []string
+		for {
+			top := stack[len(stack)-1]
+			stack = stack[:len(stack)-1]
+			onStack[top] = false
+			component = append(component, top)
+			if top == name {
+				break
+			}
+		}
+		components = append(components, component)
+	}
+	for _, node := range flow {
+		if _, visited := indices[node.Name]; !visited {
+			strongConnect(node.Name)
+		}
+	}
+
+	var closed [][]string
+	for _, component := range components {
+		members := make(map[string]bool, len(component))
+		for _, name := range component {
+			members[name] = true
+		}
+		cyclic := len(component) > 1
+		hasExit := false
+		for _, name := range component {
+			node := nodes[name]
+			if len(node.Edges) == 0 || node.ExitWeight > 0 {
+				hasExit = true
+			}
+			for _, edge := range node.Edges {
+				if _, ok := nodes[edge.To]; !ok {
+					continue
+				}
+				if edge.To == name {
+					cyclic = true
+				}
+				if !members[edge.To] {
+					hasExit = true
+				}
+			}
+		}
+		if cyclic && !hasExit && unboundedCycle(members, nodes) {
+			sort.Slice(component, func(i, j int) bool { return order[component[i]] < order[component[j]] })
+			closed = append(closed, component)
+		}
+	}
+	return closed
+}
+
+// unboundedCycle reports whether the members have a cycle that passes no node
+// with maxvisits and no edge with maxtraversals.
+func unboundedCycle(members map[string]bool, nodes map[string]flowgen.FlowNode) bool {
+	const (
+		unvisited = iota
+		active
+		finished
+	)
+	state := make(map[string]int, len(members))
+	var visit func(name string) bool
+	visit = func(name string) bool {
+		state[name] = active
+		for _, edge := range nodes[name].Edges {
+			if !members[edge.To] || edge.MaxTraversals > 0 || nodes[edge.To].MaxVisits > 0 {
+				continue
+			}
+			switch state[edge.To] {
+			case active:
+				return true
+			case unvisited:
+				if visit(edge.To) {
+					return true
+				}
+			}
+		}
+		state[name] = finished
+		return false
+	}
+	for name := range members {
+		if nodes[name].MaxVisits == 0 && state[name] == unvisited && visit(name) {
+			return true
+		}
+	}
+	return false
+}
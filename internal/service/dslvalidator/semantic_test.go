@@ -0,0 +1,306 @@
+package dslvalidator
+
+import (
+	"context"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/d-iii-s/slsbench/internal/service/flowgen"
+)
+
+func TestValidateFile_ValidFlowHasNoIssues(t *testing.T) {
+	issues, err := ValidateFile(context.Background(), filepath.Join("testdata", "valid-dsl.yaml"), "")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(issues) != 0 {
+		t.Fatalf("expected no issues, got %v", issues)
+	}
+}
+
+func TestValidateFile_SchemaIssueCarriesPosition(t *testing.T) {
+	data := []byte("stages:\n  s1:\n    wrk2params: -d1s -R1\n    flow:\n      - a:\n        operationId: opA\n        entrynode: \"yes\"\n")
+	issues, err := ValidateBytes(context.Background(), data, nil)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	for _, issue := range issues {
+		if strings.Contains(issue.Message, "/entrynode") {
+			if issue.Line != 7 || issue.Column != 9 {
+				t.Fatalf("expected issue at 7:9, got %d:%d (%s)", issue.Line, issue.Column, issue.Message)
+			}
+			return
+		}
+	}
+	t.Fatalf("expected a schema issue for entrynode, got %v", issues)
+}
+
+func TestValidateFile_SemanticIssues(t *testing.T) {
+	issues, err := ValidateFile(
+		context.Background(),
+		filepath.Join("testdata", "semantic-dsl.yaml"),
+		filepath.Join("testdata", "openapi.yaml"),
+	)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	expected := []struct {
+		line     int
+		severity Severity
+		contains string
+	}{
+		{9, SeverityError, "non-positive weight"},
+		{11, SeverityError, `unknown node "missing"`},
+		{13, SeverityError, "cycle fetch -> list has no exit"},
+		{16, SeverityError, `no OpenAPI Link from "getOwner" to "listX"`},
+		{23, SeverityWarning, `"orphan" is not reachable`},
+		{23, SeverityError, `"unknownOp" of node "orphan" is not defined`},
+	}
+	for _, want := range expected {
+		found := false
+		for _, issue := range issues {
+			if issue.Line == want.line && issue.Severity == want.severity && strings.Contains(issue.Message, want.contains) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			t.Errorf("missing %s at line %d containing %q; got %v", want.severity, want.line, want.contains, issues)
+		}
+	}
+	if len(issues) != len(expected) {
+		t.Errorf("expected %d issues, got %d: %v", len(expected), len(issues), issues)
+	}
+}
+
+func TestCheckSemantics_EntryNodes(t *testing.T) {
+	data := []byte(`
+stages:
+  none:
+    wrk2params: -d1s -R1
+    flow:
+      - a:
+        operationId: opA
+  many:
+    wrk2params: -d1s -R1
+    flow:
+      - a:
+        operationId: opA
+        entrynode: true
+        entryweight: 3
+      - b:
+        operationId: opB
+        entrynode: true
+      - c:
+        operationId: opC
+        entryweight: 2
+`)
+	issues, err := ValidateBytes(context.Background(), data, nil)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	var sawNone, sawStrayWeight bool
+	for _, issue := range issues {
+		switch {
+		case issue.Stage == "none" && strings.Contains(issue.Message, "no entry node"):
+			if strings.Contains(issue.Message, "none") || !strings.Contains(issue.String(), `stage "none": `) {
+				t.Errorf("the stage belongs in the Stage field and String only, got %q", issue.String())
+			}
+			sawNone = true
+		case issue.Stage == "many" && issue.Node == "c" && strings.Contains(issue.Message, "not an entry node"):
+			sawStrayWeight = true
+		case issue.Stage == "many" && issue.Severity == SeverityError:
+			t.Errorf("weighted entry nodes must be accepted, got %v", issue)
+		}
+	}
+	if !sawNone || !sawStrayWeight {
+		t.Fatalf("expected missing entry node and stray entryweight issues, got %v", issues)
+	}
+
+	// An explicit 0 is not the default weight.
+	dsl, err := flowgen.ParseDSLBytes([]byte(`
+stages:
+  zero:
+    wrk2params: -d1s -R1
+    flow:
+      - a:
+        operationId: opA
+        entrynode: true
+        entryweight: 0
+`))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	issues = CheckSemantics(dsl, nil)
+	if len(issues) != 1 || issues[0].Node != "a" || !strings.Contains(issues[0].Message, "non-positive entryweight") {
+		t.Fatalf("expected entryweight 0 to be rejected, got %v", issues)
+	}
+}
+
+func TestCheckSemantics_BoundedAndExitingCycles(t *testing.T) {
+	data := []byte(`
+stages:
+  cart:
+    wrk2params: -d1s -R1
+    flow:
+      - add:
+        operationId: addToCart
+        entrynode: true
+        maxvisits: 5
+        edges:
+          - to: add
+            weight: 1
+  pingpong:
+    wrk2params: -d1s -R1
+    flow:
+      - ping:
+        operationId: ping
+        entrynode: true
+        edges:
+          - to: pong
+            weight: 1
+      - pong:
+        operationId: pong
+        edges:
+          - to: ping
+            weight: 1
+            maxtraversals: 3
+  browse:
+    wrk2params: -d1s -R1
+    flow:
+      - list:
+        operationId: listItems
+        entrynode: true
+        exitweight: 0.6
+        edges:
+          - to: detail
+            weight: 0.4
+      - detail:
+        operationId: getItem
+        edges:
+          - to: list
+            weight: 1
+  loop:
+    wrk2params: -d1s -R1
+    flow:
+      - a:
+        operationId: opA
+        entrynode: true
+        edges:
+          - to: b
+            weight: 1
+      - b:
+        operationId: opB
+        edges:
+          - to: a
+            weight: 1
+          - to: b
+            weight: 1
+            maxtraversals: 2
+`)
+	issues, err := ValidateBytes(context.Background(), data, nil)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	var sawLoop bool
+	for _, issue := range issues {
+		switch {
+		case issue.Stage == "loop" && strings.Contains(issue.Message, "cycle a -> b has no exit"):
+			sawLoop = true
+		default:
+			t.Errorf("bounded and exiting cycles must be accepted, got %v", issue)
+		}
+	}
+	if !sawLoop {
+		t.Fatalf("expected the a -> b cycle without limit to be rejected, got %v", issues)
+	}
+}
+
+func TestCheckSemantics_ThinkTime(t *testing.T) {
+	data := []byte(`
+stages:
+  shop:
+    wrk2params: -d1s -R1
+    thinktime:
+      distribution: uniform
+      min: 1m30s
+      max: 1.5s
+    flow:
+      - list:
+        operationId: listItems
+        entrynode: true
+        edges:
+          - to: detail
+            weight: 1
+            thinktime:
+              distribution: exponential
+              mean: 500us
+              seed: 3
+      - detail:
+        operationId: getItem
+`)
+	issues, err := ValidateBytes(context.Background(), data, nil)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	var sawRange, sawSeed bool
+	for _, issue := range issues {
+		switch {
+		case issue.Severity == SeverityError && strings.Contains(issue.Message, "0 <= min <= max"):
+			sawRange = true
+		case issue.Severity == SeverityWarning && strings.Contains(issue.Message, "seed of an edge thinktime is ignored"):
+			sawSeed = true
+		default:
+			t.Errorf("unexpected issue %v", issue)
+		}
+	}
+	if !sawRange || !sawSeed {
+		t.Fatalf("expected the uniform range error and the edge seed warning, got %v", issues)
+	}
+}
+
+func TestValidateBytes_Load(t *testing.T) {
+	data := []byte(`
+stages:
+  both:
+    wrk2params: -d1s -R1
+    load:
+      rate: 10
+      duration: 1s
+    flow:
+      - list:
+        operationId: listItems
+        entrynode: true
+  ramp:
+    load:
+      threads: 4
+      connections: 2
+      segments:
+        - duration: 10s
+          rate: 10
+          to: 100
+    flow:
+      - list:
+        operationId: listItems
+        entrynode: true
+`)
+	issues, err := ValidateBytes(context.Background(), data, nil)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	var sawBoth, sawSegment, sawThreads bool
+	for _, issue := range issues {
+		switch {
+		case issue.Stage == "both" && strings.Contains(issue.Message, "not both"):
+			sawBoth = true
+		case strings.Contains(issue.Message, "/segments/0"):
+			sawSegment = true
+		case issue.Stage == "ramp" && strings.Contains(issue.Message, "4 threads need at least as many connections"):
+			sawThreads = true
+		}
+	}
+	if !sawBoth || !sawSegment || !sawThreads {
+		t.Fatalf("expected the load and wrk2params, segment and threads issues, got %v", issues)
+	}
+}
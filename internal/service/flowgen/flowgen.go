@@ -1,6 +1,6 @@
 // Package flowgen parses a DSL YAML file describing benchmark flows,
-// computes the number of request bodies needed per endpoint using
-// wrk2 parameters and Weighted Round Robin (WRR), and delegates
+// computes the number of request bodies needed per endpoint using the
+// load profile of each stage and Weighted Round Robin (WRR), and delegates
 // body generation to the datagen service.
 package flowgen
 
@@ -8,9 +8,9 @@ import (
 	"fmt"
 	"math"
 	"os"
-	"regexp"
 	"strconv"
 	"strings"
+	"time"
 
 	"gopkg.in/yaml.v3"
 )
@@ -26,8 +26,117 @@ type DSL struct {
 
 // Stage describes a single benchmark stage.
 type Stage struct {
-	Wrk2Params string     `yaml:"wrk2params"`
-	Flow       []FlowNode `yaml:"flow"`
+	// Wrk2Params is the raw wrk2 command line of the stage, translated into
+	// a Load by LoadProfile. It is an alternative to Load.
+	Wrk2Params string  `yaml:"wrk2params"`
+	Load       *Load   `yaml:"load"`
+	Guards     *Guards `yaml:"guards"`
+	// ThinkTime is the pause between the steps of an iteration, unless the
+	// edge between them sets its own.
+	ThinkTime *ThinkTime `yaml:"thinktime"`
+	Flow      []FlowNode `yaml:"flow"`
+	Pos       Position   `yaml:"-"` // location of the stage key in the DSL file
+}
+
+// Think time distributions.
+const (
+	ThinkTimeConstant    = "constant"
+	ThinkTimeUniform     = "uniform"
+	ThinkTimeExponential = "exponential"
+)
+
+// ThinkTime is a distribution of pauses a user takes before the next step
+// of an iteration.
+type ThinkTime struct {
+	Distribution string `yaml:"distribution"`
+	// Mean is the pause of a constant distribution and the mean of an
+	// exponential one; Min and Max bound a uniform one.
+	Mean time.Duration `yaml:"mean"`
+	Min  time.Duration `yaml:"min"`
+	Max  time.Duration `yaml:"max"`
+	// Seed makes the drawn pauses of a stage repeatable. It is only read
+	// from the stage think time; zero picks a seed per run.
+	Seed int64 `yaml:"seed"`
+}
+
+// Validate checks that the distribution is known and its parameters fit it.
+func (t ThinkTime) Validate() error {
+	switch t.Distribution {
+	case ThinkTimeConstant:
+		if t.Mean < 0 {
+			return fmt.Errorf("constant think time needs a non-negative mean, got %s", t.Mean)
+		}
+	case ThinkTimeUniform:
+		if t.Min < 0 || t.Max <= 0 || t.Min > t.Max {
+			return fmt.Errorf("uniform think time needs 0 <= min <= max and a positive max, got min=%s max=%s", t.Min, t.Max)
+		}
+	case ThinkTimeExponential:
+		if t.Mean <= 0 {
+			return fmt.Errorf("exponential think time needs a positive mean, got %s", t.Mean)
+		}
+	default:
+		return fmt.Errorf("unknown think time distribution %q (expected %s, %s or %s)", t.Distribution, ThinkTimeConstant, ThinkTimeUniform, ThinkTimeExponential)
+	}
+	return nil
+}
+
+// MeanDuration returns the expected pause.
+func (t ThinkTime) MeanDuration() time.Duration {
+	if t.Distribution == ThinkTimeUniform {
+		return (t.Min + t.Max) / 2
+	}
+	return t.Mean
+}
+
+// ThinkTimeBefore returns the think time before a step of operationID that
+// follows a step of previousOperationID: the think time of the first edge
+// between nodes of these operations that sets one, or else the stage think
+// time. It returns nil when neither is set.
+func (s Stage) ThinkTimeBefore(previousOperationID, operationID string) *ThinkTime {
+	targets := make(map[string]string, len(s.Flow))
+	for _, node := range s.Flow {
+		targets[node.Name] = node.OperationID
+	}
+	for _, node := range s.Flow {
+		if node.OperationID != previousOperationID {
+			continue
+		}
+		for _, edge := range node.Edges {
+			if edge.ThinkTime != nil && targets[edge.To] == operationID {
+				return edge.ThinkTime
+			}
+		}
+	}
+	return s.ThinkTime
+}
+
+// HasThinkTime reports whether the stage or any of its edges sets a think
+// time.
+func (s Stage) HasThinkTime() bool {
+	if s.ThinkTime != nil {
+		return true
+	}
+	for _, node := range s.Flow {
+		for _, edge := range node.Edges {
+			if edge.ThinkTime != nil {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// Guards are limits the harness checks while a stage runs. A stage that
+// breaks one is stopped early and marked failed. Zero values disable a
+// guard.
+type Guards struct {
+	// MaxNon2xxRatio is the highest share of non-2xx/3xx responses, 0-1.
+	MaxNon2xxRatio float64 `yaml:"maxNon2xxRatio"`
+	// MaxCPUSaturation is how long the service may run its CPUs at the
+	// limit without a break.
+	MaxCPUSaturation time.Duration `yaml:"maxCpuSaturation"`
+	// MinRateRatio is the lowest achieved request rate relative to -R, 0-1.
+	MinRateRatio float64 `yaml:"minRateRatio"`
 }
 
 // FlowNode is one node in a stage flow.
@@ -37,14 +146,87 @@ type FlowNode struct {
 	Endpoint    string `yaml:"endpoint"`
 	Method      string `yaml:"method"`
 	EntryNode   bool   `yaml:"entrynode"`
-	Edges       []Edge `yaml:"edges"`
+	// EntryWeight is the share of iterations that start at this entry node
+	// relative to the other entry nodes; nil means DefaultEntryWeight.
+	EntryWeight *float64 `yaml:"entryweight"`
+	// MaxVisits limits how often the node runs within one iteration; zero
+	// means no limit. Edges to a node that reached it are not taken.
+	MaxVisits int `yaml:"maxvisits"`
+	// ExitWeight is the weight of ending the iteration at this node,
+	// normalized together with the edge weights.
+	ExitWeight float64  `yaml:"exitweight"`
+	Edges      []Edge   `yaml:"edges"`
+	Pos        Position `yaml:"-"` // location of the node in the DSL file
+}
+
+// Choices returns the edges of the node followed, when ExitWeight is set, by
+// an exit choice with an empty To. A node without edges always exits.
+func (n FlowNode) Choices() []Edge {
+	if n.ExitWeight <= 0 || len(n.Edges) == 0 {
+		return n.Edges
+	}
+	choices := make([]Edge, 0, len(n.Edges)+1)
+	choices = append(choices, n.Edges...)
+	return append(choices, Edge{Weight: n.ExitWeight, Pos: n.Pos})
+}
+
+// VisitsLeft reports whether the node may run again after visits runs in
+// the current iteration.
+func (n FlowNode) VisitsLeft(visits int) bool {
+	return n.MaxVisits == 0 || visits < n.MaxVisits
+}
+
+// DefaultEntryWeight is the weight of an entry node without entryweight.
+const DefaultEntryWeight = 1.0
+
+// Entries returns the entry nodes of the stage as weighted edges from the
+// start of an iteration, in flow order.
+func (s Stage) Entries() []Edge {
+	var entries []Edge
+	for _, node := range s.Flow {
+		if !node.EntryNode {
+			continue
+		}
+		weight := DefaultEntryWeight
+		if node.EntryWeight != nil {
+			weight = *node.EntryWeight
+		}
+		entries = append(entries, Edge{To: node.Name, Weight: weight, Pos: node.Pos})
+	}
+	return entries
 }
 
 // Edge is a weighted outgoing edge from one flow node to another.
 type Edge struct {
-	To       string    `yaml:"to"`
-	Weight   float64   `yaml:"weight"`
-	Mappings []Mapping `yaml:"mappings"`
+	To     string  `yaml:"to"`
+	Weight float64 `yaml:"weight"`
+	// MaxTraversals limits how often the edge is taken within one
+	// iteration; zero means no limit.
+	MaxTraversals int `yaml:"maxtraversals"`
+	// ThinkTime replaces the stage think time before the target step.
+	ThinkTime *ThinkTime `yaml:"thinktime"`
+	Mappings  []Mapping  `yaml:"mappings"`
+	Pos       Position   `yaml:"-"` // location of the edge in the DSL file
+}
+
+// TraversalsLeft reports whether the edge may be taken again after
+// traversals in the current iteration.
+func (e Edge) TraversalsLeft(traversals int) bool {
+	return e.MaxTraversals == 0 || traversals < e.MaxTraversals
+}
+
+// Position is a 1-based line/column location inside the DSL file. It is
+// zero when the value was not read from YAML (for example in tests).
+type Position struct {
+	Line   int `json:"line"`
+	Column int `json:"column"`
+}
+
+func positionOf(n *yaml.Node) Position {
+	if n == nil {
+		return Position{}
+	}
+	return Position{Line: n.Line, Column: n.Column}
 }
 
 // Mapping describes a field mapping between source and destination.
@@ -71,25 +253,43 @@ func ParseDSL(path string) (*DSL, error) {
 	if err != nil {
 		return nil, fmt.Errorf("failed to read DSL file %q: %w", path, err)
 	}
+	return ParseDSLBytes(data)
+}
 
+// ParseDSLBytes parses DSL YAML content into a DSL struct. Stages, nodes and
+// edges keep their source positions so callers can report findings against
+// the original file.
+func ParseDSLBytes(data []byte) (*DSL, error) {
 	// The DSL YAML uses a pattern where each flow list item has a node
 	// name as a key alongside endpoint/method/etc.  Because of this
 	// mixed-key structure we first unmarshal into raw form, then convert.
 	var raw struct {
-		Stages map[string]struct {
-			Wrk2Params string      `yaml:"wrk2params"`
-			Flow       []yaml.Node `yaml:"flow"`
-		} `yaml:"stages"`
+		Stages yaml.Node `yaml:"stages"`
 	}
 
 	if err := yaml.Unmarshal(data, &raw); err != nil {
 		return nil, fmt.Errorf("failed to parse DSL YAML: %w", err)
 	}
+	if raw.Stages.Kind != 0 && raw.Stages.Kind != yaml.MappingNode {
+		return nil, fmt.Errorf("failed to parse DSL YAML: stages must be a mapping")
+	}
 
-	dsl := &DSL{Stages: make(map[string]Stage, len(raw.Stages))}
+	dsl := &DSL{Stages: make(map[string]Stage, len(raw.Stages.Content)/2)}
 
-	for stageName, rawStage := range raw.Stages {
-		stage := Stage{Wrk2Params: rawStage.Wrk2Params}
+	for i := 0; i+1 < len(raw.Stages.Content); i += 2 {
+		keyNode := raw.Stages.Content[i]
+		stageName := keyNode.Value
+		var rawStage struct {
+			Wrk2Params string      `yaml:"wrk2params"`
+			Load       *Load       `yaml:"load"`
+			Guards     *Guards     `yaml:"guards"`
+			ThinkTime  *ThinkTime  `yaml:"thinktime"`
+			Flow       []yaml.Node `yaml:"flow"`
+		}
+		if err := raw.Stages.Content[i+1].Decode(&rawStage); err != nil {
+			return nil, fmt.Errorf("stage %q: %w", stageName, err)
+		}
+		stage := Stage{Wrk2Params: rawStage.Wrk2Params, Load: rawStage.Load, Guards: rawStage.Guards, ThinkTime: rawStage.ThinkTime, Pos: positionOf(keyNode)}
 
 		for _, node := range rawStage.Flow {
 			fn, err := parseFlowNode(&node)
@@ -118,7 +318,7 @@ func parseFlowNode(n *yaml.Node) (FlowNode, error) {
 		return FlowNode{}, fmt.Errorf("expected mapping node, got %v", n.Kind)
 	}
 
-	fn := FlowNode{}
+	fn := FlowNode{Pos: positionOf(n)}
 
 	for i := 0; i < len(n.Content)-1; i += 2 {
 		key := n.Content[i].Value
@@ -133,10 +333,28 @@ func parseFlowNode(n *yaml.Node) (FlowNode, error) {
 			fn.Method = val.Value
 		case "entrynode":
 			fn.EntryNode = val.Value == "true"
+		case "entryweight":
+			weight, err := strconv.ParseFloat(val.Value, 64)
+			if err != nil {
+				return FlowNode{}, fmt.Errorf("invalid entryweight %q: %w", val.Value, err)
+			}
+			fn.EntryWeight = &weight
+		case "maxvisits":
+			visits, err := strconv.Atoi(val.Value)
+			if err != nil {
+				return FlowNode{}, fmt.Errorf("invalid maxvisits %q: %w", val.Value, err)
+			}
+			fn.MaxVisits = visits
+		case "exitweight":
+			weight, err := strconv.ParseFloat(val.Value, 64)
+			if err != nil {
+				return FlowNode{}, fmt.Errorf("invalid exitweight %q: %w", val.Value, err)
+			}
+			fn.ExitWeight = weight
 		case "edges":
-			var edges []Edge
-			if err := val.Decode(&edges); err != nil {
-				return FlowNode{}, fmt.Errorf("failed to decode edges: %w", err)
+			edges, err := parseEdges(val)
+			if err != nil {
+				return FlowNode{}, err
 			}
 			fn.Edges = edges
 		default:
@@ -157,136 +375,356 @@ func parseFlowNode(n *yaml.Node) (FlowNode, error) {
 	return fn, nil
 }
 
-// ---------------------------------------------------------------------------
-// wrk2 parameter parsing
-// ---------------------------------------------------------------------------
-
-var (
-	rateRe     = regexp.MustCompile(`-R\s*(\d+)`)
-	durationRe = regexp.MustCompile(`-d\s*(\d+)([smhSMH]?)`)
-)
-
-// Wrk2Config holds the parsed wrk2 rate and duration.
-type Wrk2Config struct {
-	Rate     int // requests per second
-	Duration int // seconds
-}
-
-// ParseWrk2Params extracts the rate (-R) and duration (-d) from a wrk2
-// parameter string.  Duration suffixes: s (default), m, h.
-func ParseWrk2Params(params string) (Wrk2Config, error) {
-	cfg := Wrk2Config{}
-
-	if m := rateRe.FindStringSubmatch(params); m != nil {
-		r, err := strconv.Atoi(m[1])
-		if err != nil {
-			return cfg, fmt.Errorf("invalid rate %q: %w", m[1], err)
+// parseEdges decodes an edges sequence, keeping the position of each item.
+func parseEdges(n *yaml.Node) ([]Edge, error) {
+	if n.Kind != yaml.SequenceNode {
+		var edges []Edge
+		if err := n.Decode(&edges); err != nil {
+			return nil, fmt.Errorf("failed to decode edges: %w", err)
 		}
-		cfg.Rate = r
-	} else {
-		return cfg, fmt.Errorf("missing -R (rate) in wrk2params %q", params)
+		return edges, nil
 	}
-
-	if m := durationRe.FindStringSubmatch(params); m != nil {
-		d, err := strconv.Atoi(m[1])
-		if err != nil {
-			return cfg, fmt.Errorf("invalid duration %q: %w", m[1], err)
+	edges := make([]Edge, 0, len(n.Content))
+	for _, item := range n.Content {
+		var edge Edge
+		if err := item.Decode(&edge); err != nil {
+			return nil, fmt.Errorf("failed to decode edges: %w", err)
 		}
-		switch strings.ToLower(m[2]) {
-		case "m":
-			d *= 60
-		case "h":
-			d *= 3600
-		default: // "s" or empty
-		}
-		cfg.Duration = d
-	} else {
-		return cfg, fmt.Errorf("missing -d (duration) in wrk2params %q", params)
+		edge.Pos = positionOf(item)
+		edges = append(edges, edge)
 	}
-
-	return cfg, nil
-}
-
-// TotalRequests returns Rate * Duration.
-func (c Wrk2Config) TotalRequests() int {
-	return c.Rate * c.Duration
+	return edges, nil
 }
 
 // ---------------------------------------------------------------------------
 // Weighted Round Robin body count computation
 // ---------------------------------------------------------------------------
 
-// ComputeBodyCounts walks the flow graph starting from entry nodes and
-// distributes the total request count according to edge weights.
+// ComputeBodyCounts distributes the total request count over the flow nodes
+// by their expected visits per iteration (see ExpectedVisits), so an entry
+// node that starts every iteration gets the total.
 // Only nodes whose HTTP method typically carries a request body
 // (POST, PUT, PATCH) will have a non-zero count.
 func ComputeBodyCounts(stage Stage, totalRequests int) ([]NodeBodyCount, error) {
-	// Build a name -> FlowNode index.
-	nodeByName := make(map[string]*FlowNode, len(stage.Flow))
-	for i := range stage.Flow {
-		nodeByName[stage.Flow[i].Name] = &stage.Flow[i]
+	visits, err := ExpectedVisits(stage)
+	if err != nil {
+		return nil, err
+	}
+	counts := make(map[string]int, len(visits))
+	for name, expected := range visits {
+		counts[name] = int(math.Round(float64(totalRequests) * expected))
+	}
+
+	// Build result, only including body-bearing methods.
+	result := make([]NodeBodyCount, 0, len(stage.Flow))
+	for _, fn := range stage.Flow {
+		c := counts[fn.Name]
+		if !methodHasBody(fn.Method) {
+			c = 0
+		}
+		result = append(result, NodeBodyCount{
+			NodeName: fn.Name,
+			Endpoint: fn.Endpoint,
+			Method:   fn.Method,
+			Count:    c,
+		})
 	}
 
-	// Accumulate raw request counts per node name.
-	counts := make(map[string]int, len(stage.Flow))
+	return result, nil
+}
+
+// ---------------------------------------------------------------------------
+// Expected visits
+// ---------------------------------------------------------------------------
+
+// maxVisitStates bounds the visit and traversal counts ExpectedVisits
+// enumerates, so limits on many nodes fail instead of running for ages.
+const maxVisitStates = 100000
 
-	// Find entry nodes.
-	var entries []*FlowNode
-	for i := range stage.Flow {
-		if stage.Flow[i].EntryNode {
-			entries = append(entries, &stage.Flow[i])
+// ExpectedVisits returns how often each node runs per iteration on average.
+// An iteration is an absorbing Markov chain: it starts at an entry node with
+// probability proportional to the entry weight, takes each edge with
+// probability proportional to its weight among the edges still allowed by
+// maxvisits and maxtraversals, and ends with probability proportional to
+// the exit weight, or at a node without such edges.
+//
+// With limits the chain state includes the visit and traversal counts so
+// far. Taking a limited edge or entering a limited node increases a count,
+// so the states are solved in blocks of equal counts, in increasing order of
+// their sum; within a block only unlimited edges between unlimited nodes
+// remain and the expected visits solve x = s + xQ.
+func ExpectedVisits(stage Stage) (map[string]float64, error) {
+	index := make(map[string]int, len(stage.Flow))
+	for i, node := range stage.Flow {
+		index[node.Name] = i
+	}
+	// Each limited node and edge gets a slot in the count vector, which is
+	// encoded as one integer with a digit of radix limit+1 per slot.
+	nodeSlot := make([]int, len(stage.Flow))
+	edgeSlot := make([][]int, len(stage.Flow))
+	var radix []uint64
+	addSlot := func(limit int) int {
+		radix = append(radix, uint64(limit)+1)
+		return len(radix) - 1
+	}
+	for i, node := range stage.Flow {
+		nodeSlot[i] = -1
+		if node.MaxVisits > 0 {
+			nodeSlot[i] = addSlot(node.MaxVisits)
 		}
+		edgeSlot[i] = make([]int, len(node.Edges))
+		for j, edge := range node.Edges {
+			if _, ok := index[edge.To]; !ok {
+				return nil, fmt.Errorf("node %q has edge to unknown node %q", node.Name, edge.To)
+			}
+			if edge.Weight <= 0 {
+				return nil, fmt.Errorf("edge %q -> %q has non-positive weight %v", node.Name, edge.To, edge.Weight)
+			}
+			edgeSlot[i][j] = -1
+			if edge.MaxTraversals > 0 {
+				edgeSlot[i][j] = addSlot(edge.MaxTraversals)
+			}
+		}
+	}
+	place := make([]uint64, len(radix))
+	combinations := uint64(1)
+	for slot, r := range radix {
+		if combinations > math.MaxUint64/r {
+			return nil, fmt.Errorf("flow limits allow more than %d visit count combinations", maxVisitStates)
+		}
+		place[slot] = combinations
+		combinations *= r
+	}
+	count := func(state uint64, slot int) int {
+		return int(state / place[slot] % radix[slot])
 	}
+
+	entries := stage.Entries()
 	if len(entries) == 0 {
 		return nil, fmt.Errorf("no entry node found in flow")
 	}
-
-	// BFS / DFS propagation using a simple queue.
-	type work struct {
-		name  string
-		count int
+	totalWeight := 0.0
+	for _, entry := range entries {
+		if entry.Weight <= 0 {
+			return nil, fmt.Errorf("entry node %q has non-positive entryweight %v", entry.To, entry.Weight)
+		}
+		totalWeight += entry.Weight
 	}
-	queue := make([]work, 0, len(stage.Flow))
 
-	// Divide total evenly among entry nodes (usually just one).
-	perEntry := totalRequests / len(entries)
-	for _, e := range entries {
-		queue = append(queue, work{name: e.Name, count: perEntry})
+	// Every move that changes the counts increases their sum, so the blocks
+	// of one sum only receive mass from those of lower sums.
+	type block struct {
+		state  uint64
+		inflow []float64
+	}
+	blocks := map[uint64]*block{}
+	bySum := [][]*block{}
+	enter := func(state uint64, sum, node int, mass float64) error {
+		b, ok := blocks[state]
+		if !ok {
+			if len(blocks) >= maxVisitStates {
+				return fmt.Errorf("flow limits allow more than %d visit count combinations", maxVisitStates)
+			}
+			b = &block{state: state, inflow: make([]float64, len(stage.Flow))}
+			blocks[state] = b
+			for len(bySum) <= sum {
+				bySum = append(bySum, nil)
+			}
+			bySum[sum] = append(bySum[sum], b)
+		}
+		b.inflow[node] += mass
+		return nil
+	}
+	// visit returns the state after entering node and whether it changed, or
+	// false if its maxvisits is used up.
+	visit := func(state uint64, node int) (uint64, bool, bool) {
+		slot := nodeSlot[node]
+		if slot < 0 {
+			return state, false, true
+		}
+		if count(state, slot) >= stage.Flow[node].MaxVisits {
+			return 0, false, false
+		}
+		return state + place[slot], true, true
 	}
 
-	for len(queue) > 0 {
-		w := queue[0]
-		queue = queue[1:]
-
-		counts[w.name] += w.count
+	for _, entry := range entries {
+		node := index[entry.To]
+		state, changed, _ := visit(0, node)
+		sum := 0
+		if changed {
+			sum = 1
+		}
+		if err := enter(state, sum, node, entry.Weight/totalWeight); err != nil {
+			return nil, err
+		}
+	}
 
-		node, ok := nodeByName[w.name]
-		if !ok || len(node.Edges) == 0 {
-			continue
+	visits := make(map[string]float64, len(stage.Flow))
+	for _, node := range stage.Flow {
+		visits[node.Name] = 0
+	}
+	for sum := 0; sum < len(bySum); sum++ {
+		for _, current := range bySum[sum] {
+			// moves lists the allowed edges of every node under the counts
+			// of the block, with their probabilities.
+			type move struct {
+				to    int
+				state uint64
+				steps int // the increase of the count sum; 0 stays in the block
+				p     float64
+			}
+			moves := make([][]move, len(stage.Flow))
+			n := len(stage.Flow)
+			matrix := make([][]float64, n)
+			for i := range matrix {
+				matrix[i] = make([]float64, n+1)
+				matrix[i][i] = 1
+				matrix[i][n] = current.inflow[i]
+			}
+			for i, node := range stage.Flow {
+				total := 0.0
+				for j, edge := range node.Edges {
+					m := move{to: index[edge.To], state: current.state, p: edge.Weight}
+					if slot := edgeSlot[i][j]; slot >= 0 {
+						if !edge.TraversalsLeft(count(m.state, slot)) {
+							continue
+						}
+						m.state += place[slot]
+						m.steps++
+					}
+					state, changed, ok := visit(m.state, m.to)
+					if !ok {
+						continue
+					}
+					if changed {
+						m.state = state
+						m.steps++
+					}
+					moves[i] = append(moves[i], m)
+					total += edge.Weight
+				}
+				if len(moves[i]) > 0 {
+					total += max(node.ExitWeight, 0)
+				}
+				for k := range moves[i] {
+					moves[i][k].p /= total
+					if moves[i][k].steps == 0 {
+						// x_to - sum_i x_i Q(i, to) = inflow_to.
+						matrix[moves[i][k].to][i] -= moves[i][k].p
+					}
+				}
+			}
+			x, err := solveLinear(matrix)
+			if err != nil {
+				return nil, fmt.Errorf("flow has a cycle that never ends; bound it with maxvisits or maxtraversals")
+			}
+			for i, node := range stage.Flow {
+				visits[node.Name] += x[i]
+				for _, m := range moves[i] {
+					if m.steps > 0 && x[i] > 0 {
+						if err := enter(m.state, sum+m.steps, m.to, x[i]*m.p); err != nil {
+							return nil, err
+						}
+					}
+				}
+			}
 		}
+	}
+	return visits, nil
+}
 
-		for _, edge := range node.Edges {
-			childCount := int(math.Round(float64(w.count) * edge.Weight))
-			queue = append(queue, work{name: edge.To, count: childCount})
+// solveLinear solves the augmented n x (n+1) system by Gaussian elimination
+// with partial pivoting. It fails when the system is singular.
+func solveLinear(matrix [][]float64) ([]float64, error) {
+	n := len(matrix)
+	for col := range n {
+		pivot := col
+		for row := col + 1; row < n; row++ {
+			if math.Abs(matrix[row][col]) > math.Abs(matrix[pivot][col]) {
+				pivot = row
+			}
+		}
+		if math.Abs(matrix[pivot][col]) < 1e-12 {
+			return nil, fmt.Errorf("singular system")
+		}
+		matrix[col], matrix[pivot] = matrix[pivot], matrix[col]
+		for row := range n {
+			if row == col || matrix[row][col] == 0 {
+				continue
+			}
+			factor := matrix[row][col] / matrix[col][col]
+			for k := col; k <= n; k++ {
+				matrix[row][k] -= factor * matrix[col][k]
+			}
 		}
 	}
+	x := make([]float64, n)
+	for i := range n {
+		x[i] = matrix[i][n] / matrix[i][i]
+	}
+	return x, nil
+}
 
-	// Build result, only including body-bearing methods.
-	result := make([]NodeBodyCount, 0, len(stage.Flow))
-	for _, fn := range stage.Flow {
-		c := counts[fn.Name]
-		if !methodHasBody(fn.Method) {
-			c = 0
+// ---------------------------------------------------------------------------
+// Weighted round robin choice
+// ---------------------------------------------------------------------------
+
+// WeightedRoundRobin picks edge targets in proportion to their weights with
+// the smooth weighted round robin of nginx, so every prefix of the picks
+// stays close to the weighted mix.
+type WeightedRoundRobin struct {
+	edges   []Edge
+	current []float64
+	total   float64
+}
+
+// NewWeightedRoundRobin returns a chooser over edges, which must all have a
+// positive weight.
+func NewWeightedRoundRobin(edges []Edge) (*WeightedRoundRobin, error) {
+	if len(edges) == 0 {
+		return nil, fmt.Errorf("cannot build weighted chooser with no edges")
+	}
+	total := 0.0
+	for _, edge := range edges {
+		if edge.Weight <= 0 {
+			return nil, fmt.Errorf("edge to %q has non-positive weight %v", edge.To, edge.Weight)
 		}
-		result = append(result, NodeBodyCount{
-			NodeName: fn.Name,
-			Endpoint: fn.Endpoint,
-			Method:   fn.Method,
-			Count:    c,
-		})
+		total += edge.Weight
 	}
+	return &WeightedRoundRobin{
+		edges:   edges,
+		current: make([]float64, len(edges)),
+		total:   total,
+	}, nil
+}
 
-	return result, nil
+// Next returns the target of the next pick.
+func (w *WeightedRoundRobin) Next() string {
+	best, _ := w.NextEligible(nil)
+	return w.edges[best].To
+}
+
+// NextEligible picks among the edges for which eligible returns true, like
+// nginx skips peers that are down, and returns the index of the picked
+// edge. ok is false when no edge is eligible. A nil eligible allows all.
+func (w *WeightedRoundRobin) NextEligible(eligible func(i int) bool) (index int, ok bool) {
+	best, total := -1, 0.0
+	for i := range w.edges {
+		if eligible != nil && !eligible(i) {
+			continue
+		}
+		w.current[i] += w.edges[i].Weight
+		total += w.edges[i].Weight
+		if best < 0 || w.current[i] > w.current[best] {
+			best = i
+		}
+	}
+	if best < 0 {
+		return -1, false
+	}
+	w.current[best] -= total
+	return best, true
 }
 
 // methodHasBody returns true for HTTP methods that carry a request body.
@@ -1,67 +1,14 @@
 package flowgen
 
 import (
+	"math"
 	"path/filepath"
+	"strconv"
+	"strings"
 	"testing"
+	"time"
 )
 
-// ---------------------------------------------------------------------------
-// ParseWrk2Params tests
-// ---------------------------------------------------------------------------
-
-func TestParseWrk2Params_Basic(t *testing.T) {
-	cfg, err := ParseWrk2Params("-t2 -c100 -d30s -R2000")
-	if err != nil {
-		t.Fatalf("unexpected error: %v", err)
-	}
-	if cfg.Rate != 2000 {
-		t.Errorf("expected rate 2000, got %d", cfg.Rate)
-	}
-	if cfg.Duration != 30 {
-		t.Errorf("expected duration 30, got %d", cfg.Duration)
-	}
-	if cfg.TotalRequests() != 60000 {
-		t.Errorf("expected total 60000, got %d", cfg.TotalRequests())
-	}
-}
-
-func TestParseWrk2Params_Minutes(t *testing.T) {
-	cfg, err := ParseWrk2Params("-d2m -R500")
-	if err != nil {
-		t.Fatalf("unexpected error: %v", err)
-	}
-	if cfg.Duration != 120 {
-		t.Errorf("expected 120s, got %d", cfg.Duration)
-	}
-	if cfg.TotalRequests() != 60000 {
-		t.Errorf("expected 60000, got %d", cfg.TotalRequests())
-	}
-}
-
-func TestParseWrk2Params_Hours(t *testing.T) {
-	cfg, err := ParseWrk2Params("-d1h -R10")
-	if err != nil {
-		t.Fatalf("unexpected error: %v", err)
-	}
-	if cfg.Duration != 3600 {
-		t.Errorf("expected 3600s, got %d", cfg.Duration)
-	}
-}
-
-func TestParseWrk2Params_MissingRate(t *testing.T) {
-	_, err := ParseWrk2Params("-d30s")
-	if err == nil {
-		t.Fatal("expected error for missing -R")
-	}
-}
-
-func TestParseWrk2Params_MissingDuration(t *testing.T) {
-	_, err := ParseWrk2Params("-R2000")
-	if err == nil {
-		t.Fatal("expected error for missing -d")
-	}
-}
-
 // ---------------------------------------------------------------------------
 // ParseDSL tests
 // ---------------------------------------------------------------------------
@@ -108,6 +55,14 @@ func TestParseDSL(t *testing.T) {
 	if s1.Flow[1].EntryNode {
 		t.Error("node2 should not be entry node")
 	}
+
+	want := Guards{MaxNon2xxRatio: 0.5, MaxCPUSaturation: 20 * time.Second, MinRateRatio: 0.8}
+	if s1.Guards == nil || *s1.Guards != want {
+		t.Errorf("stage1 guards = %+v, want %+v", s1.Guards, want)
+	}
+	if s2 := dsl.Stages["stage2"]; s2.Guards != nil {
+		t.Errorf("stage2 guards = %+v, want none", s2.Guards)
+	}
 }
 
 // ---------------------------------------------------------------------------
@@ -168,6 +123,275 @@ func TestComputeBodyCounts_NoEntryNode(t *testing.T) {
 	}
 }
 
+func TestComputeBodyCounts_WeightedEntries(t *testing.T) {
+	dsl, err := ParseDSLBytes([]byte(`
+stages:
+  mix:
+    wrk2params: -d10s -R100
+    flow:
+      - browse:
+        operationId: listOwners
+        method: POST
+        entrynode: true
+        entryweight: 3
+      - checkout:
+        operationId: addOwner
+        method: POST
+        entrynode: true
+`))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	stage := dsl.Stages["mix"]
+	if entries := stage.Entries(); len(entries) != 2 || entries[0].Weight != 3 || entries[1].Weight != DefaultEntryWeight {
+		t.Fatalf("unexpected entries: %+v", entries)
+	}
+	counts, err := ComputeBodyCounts(stage, 1000)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	assertCount(t, counts, "browse", 750)
+	assertCount(t, counts, "checkout", 250)
+
+	if _, err := ParseDSLBytes([]byte("stages:\n  s:\n    wrk2params: -d1s -R1\n    flow:\n      - a:\n        operationId: a\n        entryweight: lots\n")); err == nil {
+		t.Fatal("expected an invalid entryweight to be rejected")
+	}
+}
+
+func TestExpectedVisits_BoundedLoops(t *testing.T) {
+	dsl, err := ParseDSLBytes([]byte(`
+stages:
+  cart:
+    wrk2params: -d10s -R100
+    flow:
+      - browse:
+        operationId: listItems
+        entrynode: true
+        edges:
+          - to: add
+            weight: 1
+      - add:
+        operationId: addToCart
+        method: POST
+        maxvisits: 5
+        edges:
+          - to: add
+            weight: 1
+          - to: checkout
+            weight: 1
+      - checkout:
+        operationId: checkout
+        method: POST
+  pingpong:
+    wrk2params: -d10s -R100
+    flow:
+      - ping:
+        operationId: ping
+        entrynode: true
+        edges:
+          - to: pong
+            weight: 1
+      - pong:
+        operationId: pong
+        edges:
+          - to: ping
+            weight: 1
+            maxtraversals: 2
+`))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if add := dsl.Stages["cart"].Flow[1]; add.MaxVisits != 5 || add.VisitsLeft(5) || !add.VisitsLeft(4) {
+		t.Fatalf("unexpected maxvisits parse: %+v", add)
+	}
+
+	// addToCart repeats with probability 1/2 up to 5 times:
+	// 1 + 1/2 + 1/4 + 1/8 + 1/16 visits.
+	visits, err := ExpectedVisits(dsl.Stages["cart"])
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	want := map[string]float64{"browse": 1, "add": 1.9375, "checkout": 1}
+	for name, expected := range want {
+		if math.Abs(visits[name]-expected) > 1e-9 {
+			t.Errorf("visits[%s] = %v, want %v", name, visits[name], expected)
+		}
+	}
+	counts, err := ComputeBodyCounts(dsl.Stages["cart"], 1000)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	assertCount(t, counts, "add", 1938)
+	assertCount(t, counts, "checkout", 1000)
+
+	// The edge back to ping is taken twice, then pong ends the iteration.
+	visits, err = ExpectedVisits(dsl.Stages["pingpong"])
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if math.Abs(visits["ping"]-3) > 1e-9 || math.Abs(visits["pong"]-3) > 1e-9 {
+		t.Errorf("pingpong visits = %v, want 3 each", visits)
+	}
+
+	unbounded := dsl.Stages["pingpong"]
+	unbounded.Flow[1].Edges[0].MaxTraversals = 0
+	if _, err := ExpectedVisits(unbounded); err == nil {
+		t.Fatal("expected a cycle without exit or limit to be rejected")
+	}
+}
+
+func TestExpectedVisits_ManyVisitCounts(t *testing.T) {
+	// Every node links to every other one, so all combinations of visit
+	// counts up to maxvisits are reachable.
+	clique := func(nodes, maxVisits int) Stage {
+		var stage Stage
+		for i := range nodes {
+			node := FlowNode{Name: strconv.Itoa(i), EntryNode: i == 0, MaxVisits: maxVisits, ExitWeight: 1}
+			for j := range nodes {
+				if j != i {
+					node.Edges = append(node.Edges, Edge{To: strconv.Itoa(j), Weight: 1})
+				}
+			}
+			stage.Flow = append(stage.Flow, node)
+		}
+		return stage
+	}
+	visits, err := ExpectedVisits(clique(5, 8))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	total := 0.0
+	for _, v := range visits {
+		total += v
+	}
+	// Each step ends the iteration with probability 1/5 while all four
+	// other nodes are open, so about five steps are expected.
+	if total < 4 || total > 5 {
+		t.Errorf("total visits = %v, want a little under 5", total)
+	}
+	if _, err := ExpectedVisits(clique(6, 9)); err == nil || !strings.Contains(err.Error(), "combinations") {
+		t.Fatalf("err = %v, want the visit count combinations to be capped", err)
+	}
+}
+
+func TestExpectedVisits_ExitWeight(t *testing.T) {
+	dsl, err := ParseDSLBytes([]byte(`
+stages:
+  browse:
+    wrk2params: -d10s -R100
+    flow:
+      - list:
+        operationId: listItems
+        method: POST
+        entrynode: true
+        exitweight: 0.6
+        edges:
+          - to: detail
+            weight: 0.4
+      - detail:
+        operationId: getItem
+        method: POST
+        edges:
+          - to: list
+            weight: 1
+`))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	stage := dsl.Stages["browse"]
+	choices := stage.Flow[0].Choices()
+	if len(choices) != 2 || choices[1].To != "" || choices[1].Weight != 0.6 {
+		t.Fatalf("expected the exit as last choice, got %+v", choices)
+	}
+	if len(stage.Flow[1].Choices()) != 1 {
+		t.Fatalf("node without exitweight must not get an exit choice")
+	}
+
+	// The list is left with probability 0.6 on every visit: 1/0.6 visits.
+	counts, err := ComputeBodyCounts(stage, 1000)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	assertCount(t, counts, "list", 1667)
+	assertCount(t, counts, "detail", 667)
+}
+
+func TestParseDSL_ThinkTime(t *testing.T) {
+	dsl, err := ParseDSLBytes([]byte(`
+stages:
+  shop:
+    wrk2params: -d10s -R100
+    thinktime:
+      distribution: exponential
+      mean: 2s
+      seed: 7
+    flow:
+      - list:
+        operationId: listItems
+        entrynode: true
+        edges:
+          - to: detail
+            weight: 1
+            thinktime:
+              distribution: uniform
+              min: 500ms
+              max: 1500ms
+      - detail:
+        operationId: getItem
+        edges:
+          - to: list
+            weight: 1
+`))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	stage := dsl.Stages["shop"]
+	want := ThinkTime{Distribution: ThinkTimeExponential, Mean: 2 * time.Second, Seed: 7}
+	if stage.ThinkTime == nil || *stage.ThinkTime != want || !stage.HasThinkTime() {
+		t.Fatalf("stage think time = %+v, want %+v", stage.ThinkTime, want)
+	}
+	edge := stage.ThinkTimeBefore("listItems", "getItem")
+	if edge == nil || edge.Distribution != ThinkTimeUniform || edge.MeanDuration() != time.Second {
+		t.Fatalf("edge think time = %+v, want uniform 500ms-1500ms", edge)
+	}
+	if back := stage.ThinkTimeBefore("getItem", "listItems"); back != stage.ThinkTime {
+		t.Fatalf("edge without think time must use the stage one, got %+v", back)
+	}
+	for _, invalid := range []ThinkTime{
+		{Distribution: "normal", Mean: time.Second},
+		{Distribution: ThinkTimeUniform, Min: 2 * time.Second, Max: time.Second},
+		{Distribution: ThinkTimeExponential},
+	} {
+		if err := invalid.Validate(); err == nil {
+			t.Errorf("expected %+v to be rejected", invalid)
+		}
+	}
+	if err := (ThinkTime{Distribution: ThinkTimeConstant}).Validate(); err != nil {
+		t.Errorf("zero constant think time must be valid: %v", err)
+	}
+}
+
+func TestWeightedRoundRobin_NextEligible(t *testing.T) {
+	wrr, err := NewWeightedRoundRobin([]Edge{{To: "a", Weight: 2}, {To: "b", Weight: 1}, {To: "c", Weight: 1}})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	var picks []string
+	for range 4 {
+		i, ok := wrr.NextEligible(func(i int) bool { return i != 0 })
+		if !ok {
+			t.Fatal("expected an eligible edge")
+		}
+		picks = append(picks, []string{"a", "b", "c"}[i])
+	}
+	if got := strings.Join(picks, ""); got != "bcbc" {
+		t.Errorf("picks without a = %s, want bcbc", got)
+	}
+	if _, ok := wrr.NextEligible(func(int) bool { return false }); ok {
+		t.Error("expected no pick when no edge is eligible")
+	}
+}
+
 func assertCount(t *testing.T, counts []NodeBodyCount, name string, expected int) {
 	t.Helper()
 	for _, c := range counts {
@@ -0,0 +1,356 @@
+package flowgen
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Defaults of a load profile, the same as those of wrk2.
+const (
+	DefaultThreads     = 2
+	DefaultConnections = 10
+)
+
+// Load is the load profile of a stage: how many threads and connections
+// send requests, at which rate and for how long.
+type Load struct {
+	Threads     int `yaml:"threads"`
+	Connections int `yaml:"connections"`
+	// Rate is the requests per second over Duration. A load with Segments
+	// leaves both unset.
+	Rate     int           `yaml:"rate"`
+	Duration time.Duration `yaml:"duration"`
+	// Segments follow each other, e.g. a linear ramp and then a plateau.
+	Segments []RateSegment `yaml:"segments"`
+	// Timeout is the request timeout; zero keeps that of the executor.
+	Timeout time.Duration `yaml:"timeout"`
+	// Warmup runs before the measured part at its starting rate. Its
+	// requests are sent but not measured.
+	Warmup time.Duration `yaml:"warmup"`
+	// ExtraArgs are the flags of wrk2params the load has no field for, such
+	// as -U. They are passed on to wrk2 as they are.
+	ExtraArgs []string `yaml:"-"`
+}
+
+// RateSegment is one part of a load profile: a constant Rate, or a linear
+// ramp From one rate To another.
+type RateSegment struct {
+	Duration time.Duration `yaml:"duration"`
+	Rate     int           `yaml:"rate"`
+	From     int           `yaml:"from"`
+	To       int           `yaml:"to"`
+}
+
+// Ramp reports whether the segment changes its rate linearly.
+func (s RateSegment) Ramp() bool {
+	return s.From != 0 || s.To != 0
+}
+
+// rates returns the rate at the start and at the end of the segment.
+func (s RateSegment) rates() (float64, float64) {
+	if s.Ramp() {
+		return float64(s.From), float64(s.To)
+	}
+	return float64(s.Rate), float64(s.Rate)
+}
+
+// requests returns the requests due in the first offset of the segment.
+func (s RateSegment) requests(offset time.Duration) float64 {
+	from, to := s.rates()
+	t := offset.Seconds()
+	if from == to {
+		return from * t
+	}
+	return from*t + (to-from)*t*t/(2*s.Duration.Seconds())
+}
+
+// offsetOf returns when the first requests of the segment are due; the
+// inverse of requests.
+func (s RateSegment) offsetOf(requests float64) time.Duration {
+	from, to := s.rates()
+	seconds := requests / from
+	if slope := (to - from) / s.Duration.Seconds(); slope != 0 {
+		// slope/2 t^2 + from t - requests = 0
+		seconds = (math.Sqrt(from*from+2*slope*requests) - from) / slope
+	}
+	return min(time.Duration(seconds*float64(time.Second)), s.Duration)
+}
+
+// Validate checks that the load sends requests and its parts fit together.
+func (l Load) Validate() error {
+	if l.Threads < 0 || l.Connections < 0 {
+		return fmt.Errorf("threads and connections must not be negative, got %d and %d", l.Threads, l.Connections)
+	}
+	if l.Threads > 0 && l.Connections > 0 && l.Threads > l.Connections {
+		return fmt.Errorf("%d threads need at least as many connections, got %d", l.Threads, l.Connections)
+	}
+	if l.Timeout < 0 || l.Warmup < 0 {
+		return fmt.Errorf("timeout and warmup must not be negative, got %s and %s", l.Timeout, l.Warmup)
+	}
+	if len(l.Segments) == 0 {
+		if l.Rate <= 0 || l.Duration <= 0 {
+			return fmt.Errorf("load needs a positive rate and duration, or segments, got rate=%d duration=%s", l.Rate, l.Duration)
+		}
+		return nil
+	}
+	if l.Rate != 0 || l.Duration != 0 {
+		return fmt.Errorf("set either rate and duration or segments, not both")
+	}
+	for i, segment := range l.Segments {
+		switch {
+		case segment.Duration <= 0:
+			return fmt.Errorf("segment %d needs a positive duration, got %s", i+1, segment.Duration)
+		case segment.Rate < 0 || segment.From < 0 || segment.To < 0:
+			return fmt.Errorf("segment %d has a negative rate", i+1)
+		case segment.Ramp() && segment.Rate != 0:
+			return fmt.Errorf("segment %d sets both rate and from/to", i+1)
+		}
+	}
+	if l.TotalRequests() <= 0 {
+		return fmt.Errorf("segments send no requests")
+	}
+	if from, _ := l.Segments[0].rates(); l.Warmup > 0 && from == 0 {
+		return fmt.Errorf("warmup runs at the starting rate of the first segment, which is 0")
+	}
+	return nil
+}
+
+// RateSegments returns the measured part of the load as segments.
+func (l Load) RateSegments() []RateSegment {
+	if len(l.Segments) > 0 {
+		return l.Segments
+	}
+	return []RateSegment{{Duration: l.Duration, Rate: l.Rate}}
+}
+
+// Constant reports whether the load is a single rate without warm-up, the
+// only profile wrk2 can run.
+func (l Load) Constant() bool {
+	return len(l.RateSegments()) == 1 && !l.RateSegments()[0].Ramp() && l.Warmup == 0
+}
+
+// MeasuredDuration returns the duration after the warm-up.
+func (l Load) MeasuredDuration() time.Duration {
+	var total time.Duration
+	for _, segment := range l.RateSegments() {
+		total += segment.Duration
+	}
+	return total
+}
+
+// TotalDuration returns the duration including the warm-up.
+func (l Load) TotalDuration() time.Duration {
+	return l.Warmup + l.MeasuredDuration()
+}
+
+// warmupSegment is the warm-up at the starting rate of the first segment.
+func (l Load) warmupSegment() RateSegment {
+	from, _ := l.RateSegments()[0].rates()
+	return RateSegment{Duration: l.Warmup, Rate: int(from)}
+}
+
+// timeline returns the warm-up, if any, and the measured segments.
+func (l Load) timeline() []RateSegment {
+	if l.Warmup <= 0 {
+		return l.RateSegments()
+	}
+	return append([]RateSegment{l.warmupSegment()}, l.RateSegments()...)
+}
+
+// RequestsBefore returns how many requests are due in the first offset of
+// the stage, warm-up included.
+func (l Load) RequestsBefore(offset time.Duration) float64 {
+	total := 0.0
+	for _, segment := range l.timeline() {
+		if offset <= segment.Duration {
+			return total + segment.requests(max(offset, 0))
+		}
+		total += segment.requests(segment.Duration)
+		offset -= segment.Duration
+	}
+	return total
+}
+
+// OffsetOf returns when the first requests of the stage are due, or false
+// when the load ends before.
+func (l Load) OffsetOf(requests float64) (time.Duration, bool) {
+	var offset time.Duration
+	for _, segment := range l.timeline() {
+		inSegment := segment.requests(segment.Duration)
+		if requests < inSegment {
+			return offset + segment.offsetOf(requests), true
+		}
+		requests -= inSegment
+		offset += segment.Duration
+	}
+	return offset, false
+}
+
+// TotalRequests returns the requests due over the stage, warm-up included.
+func (l Load) TotalRequests() int {
+	return int(math.Round(l.RequestsBefore(l.TotalDuration())))
+}
+
+// MeanRate returns the requests per second due after the warm-up.
+func (l Load) MeanRate() int {
+	measured := l.MeasuredDuration()
+	if measured <= 0 {
+		return 0
+	}
+	requests := l.RequestsBefore(l.TotalDuration()) - l.RequestsBefore(l.Warmup)
+	return int(math.Round(requests / measured.Seconds()))
+}
+
+// Wrk2Args returns the wrk2 arguments of a constant load; --latency is
+// always added. Durations are rounded up to whole seconds, and threads and
+// connections left at zero keep the defaults of wrk2.
+func (l Load) Wrk2Args() ([]string, error) {
+	if !l.Constant() {
+		return nil, fmt.Errorf("wrk2 runs a single rate; rate segments and warmup need the native executor")
+	}
+	var args []string
+	if l.Threads > 0 {
+		args = append(args, "-t"+strconv.Itoa(l.Threads))
+	}
+	if l.Connections > 0 {
+		args = append(args, "-c"+strconv.Itoa(l.Connections))
+	}
+	segment := l.RateSegments()[0]
+	args = append(args, "-d"+wrk2Seconds(segment.Duration), "-R"+strconv.Itoa(segment.Rate))
+	if l.Timeout > 0 {
+		args = append(args, "--timeout", wrk2Seconds(l.Timeout))
+	}
+	for _, arg := range l.ExtraArgs {
+		if arg != "--latency" {
+			args = append(args, arg)
+		}
+	}
+	return append(args, "--latency"), nil
+}
+
+func wrk2Seconds(d time.Duration) string {
+	return strconv.FormatInt(int64(math.Ceil(d.Seconds())), 10) + "s"
+}
+
+// LoadProfile returns the load of the stage, from its load block or
+// translated from wrk2params, with the defaults filled in.
+func (s Stage) LoadProfile() (Load, error) {
+	var load Load
+	switch {
+	case s.Load != nil && strings.TrimSpace(s.Wrk2Params) != "":
+		return load, fmt.Errorf("set either load or wrk2params, not both")
+	case s.Load != nil:
+		load = *s.Load
+	default:
+		var err error
+		if load, err = ParseWrk2Params(s.Wrk2Params); err != nil {
+			return load, err
+		}
+	}
+	if load.Connections == 0 {
+		load.Connections = DefaultConnections
+	}
+	if load.Threads == 0 {
+		load.Threads = min(DefaultThreads, load.Connections)
+	}
+	if err := load.Validate(); err != nil {
+		return load, err
+	}
+	return load, nil
+}
+
+// ---------------------------------------------------------------------------
+// wrk2 parameter parsing
+// ---------------------------------------------------------------------------
+
+// wrk2Flags maps the short and long wrk2 flags the load has fields for to
+// the short one.
+var wrk2Flags = map[string]string{
+	"-t": "-t", "--threads": "-t",
+	"-c": "-c", "--connections": "-c",
+	"-d": "-d", "--duration": "-d",
+	"-R": "-R", "--rate": "-R",
+	"-T": "-T", "--timeout": "-T",
+}
+
+// ParseWrk2Params translates a wrk2 parameter string into a load. Values
+// may follow their flag directly (-R2000), after a space or after "=" for
+// long flags. Durations take the suffixes s (default), m and h. Other flags
+// are kept in ExtraArgs.
+func ParseWrk2Params(params string) (Load, error) {
+	var load Load
+	fields := strings.Fields(params)
+	seen := map[string]bool{}
+	for i := 0; i < len(fields); i++ {
+		flag, value, ok := splitWrk2Flag(fields[i])
+		if !ok {
+			load.ExtraArgs = append(load.ExtraArgs, fields[i])
+			continue
+		}
+		if value == "" {
+			if i+1 == len(fields) {
+				return load, fmt.Errorf("missing value of %s in wrk2params %q", fields[i], params)
+			}
+			i++
+			value = fields[i]
+		}
+		seen[flag] = true
+		var err error
+		switch flag {
+		case "-t":
+			load.Threads, err = strconv.Atoi(value)
+		case "-c":
+			load.Connections, err = strconv.Atoi(value)
+		case "-R":
+			load.Rate, err = strconv.Atoi(value)
+		case "-d":
+			load.Duration, err = parseWrk2Duration(value)
+		case "-T":
+			load.Timeout, err = parseWrk2Duration(value)
+		}
+		if err != nil {
+			return load, fmt.Errorf("invalid %s %q in wrk2params %q: %w", flag, value, params, err)
+		}
+	}
+	if !seen["-R"] {
+		return load, fmt.Errorf("missing -R (rate) in wrk2params %q", params)
+	}
+	if !seen["-d"] {
+		return load, fmt.Errorf("missing -d (duration) in wrk2params %q", params)
+	}
+	return load, nil
+}
+
+// splitWrk2Flag splits a field into a known flag and its attached value.
+func splitWrk2Flag(field string) (flag, value string, ok bool) {
+	if strings.HasPrefix(field, "--") {
+		name, value, _ := strings.Cut(field, "=")
+		flag, ok = wrk2Flags[name]
+		return flag, value, ok
+	}
+	if len(field) < 2 {
+		return "", "", false
+	}
+	flag, ok = wrk2Flags[field[:2]]
+	return flag, field[2:], ok
+}
+
+func parseWrk2Duration(value string) (time.Duration, error) {
+	unit := time.Second
+	switch strings.ToLower(value[len(value)-1:]) {
+	case "s":
+		value = value[:len(value)-1]
+	case "m":
+		unit, value = time.Minute, value[:len(value)-1]
+	case "h":
+		unit, value = time.Hour, value[:len(value)-1]
+	}
+	n, err := strconv.Atoi(value)
+	if err != nil {
+		return 0, err
+	}
+	return time.Duration(n) * unit, nil
+}
@@ -0,0 +1,201 @@
+package flowgen
+
+import (
+	"math"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+)
+
+// ---------------------------------------------------------------------------
+// ParseWrk2Params tests
+// ---------------------------------------------------------------------------
+
+func TestParseWrk2Params_Basic(t *testing.T) {
+	cfg, err := ParseWrk2Params("-t2 -c100 -d30s -R2000")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if cfg.Rate != 2000 {
+		t.Errorf("expected rate 2000, got %d", cfg.Rate)
+	}
+	if cfg.Duration != 30*time.Second {
+		t.Errorf("expected duration 30s, got %s", cfg.Duration)
+	}
+	if cfg.TotalRequests() != 60000 {
+		t.Errorf("expected total 60000, got %d", cfg.TotalRequests())
+	}
+}
+
+func TestParseWrk2Params_Minutes(t *testing.T) {
+	cfg, err := ParseWrk2Params("-d2m -R500")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if cfg.Duration != 2*time.Minute {
+		t.Errorf("expected 2m, got %s", cfg.Duration)
+	}
+	if cfg.TotalRequests() != 60000 {
+		t.Errorf("expected 60000, got %d", cfg.TotalRequests())
+	}
+}
+
+func TestParseWrk2Params_Hours(t *testing.T) {
+	cfg, err := ParseWrk2Params("-d1h -R10")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if cfg.Duration != time.Hour {
+		t.Errorf("expected 1h, got %s", cfg.Duration)
+	}
+}
+
+func TestParseWrk2Params_MissingRate(t *testing.T) {
+	_, err := ParseWrk2Params("-d30s")
+	if err == nil {
+		t.Fatal("expected error for missing -R")
+	}
+}
+
+func TestParseWrk2Params_MissingDuration(t *testing.T) {
+	_, err := ParseWrk2Params("-R2000")
+	if err == nil {
+		t.Fatal("expected error for missing -d")
+	}
+}
+
+func TestParseWrk2Params_TranslatesIntoLoad(t *testing.T) {
+	load, err := ParseWrk2Params("-t4 --connections=64 -d 30s --rate 2000 --timeout 5s -U --latency")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if load.Threads != 4 || load.Connections != 64 || load.Timeout != 5*time.Second || !slices.Equal(load.ExtraArgs, []string{"-U", "--latency"}) {
+		t.Fatalf("load = %+v", load)
+	}
+	args, err := load.Wrk2Args()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	want := []string{"-t4", "-c64", "-d30s", "-R2000", "--timeout", "5s", "-U", "--latency"}
+	if !slices.Equal(args, want) {
+		t.Fatalf("args = %v, want %v", args, want)
+	}
+	load = Load{Duration: 1500 * time.Millisecond, Rate: 10}
+	if args, err := load.Wrk2Args(); err != nil || !slices.Equal(args, []string{"-d2s", "-R10", "--latency"}) {
+		t.Fatalf("args = %v (%v), want the duration in whole seconds and --latency added", args, err)
+	}
+	if _, err := ParseWrk2Params("-d30s -Rlots"); err == nil || !strings.Contains(err.Error(), "invalid -R") {
+		t.Fatalf("err = %v, want an invalid -R", err)
+	}
+}
+
+// ---------------------------------------------------------------------------
+// Load tests
+// ---------------------------------------------------------------------------
+
+func TestLoad_RateSegments(t *testing.T) {
+	load := Load{
+		Threads:     2,
+		Connections: 10,
+		Warmup:      5 * time.Second,
+		Segments: []RateSegment{
+			{Duration: 10 * time.Second, From: 10, To: 110}, // ramp: 600 requests
+			{Duration: 10 * time.Second, Rate: 100},         // plateau: 1000
+			{Duration: 2 * time.Second, Rate: 500},          // spike: 1000
+		},
+	}
+	if err := load.Validate(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	// The warm-up runs 5s at the starting rate of 10 req/s.
+	if got := load.TotalRequests(); got != 2650 {
+		t.Errorf("total requests = %d, want 2650", got)
+	}
+	if got := load.MeanRate(); got != 118 {
+		t.Errorf("mean rate = %d, want 2600/22s", got)
+	}
+	if load.MeasuredDuration() != 22*time.Second || load.TotalDuration() != 27*time.Second {
+		t.Errorf("durations = %s and %s", load.MeasuredDuration(), load.TotalDuration())
+	}
+	// 10t + 5t^2 = 75 three seconds into the ramp.
+	if got := load.RequestsBefore(8 * time.Second); math.Abs(got-125) > 1e-9 {
+		t.Errorf("requests before 8s = %v, want 125", got)
+	}
+	for requests, want := range map[float64]time.Duration{0: 0, 50: 5 * time.Second, 125: 8 * time.Second, 1650: 25 * time.Second, 2150: 26 * time.Second} {
+		if got, ok := load.OffsetOf(requests); !ok || (got-want).Abs() > time.Microsecond {
+			t.Errorf("offset of request %v = %s (%v), want %s", requests, got, ok, want)
+		}
+	}
+	if _, ok := load.OffsetOf(2650); ok {
+		t.Error("the load must end before request 2650")
+	}
+	if _, err := load.Wrk2Args(); err == nil {
+		t.Error("wrk2 cannot run rate segments")
+	}
+}
+
+func TestLoad_Validate(t *testing.T) {
+	cases := map[string]struct {
+		load Load
+		want string
+	}{
+		"no rate":         {Load{Duration: time.Second}, "positive rate"},
+		"rate and ramp":   {Load{Rate: 10, Duration: time.Second, Segments: []RateSegment{{Duration: time.Second, Rate: 5}}}, "not both"},
+		"segment rates":   {Load{Segments: []RateSegment{{Duration: time.Second, Rate: 5, To: 10}}}, "both rate and from/to"},
+		"silent":          {Load{Segments: []RateSegment{{Duration: time.Second}}}, "no requests"},
+		"cold warm-up":    {Load{Warmup: time.Second, Segments: []RateSegment{{Duration: time.Second, To: 10}}}, "warmup"},
+		"few connections": {Load{Threads: 4, Connections: 2, Rate: 10, Duration: time.Second}, "connections"},
+	}
+	for name, tc := range cases {
+		if err := tc.load.Validate(); err == nil || !strings.Contains(err.Error(), tc.want) {
+			t.Errorf("%s: err = %v, want %q", name, err, tc.want)
+		}
+	}
+}
+
+func TestParseDSL_Load(t *testing.T) {
+	dsl, err := ParseDSLBytes([]byte(`
+stages:
+  ramp:
+    load:
+      connections: 1
+      timeout: 500ms
+      warmup: 10s
+      segments:
+        - duration: 30s
+          rate: 50
+        - duration: 1m
+          from: 50
+          to: 200
+    flow:
+      - list:
+        operationId: listItems
+        entrynode: true
+`))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	stage := dsl.Stages["ramp"]
+	load, err := stage.LoadProfile()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if load.Threads != 1 || load.Connections != 1 || load.Timeout != 500*time.Millisecond || load.Warmup != 10*time.Second {
+		t.Fatalf("load = %+v, want one thread by default", load)
+	}
+	if len(load.Segments) != 2 || load.Segments[1] != (RateSegment{Duration: time.Minute, From: 50, To: 200}) {
+		t.Fatalf("segments = %+v", load.Segments)
+	}
+	if got := load.TotalRequests(); got != 500+1500+7500 {
+		t.Fatalf("total requests = %d", got)
+	}
+
+	stage.Wrk2Params = "-d1s -R1"
+	if _, err := stage.LoadProfile(); err == nil || !strings.Contains(err.Error(), "not both") {
+		t.Fatalf("err = %v, want load and wrk2params to exclude each other", err)
+	}
+	if load, err := (Stage{Wrk2Params: "-c1 -d1s -R5"}).LoadProfile(); err != nil || load.Threads != 1 || load.Connections != 1 {
+		t.Fatalf("load = %+v (%v), want the threads capped at the connections", load, err)
+	}
+}
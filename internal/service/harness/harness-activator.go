@@ -0,0 +1,183 @@
+package harness
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/d-iii-s/slsbench/internal/service/activator"
+	"github.com/d-iii-s/slsbench/internal/service/summary"
+	dockertypes "github.com/docker/docker/api/types/container"
+	"github.com/docker/docker/api/types/mount"
+	"github.com/docker/docker/client"
+	"github.com/docker/docker/pkg/stdcopy"
+)
+
+const (
+	// ActivatorReadyLine is logged by `slsbench activator` once it accepts
+	// connections.
+	ActivatorReadyLine = "[activator] listening"
+
+	activatorDir          = "activator"
+	activatorLogFile      = "activator.log"
+	activatorStartTimeout = 60 * time.Second
+	activatorStopTimeout  = 30
+)
+
+// ActivatorOptions puts the scale-to-zero activator between the load
+// generator and the service.
+type ActivatorOptions struct {
+	// Image runs `slsbench activator`. No released image has the command
+	// yet, so it is built from this repository.
+	Image          string
+	Mode           string
+	IdleTimeout    time.Duration
+	MaxConcurrency int
+}
+
+// activatorContainer is a running `slsbench activator` container on the
+// compose network. wrk2 reaches the service through Name.
+type activatorContainer struct {
+	ID         string
+	Name       string
+	dir        string
+	settings   activator.Settings
+	dockerCli  *client.Client
+	stopped    bool
+	stopResult error
+}
+
+// startActivatorContainer starts the activator in front of the service
+// container and waits until it accepts connections. Its events and log go to
+// <runDir>/activator/.
+func startActivatorContainer(
+	ctx context.Context,
+	dockerCli *client.Client,
+	opts *ActivatorOptions,
+	networkName, serviceName string,
+	port int,
+	serviceContainerID, readyPath, dockerSocketPath, runDir string,
+) (*activatorContainer, error) {
+	dir := filepath.Join(runDir, activatorDir)
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, fmt.Errorf("failed to create activator directory: %w", err)
+	}
+	image := opts.Image
+	idleTimeout := opts.IdleTimeout
+	if idleTimeout <= 0 {
+		idleTimeout = activator.DefaultIdleTimeout
+	}
+	socketPath := dockerSocketPath
+	if strings.TrimSpace(socketPath) == "" {
+		socketPath = "/var/run/docker.sock"
+	}
+
+	containerConfig := &dockertypes.Config{
+		Image: image,
+		Cmd: []string{
+			"activator",
+			"--listen", fmt.Sprintf(":%d", port),
+			"--upstream", fmt.Sprintf("http://%s:%d", serviceName, port),
+			"--container-id", serviceContainerID,
+			"--mode", opts.Mode,
+			"--idle-timeout", idleTimeout.String(),
+			"--max-concurrency", strconv.Itoa(opts.MaxConcurrency),
+			"--readiness-path", readyPath,
+			"--events-path", "/activator/" + activator.EventsFile,
+			"--docker-socket-path", "/var/run/docker.sock",
+		},
+	}
+	hostConfig := &dockertypes.HostConfig{
+		NetworkMode: dockertypes.NetworkMode(networkName),
+		Mounts: []mount.Mount{
+			{Type: mount.TypeBind, Source: socketPath, Target: "/var/run/docker.sock"},
+			{Type: mount.TypeBind, Source: dir, Target: "/activator"},
+		},
+	}
+	name := fmt.Sprintf("harness-activator-%d", time.Now().UnixNano())
+	resp, err := dockerCli.ContainerCreate(ctx, containerConfig, hostConfig, nil, nil, name)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create activator container: %w", err)
+	}
+	container := &activatorContainer{
+		ID:        resp.ID,
+		Name:      name,
+		dir:       dir,
+		settings:  activator.Settings{Mode: opts.Mode, IdleTimeout: idleTimeout, MaxConcurrency: opts.MaxConcurrency},
+		dockerCli: dockerCli,
+	}
+	if err := dockerCli.ContainerStart(ctx, resp.ID, dockertypes.StartOptions{}); err != nil {
+		_ = container.stop(context.Background())
+		return nil, fmt.Errorf("failed to start activator container: %w", err)
+	}
+	log.Printf("[harness][activator] begin container=%s image=%s mode=%s idle-timeout=%s max-concurrency=%d", name, image, opts.Mode, idleTimeout, opts.MaxConcurrency)
+
+	deadline := time.Now().Add(activatorStartTimeout)
+	for {
+		logs, err := containerOutput(ctx, dockerCli, resp.ID)
+		if err == nil && strings.Contains(logs, ActivatorReadyLine) {
+			return container, nil
+		}
+		inspect, inspectErr := dockerCli.ContainerInspect(ctx, resp.ID)
+		if inspectErr == nil && inspect.State != nil && !inspect.State.Running {
+			_ = container.stop(context.Background())
+			return nil, fmt.Errorf("activator container exited during start-up: %s", strings.TrimSpace(logs))
+		}
+		if time.Now().After(deadline) {
+			_ = container.stop(context.Background())
+			return nil, fmt.Errorf("activator container did not start listening within %s", activatorStartTimeout)
+		}
+		time.Sleep(100 * time.Millisecond)
+	}
+}
+
+// summary aggregates the activator events between from and to; zero bounds
+// are open.
+func (c *activatorContainer) summary(from, to time.Time) (*summary.ActivatorSummary, error) {
+	events, err := activator.LoadEvents(filepath.Join(c.dir, activator.EventsFile))
+	if err != nil {
+		return nil, err
+	}
+	return activator.Summarize(events, c.settings, from, to), nil
+}
+
+// stop stops the activator, which leaves the service scaled up, writes its
+// log and removes the container. Later calls return the first result.
+func (c *activatorContainer) stop(ctx context.Context) error {
+	if c.stopped {
+		return c.stopResult
+	}
+	c.stopped = true
+	timeout := activatorStopTimeout
+	if err := c.dockerCli.ContainerStop(ctx, c.ID, dockertypes.StopOptions{Timeout: &timeout}); err != nil {
+		c.stopResult = fmt.Errorf("failed to stop activator container: %w", err)
+	}
+	if logs, err := containerOutput(ctx, c.dockerCli, c.ID); err == nil {
+		if err := os.WriteFile(filepath.Join(c.dir, activatorLogFile), []byte(logs), 0o644); err != nil {
+			log.Printf("[harness][activator] failed to write log: %v", err)
+		}
+	}
+	_ = c.dockerCli.ContainerRemove(context.Background(), c.ID, dockertypes.RemoveOptions{Force: true})
+	log.Printf("[harness][activator] done container=%s", c.Name)
+	return c.stopResult
+}
+
+// containerOutput returns the combined stdout and stderr of a container.
+func containerOutput(ctx context.Context, dockerCli *client.Client, containerID string) (string, error) {
+	reader, err := dockerCli.ContainerLogs(ctx, containerID, dockertypes.LogsOptions{ShowStdout: true, ShowStderr: true})
+	if err != nil {
+		return "", err
+	}
+	defer reader.Close()
+	var combined bytes.Buffer
+	if _, err := stdcopy.StdCopy(&combined, &combined, reader); err != nil {
+		return "", err
+	}
+	return combined.String(), nil
+}
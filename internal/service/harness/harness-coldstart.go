@@ -0,0 +1,323 @@
+package harness
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"path/filepath"
+	"slices"
+	"time"
+
+	"github.com/d-iii-s/slsbench/internal/service/datagen"
+	"github.com/d-iii-s/slsbench/internal/service/flowgen"
+	"github.com/d-iii-s/slsbench/internal/service/summary"
+	"github.com/d-iii-s/slsbench/internal/utils"
+	"github.com/docker/compose/v5/pkg/api"
+	dockertypes "github.com/docker/docker/api/types/container"
+	"github.com/docker/docker/client"
+)
+
+// Cold-start modes.
+const (
+	// ColdStartRestart stops and starts the same service container, so the
+	// container filesystem and image layers stay warm.
+	ColdStartRestart = "restart"
+	// ColdStartRecreate removes the service container and creates a new one
+	// from the compose definition, without inheriting anonymous volumes.
+	ColdStartRecreate = "recreate"
+)
+
+// coldStartPollInterval is much finer than defaultFirstResponseInterval,
+// since the first-2xx phase is the quantity being measured.
+const coldStartPollInterval = 10 * time.Millisecond
+
+// ColdStartOptions configures a cold-start measurement.
+type ColdStartOptions struct {
+	Options
+	// Iterations is the number of stop/start (or recreate) cycles.
+	Iterations int
+	// Mode is ColdStartRestart or ColdStartRecreate.
+	Mode string
+	// FirstRequests is the number of flow requests timed after the first
+	// 2xx response of every cycle.
+	FirstRequests int
+	// Stage selects the flow stage whose iterations are replayed; empty
+	// means the first stage in execution order.
+	Stage string
+}
+
+func (o ColdStartOptions) validate() error {
+	if o.Session != nil {
+		return fmt.Errorf("cold-start measurements create their own compose project and cannot use a session")
+	}
+	if o.Iterations <= 0 {
+		return fmt.Errorf("iterations must be positive, got %d", o.Iterations)
+	}
+	if o.FirstRequests < 0 {
+		return fmt.Errorf("first requests must not be negative, got %d", o.FirstRequests)
+	}
+	if o.Mode != ColdStartRestart && o.Mode != ColdStartRecreate {
+		return fmt.Errorf("unknown cold-start mode %q (expected %s or %s)", o.Mode, ColdStartRestart, ColdStartRecreate)
+	}
+	// Without timed first requests no iterations are replayed.
+	return o.Options.validateInputs(o.FirstRequests > 0)
+}
+
+// RunColdStart starts the compose project once and then cycles the service
+// container Iterations times. Every cycle times the container operations,
+// the wait for the first 2xx response on the readiness path and the first
+// FirstRequests requests of the flow. cold-start.json in
+// <result-path>/cold-start-result-<ts>/ holds every sample and a percentile
+// summary per phase.
+func RunColdStart(ctx context.Context, opts ColdStartOptions) (runErr error) {
+	if err := opts.validate(); err != nil {
+		return err
+	}
+	resultDir, err := utils.CreateResultSubdirWithPrefix(opts.ResultPath, "cold-start-result")
+	if err != nil {
+		return fmt.Errorf("failed to create result directory: %w", err)
+	}
+	log.Printf("[harness][cold-start] output directory: %s mode=%s iterations=%d first-requests=%d", resultDir, opts.Mode, opts.Iterations, opts.FirstRequests)
+
+	result := &summary.ColdStartSummary{
+		SchemaVersion: summary.ColdStartSchema,
+		StartedAt:     time.Now().UTC(),
+		ComposeFile:   opts.DockerComposePath,
+		ServiceName:   opts.ServiceName,
+		Port:          opts.Port,
+		Mode:          opts.Mode,
+		Iterations:    opts.Iterations,
+		FirstRequests: opts.FirstRequests,
+		Phases:        []summary.PhaseSummary{},
+		Samples:       []summary.ColdStartSample{},
+	}
+	defer func() {
+		result.FinishedAt = time.Now().UTC()
+		result.Phases = summary.SummarizePhases(result.Samples, coldStartPhases(opts))
+		result.Status = summary.StatusCompleted
+		if runErr != nil {
+			result.Status = summary.StatusFailed
+			result.Error = runErr.Error()
+		}
+		for _, phase := range result.Phases {
+			log.Printf("[harness][cold-start] phase=%s n=%d p50=%.1fms p90=%.1fms p99=%.1fms max=%.1fms",
+				phase.Phase, phase.N, phase.P50Millis, phase.P90Millis, phase.P99Millis, phase.MaxMillis)
+		}
+		if err := summary.WriteJSON(filepath.Join(resultDir, summary.ColdStartFile), result); err != nil && runErr == nil {
+			runErr = fmt.Errorf("failed to write cold-start summary: %w", err)
+		}
+	}()
+
+	dsl, err := flowgen.ParseDSL(opts.FlowPath)
+	if err != nil {
+		return fmt.Errorf("failed to parse flow: %w", err)
+	}
+	stageNames := sortedStageNames(dsl)
+	if len(stageNames) == 0 {
+		return fmt.Errorf("flow has no stages")
+	}
+	result.Stage = opts.Stage
+	if result.Stage == "" {
+		result.Stage = stageNames[0]
+	} else if !slices.Contains(stageNames, result.Stage) {
+		return fmt.Errorf("stage %q is not defined in the flow", result.Stage)
+	}
+	var steps []datagen.MinimalIterationStep
+	var iterationStarts []bool
+	if opts.FirstRequests > 0 {
+		iterations, err := loadStageIterations(opts.ProbeBodiesPath, result.Stage)
+		if err != nil {
+			return err
+		}
+		steps, iterationStarts = flowSteps(iterations, opts.FirstRequests)
+		if len(steps) < opts.FirstRequests {
+			log.Printf("[harness][cold-start] stage=%s only has %d requests; timing those", result.Stage, len(steps))
+		}
+	}
+
+	dockerCli, dockerHost, err := NewDockerClientWithSocket(opts.DockerSocketPath)
+	if err != nil {
+		return err
+	}
+	defer dockerCli.Close()
+
+	session, err := StartComposeSession(ctx, dockerHost, opts.DockerComposePath, fmt.Sprintf("cold-start-%d", time.Now().UnixNano()), opts.ServiceName)
+	if err != nil {
+		return err
+	}
+	result.ComposeName = session.Name
+	defer func() {
+		reason := "success"
+		if runErr != nil {
+			reason = "failure"
+		}
+		if downErr := session.Down(context.Background(), reason); downErr != nil && runErr == nil {
+			runErr = fmt.Errorf("failed to tear down compose project: %w", downErr)
+		}
+	}()
+
+	// The initial boot is not a sample; it finds the reachable address.
+	readyPath := opts.effectiveReadinessPath()
+	initial, err := measureFirstResponse(ctx, opts.ServiceName, opts.Port, readyPath)
+	if err != nil {
+		return fmt.Errorf("failed to wait for the initial start: %w", err)
+	}
+	result.TargetURL = initial.TargetURL
+	containerID, err := findContainerIDByServiceName(ctx, dockerCli, session.Name, opts.ServiceName)
+	if err != nil {
+		return fmt.Errorf("failed to find service container id: %w", err)
+	}
+
+	parsed, err := url.Parse(initial.TargetURL)
+	if err != nil {
+		return fmt.Errorf("invalid readiness target %q: %w", initial.TargetURL, err)
+	}
+	cycler := &coldStartCycler{
+		dockerCli:       dockerCli,
+		session:         session,
+		opts:            opts,
+		containerID:     containerID,
+		targetURL:       initial.TargetURL,
+		client:          &http.Client{Timeout: 5 * time.Second},
+		replayer:        newFlowReplayer(parsed.Scheme+"://"+parsed.Host, DeriveAPIBasePath(opts.OpenAPISpecPath)),
+		steps:           steps,
+		iterationStarts: iterationStarts,
+	}
+	for index := 1; index <= opts.Iterations; index++ {
+		sample, err := cycler.cycle(ctx, index)
+		result.Samples = append(result.Samples, sample)
+		if err != nil {
+			return fmt.Errorf("cold-start cycle %d failed: %w", index, err)
+		}
+		log.Printf("[harness][cold-start] cycle=%d/%d total=%.1fms first-2xx=%.1fms attempts=%d",
+			index, opts.Iterations, sample.PhasesMillis[summary.PhaseTotal], sample.PhasesMillis[summary.PhaseFirst2xx], sample.Attempts)
+	}
+	return nil
+}
+
+// coldStartPhases lists the phases in the order they are summarized.
+func coldStartPhases(opts ColdStartOptions) []string {
+	var phases []string
+	if opts.Mode == ColdStartRecreate {
+		phases = append(phases, summary.PhaseCreate)
+	}
+	phases = append(phases, summary.PhaseStart, summary.PhaseFirst2xx, summary.PhaseTotal)
+	for k := 1; k <= opts.FirstRequests; k++ {
+		phases = append(phases, summary.RequestPhase(k))
+	}
+	return phases
+}
+
+type coldStartCycler struct {
+	dockerCli       *client.Client
+	session         *ComposeSession
+	opts            ColdStartOptions
+	containerID     string
+	targetURL       string
+	client          *http.Client
+	replayer        *flowReplayer
+	steps           []datagen.MinimalIterationStep
+	iterationStarts []bool
+}
+
+func (c *coldStartCycler) cycle(ctx context.Context, index int) (summary.ColdStartSample, error) {
+	sample := summary.ColdStartSample{
+		Index:        index,
+		StartedAt:    time.Now().UTC(),
+		PhasesMillis: map[string]float64{},
+		Requests:     []summary.RequestTiming{},
+	}
+	fail := func(err error) (summary.ColdStartSample, error) {
+		sample.Error = err.Error()
+		return sample, err
+	}
+
+	stopBegin := time.Now()
+	if err := c.dockerCli.ContainerStop(ctx, c.containerID, dockertypes.StopOptions{}); err != nil {
+		return fail(fmt.Errorf("failed to stop service container: %w", err))
+	}
+	sample.StopMillis = millis(time.Since(stopBegin))
+
+	began := time.Now()
+	if c.opts.Mode == ColdStartRecreate {
+		err := c.session.compose.Create(ctx, c.session.Project, api.CreateOptions{
+			Services:             []string{c.opts.ServiceName},
+			Recreate:             api.RecreateForce,
+			RecreateDependencies: api.RecreateNever,
+		})
+		if err != nil {
+			return fail(fmt.Errorf("failed to recreate service container: %w", err))
+		}
+		sample.PhasesMillis[summary.PhaseCreate] = millis(time.Since(began))
+		id, err := findServiceContainerID(ctx, c.dockerCli, c.session.Name, c.opts.ServiceName, true)
+		if err != nil {
+			return fail(fmt.Errorf("failed to find recreated service container: %w", err))
+		}
+		c.containerID = id
+	}
+	sample.ContainerID = c.containerID
+
+	startBegin := time.Now()
+	if err := c.dockerCli.ContainerStart(ctx, c.containerID, dockertypes.StartOptions{}); err != nil {
+		return fail(fmt.Errorf("failed to start service container: %w", err))
+	}
+	started := time.Now()
+	sample.PhasesMillis[summary.PhaseStart] = millis(started.Sub(startBegin))
+
+	attempts, err := waitFor2xx(ctx, c.client, c.targetURL)
+	sample.Attempts = attempts
+	if err != nil {
+		return fail(err)
+	}
+	ready := time.Now()
+	sample.PhasesMillis[summary.PhaseFirst2xx] = millis(ready.Sub(started))
+	sample.PhasesMillis[summary.PhaseTotal] = millis(ready.Sub(began))
+
+	for k, step := range c.steps {
+		if c.iterationStarts[k] {
+			c.replayer.reset()
+		}
+		path, status, latency, err := c.replayer.send(ctx, step)
+		timing := summary.RequestTiming{FlowID: step.FlowID, Method: step.Method, Path: path, StatusCode: status, LatencyMillis: millis(latency)}
+		if err != nil {
+			timing.Error = err.Error()
+		} else {
+			sample.PhasesMillis[summary.RequestPhase(k+1)] = timing.LatencyMillis
+		}
+		sample.Requests = append(sample.Requests, timing)
+	}
+	return sample, nil
+}
+
+// waitFor2xx polls targetURL until it answers with a 2xx status and returns
+// the number of attempts.
+func waitFor2xx(ctx context.Context, httpClient *http.Client, targetURL string) (int, error) {
+	deadlineCtx, cancel := context.WithTimeout(ctx, defaultFirstResponseTimeout)
+	defer cancel()
+	attempts := 0
+	for {
+		attempts++
+		req, err := http.NewRequestWithContext(deadlineCtx, http.MethodGet, targetURL, nil)
+		if err != nil {
+			return attempts, err
+		}
+		resp, err := httpClient.Do(req)
+		if err == nil {
+			_ = resp.Body.Close()
+			if resp.StatusCode >= 200 && resp.StatusCode < 300 {
+				return attempts, nil
+			}
+		}
+		select {
+		case <-deadlineCtx.Done():
+			return attempts, fmt.Errorf("timeout waiting for a 2xx response at %s after %d attempts", targetURL, attempts)
+		case <-time.After(coldStartPollInterval):
+		}
+	}
+}
+
+func millis(d time.Duration) float64 {
+	return float64(d) / float64(time.Millisecond)
+}
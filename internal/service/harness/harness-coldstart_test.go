@@ -0,0 +1,123 @@
+package harness
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	"github.com/d-iii-s/slsbench/internal/service/datagen"
+)
+
+func TestFlowReplayer_ResolvesReferencesFromEarlierSteps(t *testing.T) {
+	var got []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		got = append(got, r.Method+" "+r.URL.RequestURI()+" "+string(body))
+		if r.Method == http.MethodPost {
+			w.WriteHeader(http.StatusCreated)
+			_ = json.NewEncoder(w).Encode(map[string]any{"id": 42})
+		}
+	}))
+	defer server.Close()
+
+	iterations := []datagen.MinimalIteration{
+		{Steps: []datagen.MinimalIterationStep{
+			{FlowID: "addOwner", Method: "post", PathTemplate: "/owners", RequestBody: map[string]any{"name": "Ann"}},
+			{FlowID: "getOwner", Method: "get", PathTemplate: "/owners/{ownerId}", PathParams: map[string]any{"ownerId": "addOwner.responseBody#/id"}, Query: map[string]any{"name": "addOwner.requestBody#/name"}},
+		}},
+		{Steps: []datagen.MinimalIterationStep{
+			{FlowID: "getOwner", Method: "GET", PathTemplate: "/owners/{ownerId}", PathParams: map[string]any{"ownerId": "addOwner.responseBody#/id"}},
+		}},
+	}
+	steps, starts := flowSteps(iterations, 5)
+	if len(steps) != 3 || !starts[0] || starts[1] || !starts[2] {
+		t.Fatalf("unexpected steps %v starts %v", steps, starts)
+	}
+
+	replayer := newFlowReplayer(server.URL, "/api")
+	for i, step := range steps {
+		if starts[i] {
+			replayer.reset()
+		}
+		if _, _, _, err := replayer.send(context.Background(), step); err != nil {
+			t.Fatalf("step %d failed: %v", i, err)
+		}
+	}
+	want := []string{
+		`POST /api/owners {"name":"Ann"}`,
+		"GET /api/owners/42?name=Ann ",
+		"GET /api/owners/addOwner.responseBody%23%2Fid ",
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("request %d = %q, want %q", i, got[i], want[i])
+		}
+	}
+}
+
+func TestWaitFor2xx_IgnoresOtherStatuses(t *testing.T) {
+	var calls atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if calls.Add(1) < 3 {
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+
+	attempts, err := waitFor2xx(context.Background(), server.Client(), server.URL)
+	if err != nil || attempts != 3 {
+		t.Fatalf("waitFor2xx = %d, %v; want 3 attempts", attempts, err)
+	}
+}
+
+func TestColdStartOptions_Validate(t *testing.T) {
+	opts := ColdStartOptions{Iterations: 1, Mode: "reboot"}
+	if err := opts.validate(); err == nil {
+		t.Fatalf("expected an error for an unknown mode")
+	}
+	opts = ColdStartOptions{Iterations: 0, Mode: ColdStartRestart}
+	if err := opts.validate(); err == nil {
+		t.Fatalf("expected an error for zero iterations")
+	}
+}
+
+func TestColdStartOptions_ValidateProbeBodiesOnlyForFirstRequests(t *testing.T) {
+	dir := t.TempDir()
+	path := func(name string) string {
+		p := filepath.Join(dir, name)
+		if err := os.WriteFile(p, nil, 0o644); err != nil {
+			t.Fatal(err)
+		}
+		return p
+	}
+	opts := ColdStartOptions{
+		Options: Options{
+			FlowPath:          path("flow.yaml"),
+			ResultPath:        dir,
+			OpenAPISpecPath:   path("openapi.yml"),
+			DockerComposePath: path("docker-compose.yml"),
+			ServiceName:       "petclinic",
+			Port:              9966,
+		},
+		Iterations: 1,
+		Mode:       ColdStartRestart,
+	}
+	if err := opts.validate(); err != nil {
+		t.Fatalf("unexpected error without first requests: %v", err)
+	}
+	opts.FirstRequests = 5
+	if err := opts.validate(); err == nil || !strings.Contains(err.Error(), "probe-bodies") {
+		t.Fatalf("err = %v, want the probe-bodies path to be required", err)
+	}
+	opts.ProbeBodiesPath = dir
+	if err := opts.validate(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+}
@@ -0,0 +1,221 @@
+package harness
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/d-iii-s/slsbench/internal/service/summary"
+	"github.com/docker/docker/api/types/events"
+	"github.com/docker/docker/api/types/filters"
+	"github.com/docker/docker/client"
+)
+
+// Docker events an event policy can react to.
+const (
+	EventOOM       = "oom"
+	EventDie       = "die"
+	EventUnhealthy = "unhealthy"
+)
+
+// Event policy actions.
+const (
+	// EventActionIgnore only records the event.
+	EventActionIgnore = "ignore"
+	// EventActionInvalidate marks the stage the event happened in invalid.
+	EventActionInvalidate = "invalidate"
+	// EventActionAbort stops the run.
+	EventActionAbort = "abort"
+)
+
+// ErrRunAborted is wrapped by the error of a run stopped by an abort policy.
+var ErrRunAborted = errors.New("run aborted by event policy")
+
+// EventPolicies maps a watched Docker event to the action taken when a
+// container of the compose project emits it.
+type EventPolicies map[string]string
+
+// DefaultEventPolicies invalidates the stage on every watched event.
+func DefaultEventPolicies() EventPolicies {
+	return EventPolicies{
+		EventOOM:       EventActionInvalidate,
+		EventDie:       EventActionInvalidate,
+		EventUnhealthy: EventActionInvalidate,
+	}
+}
+
+// ParseEventPolicies applies <event>=<action> entries on top of the defaults.
+func ParseEventPolicies(raw []string) (EventPolicies, error) {
+	policies := DefaultEventPolicies()
+	for _, entry := range raw {
+		event, action, ok := strings.Cut(strings.TrimSpace(entry), "=")
+		event, action = strings.TrimSpace(event), strings.TrimSpace(action)
+		if !ok {
+			return nil, fmt.Errorf("invalid event policy %q (expected <event>=<action>)", entry)
+		}
+		if _, known := policies[event]; !known {
+			return nil, fmt.Errorf("unknown event %q in event policy (expected %s, %s or %s)", event, EventOOM, EventDie, EventUnhealthy)
+		}
+		switch action {
+		case EventActionIgnore, EventActionInvalidate, EventActionAbort:
+		default:
+			return nil, fmt.Errorf("unknown action %q in event policy (expected %s, %s or %s)", action, EventActionIgnore, EventActionInvalidate, EventActionAbort)
+		}
+		policies[event] = action
+	}
+	return policies, nil
+}
+
+// eventWatcher records the container events of a compose project into
+// docker-events.jsonl and applies the event policies to them.
+type eventWatcher struct {
+	policies EventPolicies
+	phases   *timeline
+	abort    context.CancelCauseFunc
+	// expectedDie is a container whose die events are part of the run, such
+	// as the service stopped by the activator.
+	expectedDie string
+
+	mu      sync.Mutex
+	encoder *json.Encoder
+	invalid map[string][]string
+
+	file   *os.File
+	cancel context.CancelFunc
+	done   chan struct{}
+}
+
+func newEventWatcher(policies EventPolicies, phases *timeline, abort context.CancelCauseFunc) *eventWatcher {
+	if policies == nil {
+		policies = DefaultEventPolicies()
+	}
+	return &eventWatcher{policies: policies, phases: phases, abort: abort, invalid: map[string][]string{}}
+}
+
+// start subscribes to the container events of the project and writes them
+// to outputPath until stop is called.
+func (w *eventWatcher) start(ctx context.Context, dockerCli *client.Client, projectName, outputPath string) error {
+	file, err := os.Create(outputPath)
+	if err != nil {
+		return fmt.Errorf("failed to create docker events file: %w", err)
+	}
+	w.file = file
+	w.encoder = json.NewEncoder(file)
+
+	watchCtx, cancel := context.WithCancel(ctx)
+	w.cancel = cancel
+	w.done = make(chan struct{})
+	messages, errs := dockerCli.Events(watchCtx, events.ListOptions{
+		Filters: filters.NewArgs(
+			filters.Arg("type", string(events.ContainerEventType)),
+			filters.Arg("label", fmt.Sprintf("%s=%s", "com.docker.compose.project", projectName)),
+		),
+	})
+	log.Printf("[harness][events] watching project=%s output=%s", projectName, outputPath)
+	go func() {
+		defer close(w.done)
+		for {
+			select {
+			case message := <-messages:
+				w.handle(message)
+			case err := <-errs:
+				if err != nil && watchCtx.Err() == nil {
+					log.Printf("[harness][events] stream failed: %v", err)
+				}
+				return
+			}
+		}
+	}()
+	return nil
+}
+
+// handle records one Docker event and applies its policy.
+func (w *eventWatcher) handle(message events.Message) {
+	event := watchedEvent(message.Action)
+	if event == "" {
+		return
+	}
+	at := time.Unix(0, message.TimeNano).UTC()
+	if message.TimeNano == 0 {
+		at = time.Unix(message.Time, 0).UTC()
+	}
+	record := summary.DockerEvent{
+		Time:          at,
+		Event:         event,
+		Service:       message.Actor.Attributes["com.docker.compose.service"],
+		ContainerID:   message.Actor.ID,
+		ContainerName: message.Actor.Attributes["name"],
+		ExitCode:      message.Actor.Attributes["exitCode"],
+	}
+	record.Phase, record.Stage = w.phases.phaseAt(at)
+	record.Action = w.policies[event]
+	// A clean exit is a finished one-off container, and the activator stops
+	// the service on purpose.
+	if event == EventDie && (record.ExitCode == "0" || message.Actor.ID == w.expectedDie) {
+		record.Action = EventActionIgnore
+	}
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.encoder != nil {
+		if err := w.encoder.Encode(record); err != nil {
+			log.Printf("[harness][events] failed to write event: %v", err)
+		}
+	}
+	reason := fmt.Sprintf("%s event from service %s (container %s) at %s", event, record.Service, record.ContainerName, at.Format(time.RFC3339Nano))
+	log.Printf("[harness][events] %s phase=%s stage=%s action=%s", reason, record.Phase, record.Stage, record.Action)
+	switch record.Action {
+	case EventActionInvalidate:
+		if record.Phase == summary.TimelineStage {
+			w.invalid[record.Stage] = append(w.invalid[record.Stage], reason)
+		}
+	case EventActionAbort:
+		if w.abort != nil {
+			w.abort(fmt.Errorf("%w: %s", ErrRunAborted, reason))
+		}
+	}
+}
+
+// invalidReasons returns why the stage is invalid, or nil.
+func (w *eventWatcher) invalidReasons(stage string) []string {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	reasons := append([]string(nil), w.invalid[stage]...)
+	sort.Strings(reasons)
+	return reasons
+}
+
+// stop ends the subscription and closes the events file.
+func (w *eventWatcher) stop() {
+	if w.cancel == nil {
+		return
+	}
+	w.cancel()
+	<-w.done
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	_ = w.file.Close()
+	w.encoder = nil
+	w.cancel = nil
+}
+
+// watchedEvent maps a Docker action to the event name used by the policies,
+// or "" for actions the harness does not watch.
+func watchedEvent(action events.Action) string {
+	switch action {
+	case events.ActionOOM:
+		return EventOOM
+	case events.ActionDie:
+		return EventDie
+	case events.ActionHealthStatusUnhealthy:
+		return EventUnhealthy
+	}
+	return ""
+}
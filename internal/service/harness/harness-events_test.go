@@ -0,0 +1,73 @@
+package harness
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/d-iii-s/slsbench/internal/service/summary"
+	"github.com/docker/docker/api/types/events"
+)
+
+func TestParseEventPolicies(t *testing.T) {
+	policies, err := ParseEventPolicies([]string{"oom=abort", " die = ignore "})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if policies[EventOOM] != EventActionAbort || policies[EventDie] != EventActionIgnore || policies[EventUnhealthy] != EventActionInvalidate {
+		t.Fatalf("unexpected policies: %v", policies)
+	}
+	for _, raw := range []string{"oom", "restart=abort", "oom=panic"} {
+		if _, err := ParseEventPolicies([]string{raw}); err == nil {
+			t.Fatalf("expected %q to be rejected", raw)
+		}
+	}
+}
+
+func TestEventWatcher_InvalidatesStageAndAborts(t *testing.T) {
+	start := time.Date(2026, 5, 1, 12, 0, 0, 0, time.UTC)
+	phases := newTimeline()
+	phases.add(summary.TimelineEntry{Phase: summary.TimelineStage, Stage: "warmup", StartedAt: start, FinishedAt: start.Add(time.Minute)})
+
+	ctx, abort := context.WithCancelCause(context.Background())
+	defer abort(nil)
+	policies := DefaultEventPolicies()
+	policies[EventUnhealthy] = EventActionAbort
+	watcher := newEventWatcher(policies, phases, abort)
+	watcher.expectedDie = "svc"
+
+	message := func(action events.Action, id, exitCode string, at time.Time) events.Message {
+		return events.Message{
+			Type:     events.ContainerEventType,
+			Action:   action,
+			TimeNano: at.UnixNano(),
+			Actor: events.Actor{ID: id, Attributes: map[string]string{
+				"com.docker.compose.service": "postgres",
+				"name":                       "harness-1-postgres-1",
+				"exitCode":                   exitCode,
+			}},
+		}
+	}
+	watcher.handle(message(events.ActionStart, "db", "", start.Add(time.Second)))
+	watcher.handle(message(events.ActionDie, "db", "0", start.Add(2*time.Second)))
+	watcher.handle(message(events.ActionDie, "svc", "137", start.Add(3*time.Second)))
+	if reasons := watcher.invalidReasons("warmup"); len(reasons) != 0 {
+		t.Fatalf("clean and expected exits must not invalidate the stage: %v", reasons)
+	}
+
+	watcher.handle(message(events.ActionOOM, "db", "", start.Add(4*time.Second)))
+	// Outside every stage: recorded, but no stage to invalidate.
+	watcher.handle(message(events.ActionOOM, "db", "", start.Add(2*time.Minute)))
+	if reasons := watcher.invalidReasons("warmup"); len(reasons) != 1 {
+		t.Fatalf("invalid reasons = %v, want one oom", reasons)
+	}
+	if ctx.Err() != nil {
+		t.Fatal("run aborted before any abort policy matched")
+	}
+
+	watcher.handle(message(events.ActionHealthStatusUnhealthy, "db", "", start.Add(5*time.Second)))
+	if !errors.Is(context.Cause(ctx), ErrRunAborted) {
+		t.Fatalf("cause = %v, want ErrRunAborted", context.Cause(ctx))
+	}
+}
@@ -0,0 +1,539 @@
+package harness
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/d-iii-s/slsbench/internal/service/datagen"
+	"github.com/d-iii-s/slsbench/internal/service/flowgen"
+	"github.com/d-iii-s/slsbench/internal/service/summary"
+	"github.com/docker/docker/client"
+)
+
+// Executor names accepted by Options.Executor.
+const (
+	// ExecutorWrk2Flow runs every stage in a wrk2-flow container on the
+	// compose network.
+	ExecutorWrk2Flow = "wrk2-flow"
+	// ExecutorNative replays the stages from the harness process.
+	ExecutorNative = "native"
+)
+
+const (
+	// nativeRequestTimeout matches the default socket timeout of wrk2.
+	nativeRequestTimeout = 2 * time.Second
+	// operationErrorSamples failed requests are kept per flow node, with at
+	// most errorSampleBodyBytes of their response body.
+	operationErrorSamples = 5
+	errorSampleBodyBytes  = 512
+)
+
+// executor generates the load of one stage.
+type executor interface {
+	// name is recorded as the executor of the stage summary.
+	name() string
+	// run drives the stage until its load has ended or stage.interrupt is
+	// closed. The returned Stdout holds a wrk2 --latency report.
+	run(ctx context.Context, stage stageRun) (*stageExecution, error)
+}
+
+// stageRun is the input of one executor run.
+type stageRun struct {
+	name string
+	load flowgen.Load
+	// targetHost and port are the service as seen from the compose network.
+	targetHost string
+	port       int
+	iterations []datagen.MinimalIteration
+	// inputDir holds the iterations as <inputDir>/<name>/iteration-*.json.
+	inputDir  string
+	outputDir string
+	// statsPath receives the stats of a load generator container.
+	statsPath string
+	phases    *timeline
+	// interrupt is closed when the stage has to end early.
+	interrupt <-chan struct{}
+	// seriesInterval is the interval of the stage time series.
+	seriesInterval time.Duration
+	// thinkTime is set when iteration steps carry think times, which are
+	// drawn with thinkTimeSeed.
+	thinkTime     bool
+	thinkTimeSeed int64
+}
+
+// stageExecution is what an executor run left behind.
+type stageExecution struct {
+	Executor   string
+	StartedAt  time.Time
+	FinishedAt time.Time
+	ExitCode   int64
+	Stdout     string
+	// GuardReason is the guard rail that stopped the stage early, if any.
+	GuardReason string
+	// ThinkTime is the pacing the executor measured, if it did.
+	ThinkTime *summary.ThinkTimeSummary
+}
+
+// newExecutor returns the executor selected by opts.Executor. serviceURL is
+// the service address reachable from this process, used by the native
+// executor.
+func newExecutor(opts Options, dockerCli *client.Client, networkName, serviceURL string) (executor, error) {
+	switch opts.Executor {
+	case "", ExecutorWrk2Flow:
+		return &wrk2FlowExecutor{dockerCli: dockerCli, networkName: networkName, debugNon2xx: opts.DebugNon2xx}, nil
+	case ExecutorNative:
+		return &nativeExecutor{baseURL: serviceURL, apiBasePath: DeriveAPIBasePath(opts.OpenAPISpecPath)}, nil
+	}
+	return nil, fmt.Errorf("unknown executor %q (expected %s or %s)", opts.Executor, ExecutorWrk2Flow, ExecutorNative)
+}
+
+// wrk2FlowExecutor runs the aape2k/wrk2-flow image, which reads the stage
+// iterations from /flowdata and writes its own stats to /stats.
+type wrk2FlowExecutor struct {
+	dockerCli   *client.Client
+	networkName string
+	debugNon2xx bool
+}
+
+func (e *wrk2FlowExecutor) name() string { return wrkFlowImage }
+
+// nativeExecutor is an open-loop executor in Go. Each of the connections
+// replays whole iterations and sends every connections-th request of the
+// load profile, a pace of rate/connections requests per second. Latency is
+// measured from the time a request was due rather than when it was sent,
+// so a slow response delays later requests without hiding their wait (the
+// coordinated omission correction of wrk2). A step with a think time is due
+// no sooner than that pause after the previous response, and the pace of
+// the connection continues from there. Requests due during the warm-up are
+// sent but not measured.
+type nativeExecutor struct {
+	baseURL     string
+	apiBasePath string
+}
+
+func (e *nativeExecutor) name() string { return ExecutorNative }
+
+func (e *nativeExecutor) run(ctx context.Context, stage stageRun) (*stageExecution, error) {
+	load := stage.load
+	if err := load.Validate(); err != nil {
+		return nil, fmt.Errorf("stage %q has an invalid load: %w", stage.name, err)
+	}
+	if len(stage.iterations) == 0 {
+		return nil, fmt.Errorf("stage %q has no iterations to replay", stage.name)
+	}
+	threads := load.Threads
+	if threads == 0 {
+		threads = flowgen.DefaultThreads
+	}
+	connections := load.Connections
+	if connections == 0 {
+		connections = flowgen.DefaultConnections
+	}
+	timeout := load.Timeout
+	if timeout == 0 {
+		timeout = nativeRequestTimeout
+	}
+
+	client := &http.Client{
+		Timeout: timeout,
+		Transport: &http.Transport{
+			Proxy:               http.ProxyFromEnvironment,
+			MaxConnsPerHost:     connections,
+			MaxIdleConnsPerHost: connections,
+		},
+	}
+	defer client.CloseIdleConnections()
+	rec := newNativeRecorder()
+	var thinkTimes thinkTimeRecorder
+
+	series, err := newSeriesRecorder(filepath.Join(stage.outputDir, summary.SeriesFile), stage.name, stage.seriesInterval)
+	if err != nil {
+		return nil, err
+	}
+	stopSeries := series.start()
+	defer stopSeries()
+
+	execution := &stageExecution{Executor: e.name(), StartedAt: time.Now().UTC()}
+	endStage := stage.phases.begin(summary.TimelineStage, stage.name)
+	start := time.Now()
+	measured := start.Add(load.Warmup)
+
+	progressDone := make(chan struct{})
+	progressStopped := make(chan struct{})
+	go func() {
+		defer close(progressStopped)
+		ticker := time.NewTicker(guardPollInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-progressDone:
+				return
+			case <-ticker.C:
+				rec.writeProgress(filepath.Join(stage.outputDir, executorProgressFile))
+			}
+		}
+	}()
+
+	var next atomic.Int64
+	var wg sync.WaitGroup
+	for worker := range connections {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			replayer := newFlowReplayer(e.baseURL, e.apiBasePath)
+			replayer.client = client
+			rng := newThinkTimeRand(stage.thinkTimeSeed, worker)
+			var steps []datagen.MinimalIterationStep
+			var responded time.Time
+			// position counts the requests of the load due before this
+			// connection sends its next one.
+			for position := float64(worker); ; position += float64(connections) {
+				offset, ok := load.OffsetOf(position)
+				if !ok {
+					return
+				}
+				due := start.Add(offset)
+				if len(steps) == 0 {
+					iteration := stage.iterations[int(next.Add(1)-1)%len(stage.iterations)]
+					steps = iteration.Steps
+					replayer.reset()
+				}
+				var pause time.Duration
+				if len(steps) > 0 && steps[0].ThinkTime != nil {
+					pause = drawThinkTime(steps[0].ThinkTime, rng)
+					if ready := responded.Add(pause); ready.After(due) {
+						due = ready
+						position = load.RequestsBefore(due.Sub(start))
+					}
+				}
+				if due.Sub(start) >= load.TotalDuration() {
+					return
+				}
+				timer := time.NewTimer(time.Until(due))
+				select {
+				case <-ctx.Done():
+					timer.Stop()
+					return
+				case <-stage.interrupt:
+					timer.Stop()
+					return
+				case <-timer.C:
+				}
+				if len(steps) == 0 {
+					continue
+				}
+				if steps[0].ThinkTime != nil && !due.Before(measured) {
+					thinkTimes.record(pause)
+				}
+				step := steps[0]
+				steps = steps[1:]
+				received := replayer.received
+				_, status, latency, err := replayer.send(ctx, step)
+				responded = time.Now()
+				if due.Before(measured) {
+					continue
+				}
+				corrected := time.Since(due)
+				rec.record(step, due, corrected, latency, status, replayer.received-received, replayer.lastBody, err)
+				series.record(status, corrected, err)
+			}
+		}()
+	}
+	wg.Wait()
+	close(progressDone)
+	<-progressStopped
+	stopSeries()
+	endStage(ctx.Err())
+	if err := ctx.Err(); err != nil {
+		return nil, fmt.Errorf("native executor stopped for stage=%s: %w", stage.name, err)
+	}
+	execution.FinishedAt = time.Now().UTC()
+	elapsed := max(time.Since(measured), 0)
+
+	rec.writeProgress(filepath.Join(stage.outputDir, executorProgressFile))
+	uncorrected := slices.Contains(load.ExtraArgs, "-U") || slices.Contains(load.ExtraArgs, "--u_latency")
+	report := rec.report(stage.targetHost, stage.port, threads, connections, elapsed, uncorrected)
+	execution.Stdout = report
+	if err := os.WriteFile(filepath.Join(stage.outputDir, wrk2OutputFile), []byte(report), 0o644); err != nil {
+		return nil, fmt.Errorf("failed to write native executor report for stage=%s: %w", stage.name, err)
+	}
+	if err := summary.WriteJSON(filepath.Join(stage.outputDir, summary.OperationsFile), rec.operationsSummary(stage.name, elapsed)); err != nil {
+		return nil, fmt.Errorf("failed to write operations for stage=%s: %w", stage.name, err)
+	}
+	if stage.thinkTime {
+		execution.ThinkTime = thinkTimes.summary(stage.thinkTimeSeed, rec.responses+rec.errors.Total(), elapsed)
+	}
+	log.Printf("[harness][native] stage=%s requests=%d non2xx3xx=%d socket-errors=%d", stage.name, rec.responses, rec.non2xx3xx, rec.errors.Total())
+	return execution, nil
+}
+
+// operationKey identifies a flow node. Steps of iterations probed without
+// node names are told apart by flow id, method and path template.
+type operationKey struct {
+	node         string
+	flowID       string
+	method       string
+	pathTemplate string
+}
+
+func newOperationKey(step datagen.MinimalIterationStep) operationKey {
+	path := step.PathTemplate
+	if strings.TrimSpace(path) == "" {
+		path = step.ResolvedPath
+	}
+	method := strings.ToUpper(strings.TrimSpace(step.Method))
+	if method == "" {
+		method = http.MethodGet
+	}
+	return operationKey{node: step.Node, flowID: step.FlowID, method: method, pathTemplate: path}
+}
+
+// nativeOperation collects the outcomes of one flow node.
+type nativeOperation struct {
+	requests     int64
+	latency      summary.LatencyHistogram
+	statusCodes  map[string]int64
+	non2xx3xx    int64
+	socketErrors int64
+	samples      []summary.ErrorSample
+}
+
+// nativeRecorder collects the outcome of every request of a stage.
+type nativeRecorder struct {
+	mu sync.Mutex
+	// responses counts the requests that got a response. corrected is
+	// measured from the due time, uncorrected from the send.
+	responses   int64
+	corrected   summary.LatencyHistogram
+	uncorrected summary.LatencyHistogram
+	bytesRead   int64
+	non2xx3xx   int64
+	errors      summary.SocketErrors
+	operations  map[operationKey]*nativeOperation
+}
+
+func newNativeRecorder() *nativeRecorder {
+	return &nativeRecorder{operations: map[operationKey]*nativeOperation{}}
+}
+
+// record adds the outcome of step, which was due at due. body is the
+// response body, kept for error samples.
+func (r *nativeRecorder) record(step datagen.MinimalIterationStep, due time.Time, corrected, uncorrected time.Duration, status int, bytesRead int64, body []byte, err error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	key := newOperationKey(step)
+	op := r.operations[key]
+	if op == nil {
+		op = &nativeOperation{statusCodes: map[string]int64{}}
+		r.operations[key] = op
+	}
+	op.requests++
+	if err != nil {
+		// Like wrk2, failed requests count as errors but not as samples.
+		op.socketErrors++
+		var netErr net.Error
+		var opErr *net.OpError
+		switch {
+		case errors.As(err, &netErr) && netErr.Timeout():
+			r.errors.Timeout++
+		case errors.As(err, &opErr) && opErr.Op == "dial":
+			r.errors.Connect++
+		default:
+			r.errors.Read++
+		}
+		op.sample(summary.ErrorSample{Time: due.UTC(), StatusCode: status, Error: err.Error()})
+		return
+	}
+	op.statusCodes[strconv.Itoa(status)]++
+	op.latency.Record(corrected)
+	if status < 200 || status > 399 {
+		r.non2xx3xx++
+		op.non2xx3xx++
+		if len(body) > errorSampleBodyBytes {
+			body = body[:errorSampleBodyBytes]
+		}
+		op.sample(summary.ErrorSample{Time: due.UTC(), StatusCode: status, ResponseBody: string(body)})
+	}
+	r.bytesRead += bytesRead
+	r.responses++
+	r.corrected.Record(corrected)
+	r.uncorrected.Record(uncorrected)
+}
+
+func (op *nativeOperation) sample(sample summary.ErrorSample) {
+	if len(op.samples) < operationErrorSamples {
+		op.samples = append(op.samples, sample)
+	}
+}
+
+// writeProgress rewrites the progress file the guard rails read.
+func (r *nativeRecorder) writeProgress(path string) {
+	r.mu.Lock()
+	progress := executorProgress{Requests: r.responses + r.errors.Total(), Non2xx3xx: r.non2xx3xx}
+	r.mu.Unlock()
+	raw, err := json.Marshal(progress)
+	if err != nil {
+		return
+	}
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, raw, 0o644); err != nil {
+		log.Printf("[harness][native] failed to write progress: %v", err)
+		return
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		log.Printf("[harness][native] failed to write progress: %v", err)
+	}
+}
+
+// operationsSummary breaks the stage down by flow node.
+func (r *nativeRecorder) operationsSummary(stage string, elapsed time.Duration) summary.OperationsSummary {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	result := summary.OperationsSummary{
+		SchemaVersion:   summary.OperationsSchema,
+		Stage:           stage,
+		DurationSeconds: elapsed.Seconds(),
+		Operations:      []summary.OperationStats{},
+	}
+	for key, op := range r.operations {
+		stats := summary.OperationStats{
+			Node:         key.node,
+			FlowID:       key.flowID,
+			Method:       key.method,
+			PathTemplate: key.pathTemplate,
+			Requests:     op.requests,
+			Latency:      op.latency.Summary(reportPercentiles),
+			Histogram:    op.latency.Buckets(),
+			StatusCodes:  op.statusCodes,
+			Non2xx3xx:    op.non2xx3xx,
+			SocketErrors: op.socketErrors,
+			ErrorSamples: op.samples,
+		}
+		if elapsed > 0 {
+			stats.RequestsPerSecond = float64(op.requests) / elapsed.Seconds()
+		}
+		result.Operations = append(result.Operations, stats)
+	}
+	sort.Slice(result.Operations, func(i, j int) bool {
+		a, b := result.Operations[i], result.Operations[j]
+		if a.Node != b.Node {
+			return a.Node < b.Node
+		}
+		if a.FlowID != b.FlowID {
+			return a.FlowID < b.FlowID
+		}
+		return a.Method+" "+a.PathTemplate < b.Method+" "+b.PathTemplate
+	})
+	return result
+}
+
+// report renders the results in the layout of wrk2 --latency, which
+// summary.ParseWrk2Output reads.
+func (r *nativeRecorder) report(host string, port, threads, connections int, elapsed time.Duration, uncorrected bool) string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	var b strings.Builder
+	fmt.Fprintf(&b, "Running %s test @ http://%s:%d/ (native executor)\n", elapsed.Round(time.Second), host, port)
+	fmt.Fprintf(&b, "  %d threads and %d connections\n", threads, connections)
+	latency := r.corrected.Summary(nil)
+	fmt.Fprintf(&b, "  Thread Stats   Avg      Stdev     Max   +/- Stdev\n")
+	fmt.Fprintf(&b, "    Latency %9s %9s %9s\n", formatWrk2Latency(latency.MeanMillis), formatWrk2Latency(latency.StdDevMillis), formatWrk2Latency(latency.MaxMillis))
+	writeLatencyDistribution(&b, "Recorded", &r.corrected)
+	if uncorrected {
+		writeLatencyDistribution(&b, "Uncorrected", &r.uncorrected)
+	}
+	requests := r.responses
+	fmt.Fprintf(&b, "  %d requests in %.2fs, %s read\n", requests, elapsed.Seconds(), formatWrk2Bytes(float64(r.bytesRead)))
+	if r.errors.Total() > 0 {
+		fmt.Fprintf(&b, "  Socket errors: connect %d, read %d, write %d, timeout %d\n", r.errors.Connect, r.errors.Read, r.errors.Write, r.errors.Timeout)
+	}
+	if r.non2xx3xx > 0 {
+		fmt.Fprintf(&b, "  Non-2xx or 3xx responses: %d\n", r.non2xx3xx)
+	}
+	var requestRate, byteRate float64
+	if elapsed > 0 {
+		requestRate = float64(requests) / elapsed.Seconds()
+		byteRate = float64(r.bytesRead) / elapsed.Seconds()
+	}
+	fmt.Fprintf(&b, "Requests/sec: %9.2f\n", requestRate)
+	fmt.Fprintf(&b, "Transfer/sec: %10s\n", formatWrk2Bytes(byteRate))
+	return b.String()
+}
+
+// reportPercentiles are the rows of the wrk2 "Latency Distribution" block.
+var reportPercentiles = []float64{50, 75, 90, 99, 99.9, 99.99, 99.999, 100}
+
+// writeLatencyDistribution prints the percentile block and a detailed
+// spectrum of a latency histogram.
+func writeLatencyDistribution(b *strings.Builder, kind string, latencies *summary.LatencyHistogram) {
+	fmt.Fprintf(b, "  Latency Distribution (HdrHistogram - %s Latency)\n", kind)
+	for _, p := range reportPercentiles {
+		fmt.Fprintf(b, " %7.3f%% %9s\n", p, formatWrk2Latency(latencies.Percentile(p)))
+	}
+	fmt.Fprintf(b, "\n  Detailed Percentile spectrum:\n")
+	fmt.Fprintf(b, "       Value   Percentile   TotalCount 1/(1-Percentile)\n\n")
+	n := latencies.Count()
+	buckets := latencies.Buckets()
+	quantiles := []float64{0, 0.1, 0.2, 0.3, 0.4, 0.5, 0.6, 0.7, 0.8, 0.9}
+	for tail := 0.05; n > 0 && tail*float64(n) >= 1; tail /= 2 {
+		quantiles = append(quantiles, 1-tail)
+	}
+	for _, q := range quantiles {
+		if n == 0 {
+			break
+		}
+		value := latencies.Percentile(100 * q)
+		var count int64
+		for _, bucket := range buckets {
+			if bucket.LatencyMillis > value {
+				break
+			}
+			count += bucket.Count
+		}
+		fmt.Fprintf(b, "%12.3f %12.6f %12d %14.2f\n", value, q, count, 1/(1-q))
+	}
+	latency := latencies.Summary(nil)
+	if n > 0 {
+		fmt.Fprintf(b, "%12.3f %12.6f %12d %14s\n", latency.MaxMillis, 1.0, n, "inf")
+	}
+	fmt.Fprintf(b, "#[Mean    = %12.3f, StdDeviation   = %12.3f]\n", latency.MeanMillis, latency.StdDevMillis)
+	fmt.Fprintf(b, "#[Max     = %12.3f, Total count    = %12d]\n", latency.MaxMillis, n)
+	fmt.Fprintf(b, "----------------------------------------------------------\n")
+}
+
+// formatWrk2Latency prints milliseconds with the unit wrk2 would choose.
+func formatWrk2Latency(millis float64) string {
+	switch {
+	case millis < 1:
+		return fmt.Sprintf("%.2fus", millis*1000)
+	case millis < 1000:
+		return fmt.Sprintf("%.2fms", millis)
+	default:
+		return fmt.Sprintf("%.2fs", millis/1000)
+	}
+}
+
+// formatWrk2Bytes prints a size in binary units like wrk2.
+func formatWrk2Bytes(bytes float64) string {
+	units := []string{"B", "KB", "MB", "GB", "TB"}
+	unit := 0
+	for bytes >= 1024 && unit < len(units)-1 {
+		bytes /= 1024
+		unit++
+	}
+	return fmt.Sprintf("%.2f%s", bytes, units[unit])
+}
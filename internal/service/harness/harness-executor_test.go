@@ -0,0 +1,185 @@
+package harness
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/d-iii-s/slsbench/internal/service/datagen"
+	"github.com/d-iii-s/slsbench/internal/service/flowgen"
+	"github.com/d-iii-s/slsbench/internal/service/summary"
+)
+
+func TestNativeExecutor_ReplaysIterationsAtTheTargetRate(t *testing.T) {
+	var created, fetched, wrongID atomic.Int64
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.Method == http.MethodPost && r.URL.Path == "/owners":
+			created.Add(1)
+			w.Header().Set("Content-Type", "application/json")
+			_, _ = w.Write([]byte(`{"id":7}`))
+		case r.Method == http.MethodGet && r.URL.Path == "/owners/7":
+			fetched.Add(1)
+			w.WriteHeader(http.StatusNotFound)
+			_, _ = w.Write([]byte("owner not found"))
+		default:
+			wrongID.Add(1)
+			w.WriteHeader(http.StatusBadRequest)
+		}
+	}))
+	defer server.Close()
+
+	iterations := []datagen.MinimalIteration{{Steps: []datagen.MinimalIterationStep{
+		{FlowID: "addOwner", Method: "POST", ResolvedPath: "/owners", RequestBody: map[string]any{"name": "a"}},
+		{FlowID: "getOwner", Method: "GET", PathTemplate: "/owners/{id}", PathParams: map[string]any{"id": "addOwner.responseBody#/id"}},
+	}}}
+	runDir := t.TempDir()
+	outputDir := filepath.Join(runDir, "wrk2-results", "browse")
+	if err := os.MkdirAll(outputDir, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	exec := &nativeExecutor{baseURL: server.URL}
+	execution, err := exec.run(context.Background(), stageRun{
+		name:       "browse",
+		load:       flowgen.Load{Threads: 1, Connections: 2, Rate: 100, Duration: time.Second},
+		targetHost: "petclinic",
+		port:       9966,
+		iterations: iterations,
+		outputDir:  outputDir,
+	})
+	if err != nil {
+		t.Fatalf("run: %v", err)
+	}
+	if wrongID.Load() != 0 || fetched.Load() == 0 || created.Load() < fetched.Load() {
+		t.Fatalf("created=%d fetched=%d unexpected=%d", created.Load(), fetched.Load(), wrongID.Load())
+	}
+
+	result, err := summary.ParseWrk2Output(execution.Stdout)
+	if err != nil {
+		t.Fatalf("report not parsed: %v\n%s", err, execution.Stdout)
+	}
+	if result.Requests < 90 || result.Requests > 100 || result.Connections != 2 {
+		t.Fatalf("requests=%d connections=%d, want about 100 over 2 connections", result.Requests, result.Connections)
+	}
+	if result.Non2xx3xx != fetched.Load() {
+		t.Fatalf("non-2xx = %d, want the %d 404s", result.Non2xx3xx, fetched.Load())
+	}
+	if p99, ok := result.Latency.Percentile(99); !ok || p99 <= 0 || len(result.Latency.Spectrum) == 0 {
+		t.Fatalf("p99=%v ok=%v spectrum=%d", p99, ok, len(result.Latency.Spectrum))
+	}
+	progress, err := readExecutorProgress(filepath.Join(outputDir, executorProgressFile))
+	if err != nil || progress.Requests != result.Requests {
+		t.Fatalf("progress = %+v (%v), want %d requests", progress, err, result.Requests)
+	}
+	series, err := summary.LoadSeries(runDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var seriesRequests int64
+	for _, sample := range series {
+		seriesRequests += sample.Requests
+	}
+	if seriesRequests != result.Requests {
+		t.Fatalf("series counted %d requests, want %d", seriesRequests, result.Requests)
+	}
+	raw, err := os.ReadFile(filepath.Join(outputDir, summary.OperationsFile))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var operations summary.OperationsSummary
+	if err := json.Unmarshal(raw, &operations); err != nil {
+		t.Fatal(err)
+	}
+	if len(operations.Operations) != 2 {
+		t.Fatalf("operations = %+v, want addOwner and getOwner", operations.Operations)
+	}
+	added, got := operations.Operations[0], operations.Operations[1]
+	if added.FlowID != "addOwner" || added.Requests != created.Load() || added.StatusCodes["200"] != added.Requests || added.Non2xx3xx != 0 || len(added.ErrorSamples) != 0 {
+		t.Fatalf("addOwner = %+v", added)
+	}
+	if got.FlowID != "getOwner" || got.PathTemplate != "/owners/{id}" || got.Non2xx3xx != fetched.Load() || got.Latency.TotalCount != got.Requests || len(got.Histogram) == 0 {
+		t.Fatalf("getOwner = %+v", got)
+	}
+	if len(got.ErrorSamples) != operationErrorSamples || got.ErrorSamples[0].StatusCode != http.StatusNotFound || got.ErrorSamples[0].ResponseBody != "owner not found" {
+		t.Fatalf("getOwner error samples = %+v", got.ErrorSamples)
+	}
+
+	// An interrupted stage ends long before its duration.
+	interrupt := make(chan struct{})
+	close(interrupt)
+	started := time.Now()
+	if _, err := exec.run(context.Background(), stageRun{name: "soak", load: flowgen.Load{Connections: 1, Rate: 10, Duration: time.Minute}, iterations: iterations, outputDir: t.TempDir(), interrupt: interrupt}); err != nil {
+		t.Fatalf("interrupted run: %v", err)
+	}
+	if elapsed := time.Since(started); elapsed > 5*time.Second {
+		t.Fatalf("interrupted run took %s", elapsed)
+	}
+}
+
+func TestNativeExecutor_FollowsRateSegmentsAfterTheWarmup(t *testing.T) {
+	var sent atomic.Int64
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		sent.Add(1)
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	// 25 warm-up requests, then 100 on the ramp and 100 in the spike.
+	load := flowgen.Load{Connections: 2, Warmup: 500 * time.Millisecond, Segments: []flowgen.RateSegment{
+		{Duration: time.Second, From: 50, To: 150},
+		{Duration: 500 * time.Millisecond, Rate: 200},
+	}}
+	iterations := []datagen.MinimalIteration{{Steps: []datagen.MinimalIterationStep{{FlowID: "listItems", Method: "GET", ResolvedPath: "/items"}}}}
+	outputDir := t.TempDir()
+	execution, err := (&nativeExecutor{baseURL: server.URL}).run(context.Background(), stageRun{name: "ramp", load: load, iterations: iterations, outputDir: outputDir})
+	if err != nil {
+		t.Fatalf("run: %v", err)
+	}
+	result, err := summary.ParseWrk2Output(execution.Stdout)
+	if err != nil {
+		t.Fatalf("report not parsed: %v", err)
+	}
+	if result.Requests < 190 || result.Requests > 200 {
+		t.Fatalf("measured %d requests, want about 200", result.Requests)
+	}
+	if warmup := sent.Load() - result.Requests; warmup < 20 || warmup > 30 {
+		t.Fatalf("sent %d requests in the warm-up, want about 25", warmup)
+	}
+	if result.Threads != flowgen.DefaultThreads {
+		t.Fatalf("threads = %d, want the default", result.Threads)
+	}
+}
+
+func TestNativeRecorder_ReportWithoutElapsedTime(t *testing.T) {
+	report := newNativeRecorder().report("app", 8080, 1, 1, 0, false)
+	if strings.Contains(report, "NaN") {
+		t.Fatalf("report of an interrupted stage contains NaN:\n%s", report)
+	}
+	result, err := summary.ParseWrk2Output(report)
+	if err != nil {
+		t.Fatalf("report not parsed: %v", err)
+	}
+	if result.RequestsPerSecond != 0 {
+		t.Fatalf("requests/sec = %v, want 0", result.RequestsPerSecond)
+	}
+}
+
+func TestNativeRecorder_BreaksDownByNode(t *testing.T) {
+	recorder := newNativeRecorder()
+	now := time.Now()
+	for _, node := range []string{"browse", "search", "search"} {
+		step := datagen.MinimalIterationStep{Node: node, FlowID: "listItems", Method: "GET", PathTemplate: "/items"}
+		recorder.record(step, now, time.Millisecond, time.Millisecond, 200, 10, nil, nil)
+	}
+	operations := recorder.operationsSummary("browse", time.Second).Operations
+	if len(operations) != 2 || operations[0].Node != "browse" || operations[0].Requests != 1 || operations[1].Node != "search" || operations[1].Requests != 2 {
+		t.Fatalf("operations = %+v, want one entry per node calling listItems", operations)
+	}
+}
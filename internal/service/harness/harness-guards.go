@@ -0,0 +1,213 @@
+package harness
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/d-iii-s/slsbench/internal/service/flowgen"
+	"github.com/d-iii-s/slsbench/internal/service/summary"
+	dockertypes "github.com/docker/docker/api/types/container"
+	"github.com/docker/docker/client"
+)
+
+const (
+	// executorProgressFile is rewritten by the native executor in the stage
+	// output directory with the running request totals of the stage.
+	executorProgressFile = "progress.json"
+	// guardPollInterval is how often the progress file is read.
+	guardPollInterval = time.Second
+	// guardWindow is the span the rate and non-2xx guards are computed
+	// over; neither fires before the stage has run that long.
+	guardWindow = 10 * time.Second
+	// guardMinRequests keeps a handful of failed requests in a quiet
+	// window from tripping the non-2xx guard.
+	guardMinRequests = 20
+	// cpuSaturationPercent of the online CPUs, or cpuSaturationThrottledPercent
+	// of the CFS periods throttled, counts as a saturated sample.
+	cpuSaturationPercent          = 95
+	cpuSaturationThrottledPercent = 50
+	// wrk2InterruptTimeoutSeconds is how long wrk2 gets to print its report
+	// after SIGINT before Docker kills it.
+	wrk2InterruptTimeoutSeconds = 10
+)
+
+// executorProgress is the content of executorProgressFile.
+type executorProgress struct {
+	Requests  int64 `json:"requests"`
+	Non2xx3xx int64 `json:"non2xx3xxResponses"`
+}
+
+type progressPoint struct {
+	at       time.Time
+	progress executorProgress
+}
+
+// stageGuard checks the guard rails of one running stage against the
+// service stats and the executor progress, and calls stop once when one of
+// them is broken.
+type stageGuard struct {
+	guards flowgen.Guards
+	// load gives the target rate of every window of the stage, which
+	// started at start.
+	load         flowgen.Load
+	start        time.Time
+	progressPath string
+
+	mu             sync.Mutex
+	stop           func()
+	reason         string
+	saturatedSince time.Time
+	history        []progressPoint
+}
+
+func newStageGuard(guards flowgen.Guards, load flowgen.Load, start time.Time, progressPath string) *stageGuard {
+	return &stageGuard{guards: guards, load: load, start: start, progressPath: progressPath}
+}
+
+// guardsNeedProgress reports whether a guard depends on executor progress.
+func guardsNeedProgress(guards flowgen.Guards) bool {
+	return guards.MaxNon2xxRatio > 0 || guards.MinRateRatio > 0
+}
+
+// watch polls the executor progress until ctx is done; stop is called
+// when a guard is broken.
+func (g *stageGuard) watch(ctx context.Context, stop func()) {
+	g.mu.Lock()
+	g.stop = stop
+	if g.reason != "" {
+		go stop()
+	}
+	g.mu.Unlock()
+	if !guardsNeedProgress(g.guards) {
+		return
+	}
+	ticker := time.NewTicker(guardPollInterval)
+	defer ticker.Stop()
+	reported := false
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case now := <-ticker.C:
+			progress, err := readExecutorProgress(g.progressPath)
+			if err != nil {
+				if !reported {
+					log.Printf("[harness][guards] no executor progress yet, rate and non-2xx guards wait: %v", err)
+					reported = true
+				}
+				continue
+			}
+			g.observeProgress(now, progress)
+		}
+	}
+}
+
+// observeSample feeds one stats sample; only the benchmarked service counts
+// towards CPU saturation.
+func (g *stageGuard) observeSample(sample benchmarkContainerStatsSample) {
+	if g.guards.MaxCPUSaturation <= 0 || sample.Role != summary.RoleService {
+		return
+	}
+	saturated := sample.CPUThrottledPercent >= cpuSaturationThrottledPercent ||
+		(sample.OnlineCPUs > 0 && sample.CPUPercent >= cpuSaturationPercent*float64(sample.OnlineCPUs))
+
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if !saturated {
+		g.saturatedSince = time.Time{}
+		return
+	}
+	if g.saturatedSince.IsZero() {
+		g.saturatedSince = sample.TimestampUTC
+	}
+	if held := sample.TimestampUTC.Sub(g.saturatedSince); held >= g.guards.MaxCPUSaturation {
+		g.tripLocked(fmt.Sprintf("service CPU saturated for %s (limit %s)", held.Round(time.Second), g.guards.MaxCPUSaturation))
+	}
+}
+
+// observeProgress feeds one reading of the executor totals. The guards use
+// the requests of the last guardWindow after the warm-up, which is not
+// measured.
+func (g *stageGuard) observeProgress(now time.Time, progress executorProgress) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if now.Before(g.start.Add(g.load.Warmup)) {
+		return
+	}
+	g.history = append(g.history, progressPoint{at: now, progress: progress})
+	for len(g.history) > 1 && now.Sub(g.history[1].at) >= guardWindow {
+		g.history = g.history[1:]
+	}
+	first := g.history[0]
+	span := now.Sub(first.at)
+	if span < guardWindow {
+		return
+	}
+	requests := progress.Requests - first.progress.Requests
+	if g.guards.MinRateRatio > 0 {
+		rate := float64(requests) / span.Seconds()
+		target := (g.load.RequestsBefore(now.Sub(g.start)) - g.load.RequestsBefore(first.at.Sub(g.start))) / span.Seconds()
+		if rate < g.guards.MinRateRatio*target {
+			g.tripLocked(fmt.Sprintf("achieved rate %.1f req/s below %.0f%% of the target %.1f req/s", rate, 100*g.guards.MinRateRatio, target))
+			return
+		}
+	}
+	if g.guards.MaxNon2xxRatio > 0 && requests >= guardMinRequests {
+		ratio := float64(progress.Non2xx3xx-first.progress.Non2xx3xx) / float64(requests)
+		if ratio > g.guards.MaxNon2xxRatio {
+			g.tripLocked(fmt.Sprintf("non-2xx/3xx ratio %.2f above %.2f", ratio, g.guards.MaxNon2xxRatio))
+		}
+	}
+}
+
+func (g *stageGuard) tripLocked(reason string) {
+	if g.reason != "" {
+		return
+	}
+	g.reason = reason
+	log.Printf("[harness][guards] guard rail broken: %s", reason)
+	if g.stop != nil {
+		go g.stop()
+	}
+}
+
+// tripped returns the broken guard rail, or "".
+func (g *stageGuard) tripped() string {
+	if g == nil {
+		return ""
+	}
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	return g.reason
+}
+
+// interruptWrk2Container stops a stage early. wrk2 reports what it measured
+// so far on SIGINT, so the stage summary keeps its latencies.
+func interruptWrk2Container(dockerCli *client.Client, containerID, stageName string) {
+	timeout := wrk2InterruptTimeoutSeconds
+	log.Printf("[harness][guards] stopping wrk2 container stage=%s", stageName)
+	if err := dockerCli.ContainerStop(context.Background(), containerID, dockertypes.StopOptions{Signal: "SIGINT", Timeout: &timeout}); err != nil {
+		log.Printf("[harness][guards] failed to stop wrk2 container stage=%s: %v", stageName, err)
+	}
+}
+
+func readExecutorProgress(path string) (executorProgress, error) {
+	var progress executorProgress
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return progress, err
+	}
+	if len(data) == 0 {
+		return progress, errors.New("empty progress file")
+	}
+	if err := json.Unmarshal(data, &progress); err != nil {
+		return progress, fmt.Errorf("failed to parse %s: %w", path, err)
+	}
+	return progress, nil
+}
@@ -0,0 +1,92 @@
+package harness
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/d-iii-s/slsbench/internal/service/flowgen"
+	"github.com/d-iii-s/slsbench/internal/service/summary"
+)
+
+func TestStageGuard_RateAndNon2xxUseTheLastWindow(t *testing.T) {
+	start := time.Date(2026, 5, 1, 12, 0, 0, 0, time.UTC)
+	guard := newStageGuard(flowgen.Guards{MaxNon2xxRatio: 0.5, MinRateRatio: 0.8}, flowgen.Load{Rate: 100, Duration: time.Minute}, start, "")
+
+	// 100 req/s, all successful, for the first window.
+	for i := 0; i <= 10; i++ {
+		guard.observeProgress(start.Add(time.Duration(i)*time.Second), executorProgress{Requests: int64(100 * i)})
+	}
+	if reason := guard.tripped(); reason != "" {
+		t.Fatalf("healthy stage tripped: %s", reason)
+	}
+	// Then every request fails; the early successes must not dilute it.
+	for i := 11; i <= 20; i++ {
+		guard.observeProgress(start.Add(time.Duration(i)*time.Second), executorProgress{Requests: int64(100 * i), Non2xx3xx: int64(100 * (i - 10))})
+	}
+	if reason := guard.tripped(); !strings.Contains(reason, "non-2xx") {
+		t.Fatalf("reason = %q, want the non-2xx guard", reason)
+	}
+
+	slow := newStageGuard(flowgen.Guards{MinRateRatio: 0.8}, flowgen.Load{Rate: 100, Duration: time.Minute}, start, "")
+	for i := 0; i <= 10; i++ {
+		slow.observeProgress(start.Add(time.Duration(i)*time.Second), executorProgress{Requests: int64(50 * i)})
+	}
+	if reason := slow.tripped(); !strings.Contains(reason, "achieved rate 50.0") {
+		t.Fatalf("reason = %q, want the rate guard", reason)
+	}
+}
+
+func TestStageGuard_RateFollowsTheLoadAfterTheWarmup(t *testing.T) {
+	start := time.Date(2026, 5, 1, 12, 0, 0, 0, time.UTC)
+	// 5s of unmeasured warm-up, then 100 req/s rising by 10 req/s every second.
+	load := flowgen.Load{Warmup: 5 * time.Second, Segments: []flowgen.RateSegment{{Duration: 20 * time.Second, From: 100, To: 300}}}
+	measured := func(i int) int64 {
+		u := max(i-5, 0)
+		return int64(100*u + 5*u*u)
+	}
+
+	guard := newStageGuard(flowgen.Guards{MinRateRatio: 0.8}, load, start, "")
+	for i := 0; i <= 25; i++ {
+		guard.observeProgress(start.Add(time.Duration(i)*time.Second), executorProgress{Requests: measured(i)})
+	}
+	if reason := guard.tripped(); reason != "" {
+		t.Fatalf("stage on the ramp tripped: %s", reason)
+	}
+
+	flat := newStageGuard(flowgen.Guards{MinRateRatio: 0.8}, load, start, "")
+	for i := 0; i <= 15; i++ {
+		flat.observeProgress(start.Add(time.Duration(i)*time.Second), executorProgress{Requests: int64(90 * max(i-5, 0))})
+	}
+	if reason := flat.tripped(); !strings.Contains(reason, "achieved rate 90.0 req/s below 80% of the target 150.0") {
+		t.Fatalf("reason = %q, want the rate guard against the ramp", reason)
+	}
+}
+
+func TestStageGuard_CPUSaturationMustBeContinuous(t *testing.T) {
+	start := time.Date(2026, 5, 1, 12, 0, 0, 0, time.UTC)
+	stopped := make(chan struct{})
+	guard := newStageGuard(flowgen.Guards{MaxCPUSaturation: 5 * time.Second}, flowgen.Load{}, start, "")
+	guard.watch(t.Context(), func() { close(stopped) })
+
+	sample := func(offset time.Duration, cpu float64, role string) benchmarkContainerStatsSample {
+		return benchmarkContainerStatsSample{TimestampUTC: start.Add(offset), Role: role, OnlineCPUs: 2, CPUPercent: cpu}
+	}
+	guard.observeSample(sample(0, 199, summary.RoleService))
+	guard.observeSample(sample(3*time.Second, 198, summary.RoleService))
+	guard.observeSample(sample(4*time.Second, 40, summary.RoleService))
+	guard.observeSample(sample(5*time.Second, 199, summary.RoleService))
+	guard.observeSample(sample(20*time.Second, 200, summary.RoleLoadGenerator))
+	if reason := guard.tripped(); reason != "" {
+		t.Fatalf("interrupted saturation tripped: %s", reason)
+	}
+	guard.observeSample(sample(10*time.Second, 199, summary.RoleService))
+	if reason := guard.tripped(); !strings.Contains(reason, "CPU saturated for 5s") {
+		t.Fatalf("reason = %q, want the CPU guard", reason)
+	}
+	select {
+	case <-stopped:
+	case <-time.After(time.Second):
+		t.Fatal("stage was not stopped")
+	}
+}
@@ -0,0 +1,180 @@
+package harness
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	dockertypes "github.com/docker/docker/api/types/container"
+	"github.com/docker/docker/client"
+	"github.com/docker/docker/pkg/stdcopy"
+)
+
+const (
+	// serviceLogsDir holds one log file per compose container.
+	serviceLogsDir = "service-logs"
+	// serviceLogsRetryInterval is how long a stream that ended waits before
+	// following the container again, e.g. after the activator restarted it.
+	serviceLogsRetryInterval = time.Second
+)
+
+// serviceLogs follows the stdout and stderr of every compose container into
+// <runDir>/service-logs/<service>.log, each line prefixed with Docker's
+// RFC 3339 timestamp. Files are written as the lines arrive, so a run that
+// dies midway still leaves them behind.
+type serviceLogs struct {
+	dockerCli *client.Client
+	dir       string
+	cancel    context.CancelFunc
+	wg        sync.WaitGroup
+}
+
+func newServiceLogs(dockerCli *client.Client, runDir string) *serviceLogs {
+	return &serviceLogs{dockerCli: dockerCli, dir: filepath.Join(runDir, serviceLogsDir)}
+}
+
+// start follows every container of the project from its first line.
+func (s *serviceLogs) start(ctx context.Context, projectName string) error {
+	containers, err := listComposeContainers(ctx, s.dockerCli, projectName, false)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(s.dir, 0o755); err != nil {
+		return fmt.Errorf("failed to create service logs directory: %w", err)
+	}
+	// Detached from ctx so teardown output is still captured after Run
+	// returns an error; stop ends the streams.
+	followCtx, cancel := context.WithCancel(context.Background())
+	s.cancel = cancel
+
+	names := logFileNames(containers)
+	for i, c := range containers {
+		file, err := os.Create(filepath.Join(s.dir, names[i]))
+		if err != nil {
+			cancel()
+			return fmt.Errorf("failed to create service log for %s: %w", c.Service, err)
+		}
+		log.Printf("[harness][logs] follow begin container=%s service=%s", c.Name, c.Service)
+		s.wg.Add(1)
+		go func() {
+			defer s.wg.Done()
+			defer file.Close()
+			if err := s.follow(followCtx, c.ID, file); err != nil {
+				log.Printf("[harness][logs] follow failed container=%s error=%v", c.Name, err)
+			}
+		}()
+	}
+	return nil
+}
+
+// dump writes what every container of the project, running or not, logged
+// so far. It is used when the project fails to start, before any container
+// is followed.
+func (s *serviceLogs) dump(ctx context.Context, projectName string) error {
+	containers, err := listComposeContainers(ctx, s.dockerCli, projectName, true)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(s.dir, 0o755); err != nil {
+		return fmt.Errorf("failed to create service logs directory: %w", err)
+	}
+	names := logFileNames(containers)
+	for i, c := range containers {
+		file, err := os.Create(filepath.Join(s.dir, names[i]))
+		if err != nil {
+			return fmt.Errorf("failed to create service log for %s: %w", c.Service, err)
+		}
+		err = s.copyLogs(ctx, c.ID, file, false, "")
+		file.Close()
+		if err != nil && !client.IsErrNotFound(err) {
+			return fmt.Errorf("failed to read logs of %s: %w", c.Name, err)
+		}
+		log.Printf("[harness][logs] dumped container=%s service=%s", c.Name, c.Service)
+	}
+	return nil
+}
+
+// logFileNames returns the log file of every container: <service>.log, with
+// a -2, -3, ... suffix for replicas.
+func logFileNames(containers []composeContainer) []string {
+	names := make([]string, len(containers))
+	used := map[string]int{}
+	for i, c := range containers {
+		base := sanitizePathPart(c.Service)
+		used[base]++
+		names[i] = base + ".log"
+		if used[base] > 1 {
+			names[i] = fmt.Sprintf("%s-%d.log", base, used[base])
+		}
+	}
+	return names
+}
+
+// follow copies the container output into w until ctx is cancelled or the
+// container is gone. A stream that ends because the container stopped is
+// reopened from where it ended.
+func (s *serviceLogs) follow(ctx context.Context, containerID string, w io.Writer) error {
+	since := ""
+	for {
+		err := s.copyLogs(ctx, containerID, w, true, since)
+		ended := time.Now()
+		if ctx.Err() != nil || client.IsErrNotFound(err) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		since = fmt.Sprintf("%d.%09d", ended.Unix(), ended.Nanosecond())
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-time.After(serviceLogsRetryInterval):
+		}
+	}
+}
+
+// copyLogs copies the container output since since into w, until the
+// container stops when follow is set.
+func (s *serviceLogs) copyLogs(ctx context.Context, containerID string, w io.Writer, follow bool, since string) error {
+	inspect, err := s.dockerCli.ContainerInspect(ctx, containerID)
+	if err != nil {
+		return err
+	}
+	reader, err := s.dockerCli.ContainerLogs(ctx, containerID, dockertypes.LogsOptions{
+		ShowStdout: true,
+		ShowStderr: true,
+		Follow:     follow,
+		Timestamps: true,
+		Since:      since,
+	})
+	if err != nil {
+		return err
+	}
+	defer reader.Close()
+	if inspect.Config != nil && inspect.Config.Tty {
+		_, err = io.Copy(w, reader)
+	} else {
+		_, err = stdcopy.StdCopy(w, w, reader)
+	}
+	if errors.Is(err, io.EOF) {
+		return nil
+	}
+	return err
+}
+
+// stop ends all streams and waits until their files are closed. It is safe
+// to call before start.
+func (s *serviceLogs) stop() {
+	if s.cancel == nil {
+		return
+	}
+	s.cancel()
+	s.wg.Wait()
+	log.Printf("[harness][logs] follow done dir=%s", s.dir)
+}
@@ -0,0 +1,102 @@
+package harness
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	"github.com/docker/docker/client"
+	"github.com/docker/docker/pkg/stdcopy"
+)
+
+func TestServiceLogs_FollowCopiesBothStreamsUntilContainerIsGone(t *testing.T) {
+	var logCalls atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case strings.HasSuffix(r.URL.Path, "/containers/app/json"):
+			// The container disappears once its log stream has ended.
+			if logCalls.Load() > 0 {
+				http.Error(w, `{"message":"No such container: app"}`, http.StatusNotFound)
+				return
+			}
+			w.Header().Set("Content-Type", "application/json")
+			_, _ = w.Write([]byte(`{"Id":"app","Config":{"Tty":false}}`))
+		case strings.HasSuffix(r.URL.Path, "/containers/app/logs"):
+			logCalls.Add(1)
+			if r.URL.Query().Get("timestamps") != "1" || r.URL.Query().Get("follow") != "1" {
+				t.Errorf("unexpected log query: %s", r.URL.RawQuery)
+			}
+			_, _ = stdcopy.NewStdWriter(w, stdcopy.Stdout).Write([]byte("2026-05-01T12:00:00Z started\n"))
+			_, _ = stdcopy.NewStdWriter(w, stdcopy.Stderr).Write([]byte("2026-05-01T12:00:01Z boom\n"))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+
+	cli, err := client.NewClientWithOpts(client.WithHost("tcp://"+strings.TrimPrefix(server.URL, "http://")), client.WithVersion("1.47"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer cli.Close()
+
+	var out bytes.Buffer
+	logs := newServiceLogs(cli, t.TempDir())
+	if err := logs.follow(context.Background(), "app", &out); err != nil {
+		t.Fatalf("follow: %v", err)
+	}
+	want := "2026-05-01T12:00:00Z started\n2026-05-01T12:00:01Z boom\n"
+	if out.String() != want {
+		t.Fatalf("log output = %q, want %q", out.String(), want)
+	}
+	logs.stop()
+}
+
+func TestServiceLogs_DumpWritesStoppedContainers(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case strings.HasSuffix(r.URL.Path, "/containers/json"):
+			// The service crashed on start, so only a list of all containers has it.
+			if r.URL.Query().Get("all") != "1" {
+				t.Errorf("unexpected list query: %s", r.URL.RawQuery)
+			}
+			w.Header().Set("Content-Type", "application/json")
+			_, _ = w.Write([]byte(`[{"Id":"app","Names":["/harness-1-app-1"],"Labels":{"com.docker.compose.service":"app"}}]`))
+		case strings.HasSuffix(r.URL.Path, "/containers/app/json"):
+			w.Header().Set("Content-Type", "application/json")
+			_, _ = w.Write([]byte(`{"Id":"app","Config":{"Tty":false}}`))
+		case strings.HasSuffix(r.URL.Path, "/containers/app/logs"):
+			if r.URL.Query().Get("follow") == "1" {
+				t.Errorf("a dump must not follow: %s", r.URL.RawQuery)
+			}
+			_, _ = stdcopy.NewStdWriter(w, stdcopy.Stderr).Write([]byte("2026-05-01T12:00:00Z missing DATABASE_URL\n"))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+
+	cli, err := client.NewClientWithOpts(client.WithHost("tcp://"+strings.TrimPrefix(server.URL, "http://")), client.WithVersion("1.47"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer cli.Close()
+
+	runDir := t.TempDir()
+	if err := newServiceLogs(cli, runDir).dump(context.Background(), "harness-1"); err != nil {
+		t.Fatalf("dump: %v", err)
+	}
+	raw, err := os.ReadFile(filepath.Join(runDir, serviceLogsDir, "app.log"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "2026-05-01T12:00:00Z missing DATABASE_URL\n"; string(raw) != want {
+		t.Fatalf("log = %q, want %q", raw, want)
+	}
+}
@@ -0,0 +1,192 @@
+package harness
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/d-iii-s/slsbench/internal/service/runstats"
+	"github.com/d-iii-s/slsbench/internal/service/summary"
+	"github.com/d-iii-s/slsbench/internal/utils"
+)
+
+// DefaultVariant names the only variant of a repeated run without explicit
+// variants.
+const DefaultVariant = "default"
+
+// Variant is one configuration under test in a repeated run.
+type Variant struct {
+	Name              string
+	DockerComposePath string
+}
+
+// RepeatOptions configures a repeated harness run. Every repetition is a full
+// Run with its own compose project, so Options.Session must be nil.
+type RepeatOptions struct {
+	Options
+	Repetitions int
+	// Variants are benchmarked with the same flow; when empty, a single
+	// DefaultVariant uses Options.DockerComposePath.
+	Variants []Variant
+	// Interleave runs repetition i of every variant before repetition i+1
+	// of any, so drift of the host affects all variants alike.
+	Interleave bool
+	Bootstrap  runstats.Bootstrap
+}
+
+// runHarness is replaced in tests.
+var runHarness = Run
+
+// ParseVariant parses a "<name>=<docker-compose-path>" flag value.
+func ParseVariant(text string) (Variant, error) {
+	name, path, ok := strings.Cut(text, "=")
+	name, path = strings.TrimSpace(name), strings.TrimSpace(path)
+	if !ok || name == "" || path == "" {
+		return Variant{}, fmt.Errorf("invalid variant %q: expected <name>=<docker-compose-path>", text)
+	}
+	return Variant{Name: name, DockerComposePath: path}, nil
+}
+
+func (o RepeatOptions) variants() ([]Variant, error) {
+	if o.Repetitions <= 0 {
+		return nil, fmt.Errorf("repetitions must be positive, got %d", o.Repetitions)
+	}
+	if o.Session != nil {
+		return nil, fmt.Errorf("repeated runs create their own compose projects and cannot use a session")
+	}
+	if len(o.Variants) == 0 {
+		return []Variant{{Name: DefaultVariant, DockerComposePath: o.DockerComposePath}}, nil
+	}
+	seen := map[string]string{}
+	for _, variant := range o.Variants {
+		dir := sanitizePathPart(variant.Name)
+		if other, ok := seen[dir]; ok {
+			return nil, fmt.Errorf("variants %q and %q share the result directory %q", other, variant.Name, dir)
+		}
+		seen[dir] = variant.Name
+	}
+	return o.Variants, nil
+}
+
+// RunRepeated runs the harness Repetitions times per variant, tearing the
+// compose project down and recreating it between repetitions. Results go to
+// <result-path>/repeat-result-<ts>/<variant>/rep-NNN/, and aggregate.json in
+// the root summarizes the completed repetitions with bootstrap confidence
+// intervals. A failed repetition does not stop the others but makes
+// RunRepeated return an error.
+func RunRepeated(ctx context.Context, opts RepeatOptions) error {
+	variants, err := opts.variants()
+	if err != nil {
+		return err
+	}
+	rootDir, err := utils.CreateResultSubdirWithPrefix(opts.ResultPath, "repeat-result")
+	if err != nil {
+		return fmt.Errorf("failed to create result directory: %w", err)
+	}
+	log.Printf("[harness][repeat] output directory: %s variants=%d repetitions=%d interleave=%t", rootDir, len(variants), opts.Repetitions, opts.Interleave)
+
+	aggregate := runstats.AggregateSummary{
+		SchemaVersion: runstats.AggregateSchema,
+		StartedAt:     time.Now().UTC(),
+		Repetitions:   opts.Repetitions,
+		Interleaved:   opts.Interleave,
+		Bootstrap:     opts.Bootstrap,
+		Schedule:      []runstats.Repetition{},
+		Variants:      []runstats.VariantAggregate{},
+	}
+	if aggregate.Bootstrap.Resamples <= 0 {
+		aggregate.Bootstrap.Resamples = runstats.DefaultResamples
+	}
+	if aggregate.Bootstrap.Confidence <= 0 || aggregate.Bootstrap.Confidence >= 1 {
+		aggregate.Bootstrap.Confidence = runstats.DefaultConfidence
+	}
+
+	failed := 0
+	for _, step := range repetitionSchedule(variants, opts.Repetitions, opts.Interleave) {
+		if ctx.Err() != nil {
+			break
+		}
+		variant := variants[step.variant]
+		repDir := filepath.Join(rootDir, sanitizePathPart(variant.Name), fmt.Sprintf("rep-%03d", step.index))
+		record := runstats.Repetition{Variant: variant.Name, Index: step.index, Dir: repDir, StartedAt: time.Now().UTC()}
+		log.Printf("[harness][repeat] begin variant=%s repetition=%d/%d", variant.Name, step.index, opts.Repetitions)
+
+		runOpts := opts.Options
+		runOpts.ResultPath = repDir
+		runOpts.DockerComposePath = variant.DockerComposePath
+		runErr := runHarness(ctx, runOpts)
+		record.FinishedAt = time.Now().UTC()
+		if harnessDir, err := utils.LatestResultSubdir(repDir, "harness-result"); err == nil {
+			record.Dir = harnessDir
+		}
+		record.Status = summary.StatusCompleted
+		if runErr != nil {
+			failed++
+			record.Status = summary.StatusFailed
+			record.Error = runErr.Error()
+			log.Printf("[harness][repeat] failed variant=%s repetition=%d error=%v", variant.Name, step.index, runErr)
+		} else {
+			log.Printf("[harness][repeat] done variant=%s repetition=%d elapsed=%s", variant.Name, step.index, record.FinishedAt.Sub(record.StartedAt))
+		}
+		aggregate.Schedule = append(aggregate.Schedule, record)
+	}
+
+	for _, variant := range variants {
+		var dirs []string
+		for _, record := range aggregate.Schedule {
+			if record.Variant == variant.Name && record.Status == summary.StatusCompleted {
+				dirs = append(dirs, record.Dir)
+			}
+		}
+		var runs []*runstats.Run
+		if len(dirs) > 0 {
+			runs, err = runstats.LoadRuns(dirs...)
+			if err != nil {
+				return fmt.Errorf("failed to load repetitions of variant %q: %w", variant.Name, err)
+			}
+		}
+		aggregate.Variants = append(aggregate.Variants, aggregate.Bootstrap.Aggregate(variant.Name, runs))
+	}
+	aggregate.FinishedAt = time.Now().UTC()
+	aggregatePath := filepath.Join(rootDir, runstats.AggregateFile)
+	if err := summary.WriteJSON(aggregatePath, aggregate); err != nil {
+		return fmt.Errorf("failed to write aggregate: %w", err)
+	}
+	log.Printf("[harness][repeat] aggregate written to %s", aggregatePath)
+
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	if failed > 0 {
+		return fmt.Errorf("%d of %d repetitions failed; see %s", failed, len(aggregate.Schedule), aggregatePath)
+	}
+	return nil
+}
+
+type repetitionStep struct {
+	variant int
+	index   int
+}
+
+// repetitionSchedule orders the repetitions variant by variant, or round by
+// round when interleaved. Indexes start at 1.
+func repetitionSchedule(variants []Variant, repetitions int, interleave bool) []repetitionStep {
+	steps := make([]repetitionStep, 0, len(variants)*repetitions)
+	if interleave {
+		for index := 1; index <= repetitions; index++ {
+			for variant := range variants {
+				steps = append(steps, repetitionStep{variant, index})
+			}
+		}
+		return steps
+	}
+	for variant := range variants {
+		for index := 1; index <= repetitions; index++ {
+			steps = append(steps, repetitionStep{variant, index})
+		}
+	}
+	return steps
+}
@@ -0,0 +1,107 @@
+package harness
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/d-iii-s/slsbench/internal/service/runstats"
+	"github.com/d-iii-s/slsbench/internal/service/summary"
+)
+
+func TestRepetitionSchedule_Interleaves(t *testing.T) {
+	variants := []Variant{{Name: "a"}, {Name: "b"}}
+	format := func(steps []repetitionStep) string {
+		out := ""
+		for _, step := range steps {
+			out += fmt.Sprintf("%s%d ", variants[step.variant].Name, step.index)
+		}
+		return out
+	}
+	if got := format(repetitionSchedule(variants, 2, true)); got != "a1 b1 a2 b2 " {
+		t.Fatalf("unexpected interleaved schedule %q", got)
+	}
+	if got := format(repetitionSchedule(variants, 2, false)); got != "a1 a2 b1 b2 " {
+		t.Fatalf("unexpected sequential schedule %q", got)
+	}
+}
+
+func TestParseVariant(t *testing.T) {
+	variant, err := ParseVariant("jit = compose/jit.yaml")
+	if err != nil || variant != (Variant{Name: "jit", DockerComposePath: "compose/jit.yaml"}) {
+		t.Fatalf("ParseVariant = %+v, %v", variant, err)
+	}
+	for _, bad := range []string{"jit", "=compose.yaml", "jit="} {
+		if _, err := ParseVariant(bad); err == nil {
+			t.Errorf("expected an error for %q", bad)
+		}
+	}
+}
+
+func TestRunRepeated_WritesAggregate(t *testing.T) {
+	var calls []string
+	runHarness = func(_ context.Context, opts Options) error {
+		calls = append(calls, opts.DockerComposePath)
+		dir := filepath.Join(opts.ResultPath, "harness-result-x")
+		if err := os.MkdirAll(dir, 0o755); err != nil {
+			return err
+		}
+		run := summary.RunSummary{
+			SchemaVersion: summary.RunSummarySchema,
+			Status:        summary.StatusCompleted,
+			FirstResponse: &summary.FirstResponse{DurationMillis: int64(100 * len(calls))},
+		}
+		if err := summary.WriteJSON(filepath.Join(dir, summary.RunSummaryFile), run); err != nil {
+			return err
+		}
+		if len(calls) == 4 {
+			return fmt.Errorf("wrk2 failed")
+		}
+		return nil
+	}
+	t.Cleanup(func() { runHarness = Run })
+
+	root := t.TempDir()
+	err := RunRepeated(context.Background(), RepeatOptions{
+		Options:     Options{ResultPath: root},
+		Repetitions: 2,
+		Variants:    []Variant{{Name: "a", DockerComposePath: "a.yaml"}, {Name: "b", DockerComposePath: "b.yaml"}},
+		Interleave:  true,
+		Bootstrap:   runstats.Bootstrap{Resamples: 100},
+	})
+	if err == nil {
+		t.Fatalf("expected an error for the failed repetition")
+	}
+	if fmt.Sprint(calls) != "[a.yaml b.yaml a.yaml b.yaml]" {
+		t.Fatalf("unexpected run order %v", calls)
+	}
+
+	matches, _ := filepath.Glob(filepath.Join(root, "repeat-result-*", runstats.AggregateFile))
+	if len(matches) != 1 {
+		t.Fatalf("expected one aggregate, found %v", matches)
+	}
+	raw, err := os.ReadFile(matches[0])
+	if err != nil {
+		t.Fatalf("failed to read aggregate: %v", err)
+	}
+	var aggregate runstats.AggregateSummary
+	if err := json.Unmarshal(raw, &aggregate); err != nil {
+		t.Fatalf("failed to decode aggregate: %v", err)
+	}
+	if len(aggregate.Schedule) != 4 || aggregate.Schedule[3].Status != summary.StatusFailed {
+		t.Fatalf("unexpected schedule: %+v", aggregate.Schedule)
+	}
+	if filepath.Base(filepath.Dir(aggregate.Schedule[1].Dir)) != "rep-001" {
+		t.Fatalf("unexpected repetition directory %q", aggregate.Schedule[1].Dir)
+	}
+	a, b := aggregate.Variants[0], aggregate.Variants[1]
+	if a.RunLevel["first-response"].N != 2 || a.RunLevel["first-response"].Mean != 200 {
+		t.Fatalf("unexpected aggregate for a: %+v", a.RunLevel)
+	}
+	if b.RunLevel["first-response"].N != 1 {
+		t.Fatalf("the failed repetition of b should be left out: %+v", b.RunLevel)
+	}
+}
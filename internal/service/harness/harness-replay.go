@@ -0,0 +1,247 @@
+package harness
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/d-iii-s/slsbench/internal/service/datagen"
+)
+
+// flowReplayer sends the steps of probe-bodies iterations one at a time,
+// resolving "<flowId>.<part>#/<pointer>" references against the steps already
+// sent in the same iteration, the way the wrk2-flow executor does.
+type flowReplayer struct {
+	client      *http.Client
+	baseURL     string
+	apiBasePath string
+	// sent holds the exchanges of the current iteration by flow id.
+	sent map[string]replayedStep
+	// received counts the response body bytes read so far.
+	received int64
+	// lastBody is the response body of the last request.
+	lastBody []byte
+}
+
+type replayedStep struct {
+	endpoint     string
+	headers      map[string]any
+	query        map[string]any
+	requestBody  any
+	responseBody any
+}
+
+func newFlowReplayer(baseURL, apiBasePath string) *flowReplayer {
+	return &flowReplayer{
+		client:      &http.Client{Timeout: 30 * time.Second},
+		baseURL:     strings.TrimSuffix(baseURL, "/"),
+		apiBasePath: apiBasePath,
+		sent:        map[string]replayedStep{},
+	}
+}
+
+// flowSteps returns the first k steps of iterations in order. The boolean
+// marks steps that begin a new iteration.
+func flowSteps(iterations []datagen.MinimalIteration, k int) ([]datagen.MinimalIterationStep, []bool) {
+	var steps []datagen.MinimalIterationStep
+	var starts []bool
+	for _, iteration := range iterations {
+		for i, step := range iteration.Steps {
+			if len(steps) == k {
+				return steps, starts
+			}
+			steps = append(steps, step)
+			starts = append(starts, i == 0)
+		}
+	}
+	return steps, starts
+}
+
+// reset forgets the exchanges of the previous iteration.
+func (r *flowReplayer) reset() {
+	r.sent = map[string]replayedStep{}
+}
+
+// send replays one step and returns its status code and latency. Latency
+// covers the request until the response body was read.
+func (r *flowReplayer) send(ctx context.Context, step datagen.MinimalIterationStep) (string, int, time.Duration, error) {
+	pathParams, _ := r.resolve(step.PathParams).(map[string]any)
+	headers, _ := r.resolve(step.Headers).(map[string]any)
+	query, _ := r.resolve(step.Query).(map[string]any)
+	body := r.resolve(step.RequestBody)
+
+	path := step.ResolvedPath
+	if strings.TrimSpace(step.PathTemplate) != "" {
+		path = step.PathTemplate
+		for name, value := range pathParams {
+			path = strings.ReplaceAll(path, "{"+name+"}", url.PathEscape(scalarString(value)))
+		}
+	}
+	path = readinessPath(r.apiBasePath, path)
+	target := r.baseURL + path
+	if len(query) > 0 {
+		values := url.Values{}
+		for name, value := range query {
+			values.Set(name, scalarString(value))
+		}
+		target += "?" + values.Encode()
+	}
+
+	var reader io.Reader
+	if body != nil {
+		raw, err := json.Marshal(body)
+		if err != nil {
+			return path, 0, 0, fmt.Errorf("failed to encode request body: %w", err)
+		}
+		reader = bytes.NewReader(raw)
+	}
+	method := strings.ToUpper(strings.TrimSpace(step.Method))
+	if method == "" {
+		method = http.MethodGet
+	}
+	req, err := http.NewRequestWithContext(ctx, method, target, reader)
+	if err != nil {
+		return path, 0, 0, err
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	for name, value := range headers {
+		req.Header.Set(name, scalarString(value))
+	}
+
+	startedAt := time.Now()
+	resp, err := r.client.Do(req)
+	r.lastBody = nil
+	if err != nil {
+		return path, 0, time.Since(startedAt), err
+	}
+	raw, err := io.ReadAll(resp.Body)
+	latency := time.Since(startedAt)
+	_ = resp.Body.Close()
+	r.received += int64(len(raw))
+	r.lastBody = raw
+	if err != nil {
+		return path, resp.StatusCode, latency, fmt.Errorf("failed to read response body: %w", err)
+	}
+
+	exchange := replayedStep{endpoint: path, headers: headers, query: query, requestBody: body}
+	if len(raw) > 0 {
+		_ = json.Unmarshal(raw, &exchange.responseBody)
+	}
+	if step.FlowID != "" {
+		r.sent[step.FlowID] = exchange
+	}
+	return path, resp.StatusCode, latency, nil
+}
+
+// resolve replaces references in value. Unresolvable references are sent as
+// they are, which the service will usually reject.
+func (r *flowReplayer) resolve(value any) any {
+	switch v := value.(type) {
+	case map[string]any:
+		out := make(map[string]any, len(v))
+		for key, nested := range v {
+			out[key] = r.resolve(nested)
+		}
+		return out
+	case []any:
+		out := make([]any, len(v))
+		for i, nested := range v {
+			out[i] = r.resolve(nested)
+		}
+		return out
+	case string:
+		if resolved, ok := r.lookup(v); ok {
+			return resolved
+		}
+		return v
+	default:
+		return value
+	}
+}
+
+func (r *flowReplayer) lookup(ref string) (any, bool) {
+	head, pointer, ok := strings.Cut(ref, "#")
+	if !ok {
+		return nil, false
+	}
+	dot := strings.LastIndex(head, ".")
+	if dot <= 0 {
+		return nil, false
+	}
+	exchange, ok := r.sent[head[:dot]]
+	if !ok {
+		return nil, false
+	}
+	var doc any
+	switch head[dot+1:] {
+	case "endpoint":
+		doc = exchange.endpoint
+	case "headers":
+		doc = exchange.headers
+	case "query":
+		doc = exchange.query
+	case "requestBody":
+		doc = exchange.requestBody
+	case "responseBody":
+		doc = exchange.responseBody
+	default:
+		return nil, false
+	}
+	return jsonPointer(doc, pointer)
+}
+
+// jsonPointer evaluates an RFC 6901 pointer.
+func jsonPointer(doc any, pointer string) (any, bool) {
+	if pointer == "" {
+		return doc, true
+	}
+	if !strings.HasPrefix(pointer, "/") {
+		return nil, false
+	}
+	for _, token := range strings.Split(pointer[1:], "/") {
+		token = strings.ReplaceAll(strings.ReplaceAll(token, "~1", "/"), "~0", "~")
+		switch node := doc.(type) {
+		case map[string]any:
+			next, ok := node[token]
+			if !ok {
+				return nil, false
+			}
+			doc = next
+		case []any:
+			index, err := strconv.Atoi(token)
+			if err != nil || index < 0 || index >= len(node) {
+				return nil, false
+			}
+			doc = node[index]
+		default:
+			return nil, false
+		}
+	}
+	return doc, true
+}
+
+func scalarString(value any) string {
+	switch v := value.(type) {
+	case string:
+		return v
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	case nil:
+		return ""
+	default:
+		raw, err := json.Marshal(v)
+		if err != nil {
+			return fmt.Sprint(v)
+		}
+		return string(raw)
+	}
+}
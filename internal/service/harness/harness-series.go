@@ -0,0 +1,164 @@
+package harness
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/d-iii-s/slsbench/internal/service/summary"
+)
+
+// DefaultSeriesInterval is the stage time series interval used when
+// Options.SeriesInterval is zero. Only the native executor, which times
+// every request, writes a series.
+const DefaultSeriesInterval = time.Second
+
+// seriesRecorder writes the time series of one stage to summary.SeriesFile.
+// Requests count towards the interval they complete in.
+type seriesRecorder struct {
+	path     string
+	stage    string
+	interval time.Duration
+	file     *os.File
+
+	mu           sync.Mutex
+	started      time.Time
+	current      time.Time
+	requests     int64
+	non2xx3xx    int64
+	socketErrors int64
+	latency      summary.LatencyHistogram
+}
+
+// newSeriesRecorder starts the series of stage now.
+func newSeriesRecorder(path, stage string, interval time.Duration) (*seriesRecorder, error) {
+	if interval <= 0 {
+		interval = DefaultSeriesInterval
+	}
+	file, err := os.Create(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create series for stage=%s: %w", stage, err)
+	}
+	now := time.Now().UTC()
+	return &seriesRecorder{
+		path:     path,
+		stage:    stage,
+		interval: interval,
+		file:     file,
+		started:  now,
+		current:  now.Truncate(interval),
+	}, nil
+}
+
+// record adds a request that completed just now. latency is only recorded
+// for requests that got a response.
+func (s *seriesRecorder) record(status int, latency time.Duration, err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.rollLocked(time.Now().UTC())
+	s.requests++
+	if err != nil {
+		s.socketErrors++
+		return
+	}
+	if status < 200 || status > 399 {
+		s.non2xx3xx++
+	}
+	s.latency.Record(latency)
+}
+
+// start writes every finished interval until the returned function is
+// called, which writes the last, partial interval and closes the file.
+func (s *seriesRecorder) start() (stop func()) {
+	done := make(chan struct{})
+	stopped := make(chan struct{})
+	go func() {
+		defer close(stopped)
+		ticker := time.NewTicker(s.interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-done:
+				return
+			case <-ticker.C:
+				s.roll()
+			}
+		}
+	}()
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			close(done)
+			<-stopped
+			s.roll()
+			s.close()
+		})
+	}
+}
+
+func (s *seriesRecorder) roll() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.rollLocked(time.Now().UTC())
+}
+
+func (s *seriesRecorder) close() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	now := time.Now().UTC()
+	s.rollLocked(now)
+	if now.After(s.current) {
+		s.writeLocked(now)
+	}
+	if err := s.file.Close(); err != nil {
+		log.Printf("[harness][series] failed to close series stage=%s: %v", s.stage, err)
+	}
+}
+
+// rollLocked writes the intervals that ended by now.
+func (s *seriesRecorder) rollLocked(now time.Time) {
+	for end := s.current.Add(s.interval); !now.Before(end); end = s.current.Add(s.interval) {
+		s.writeLocked(end)
+		s.current = end
+	}
+}
+
+// writeLocked writes the open interval up to end and resets the counts.
+func (s *seriesRecorder) writeLocked(end time.Time) {
+	from := s.current
+	if from.Before(s.started) {
+		from = s.started
+	}
+	sample := summary.SeriesSample{
+		TimestampUTC:    from,
+		Stage:           s.stage,
+		DurationSeconds: end.Sub(from).Seconds(),
+		Requests:        s.requests,
+		Non2xx3xx:       s.non2xx3xx,
+		SocketErrors:    s.socketErrors,
+	}
+	if sample.DurationSeconds > 0 {
+		sample.RequestsPerSecond = float64(s.requests) / sample.DurationSeconds
+	}
+	if s.latency.Count() > 0 {
+		sample.Latency = &summary.SeriesLatency{
+			P50Millis: s.latency.Percentile(50),
+			P90Millis: s.latency.Percentile(90),
+			P99Millis: s.latency.Percentile(99),
+			MaxMillis: s.latency.Percentile(100),
+		}
+		sample.Histogram = s.latency.Buckets()
+	}
+	s.requests, s.non2xx3xx, s.socketErrors = 0, 0, 0
+	s.latency = summary.LatencyHistogram{}
+	raw, err := json.Marshal(sample)
+	if err != nil {
+		return
+	}
+	if _, err := s.file.Write(append(raw, '\n')); err != nil {
+		log.Printf("[harness][series] failed to write series stage=%s: %v", s.stage, err)
+	}
+}
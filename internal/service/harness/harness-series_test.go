@@ -0,0 +1,75 @@
+package harness
+
+import (
+	"errors"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/d-iii-s/slsbench/internal/service/summary"
+)
+
+func TestSeriesRecorder_WritesWallClockIntervals(t *testing.T) {
+	dir := t.TempDir()
+	interval := 50 * time.Millisecond
+	series, err := newSeriesRecorder(filepath.Join(dir, summary.SeriesFile), "warmup", interval)
+	if err != nil {
+		t.Fatal(err)
+	}
+	stop := series.start()
+	for range 3 {
+		series.record(http.StatusOK, 2*time.Millisecond, nil)
+		series.record(http.StatusInternalServerError, 40*time.Millisecond, nil)
+		series.record(0, 0, errors.New("connection refused"))
+		time.Sleep(60 * time.Millisecond)
+	}
+	stop()
+	stop()
+
+	samples := readSeries(t, filepath.Join(dir, summary.SeriesFile))
+	if len(samples) < 3 {
+		t.Fatalf("samples = %d, want one per interval", len(samples))
+	}
+	var requests, non2xx, socketErrors int64
+	for i, sample := range samples {
+		requests += sample.Requests
+		non2xx += sample.Non2xx3xx
+		socketErrors += sample.SocketErrors
+		end := sample.TimestampUTC.Add(time.Duration(sample.DurationSeconds * float64(time.Second))).Round(time.Millisecond)
+		if i < len(samples)-1 && !end.Equal(end.Truncate(interval)) {
+			t.Fatalf("interval %d ends at %s, not on a %s boundary", i, end, interval)
+		}
+		if sample.Stage != "warmup" || sample.DurationSeconds <= 0 || sample.DurationSeconds > interval.Seconds()+1e-9 {
+			t.Fatalf("unexpected sample %+v", sample)
+		}
+		if sample.Requests > sample.SocketErrors && (sample.Latency == nil || sample.Latency.MaxMillis < sample.Latency.P50Millis) {
+			t.Fatalf("interval %d with responses has latency %+v", i, sample.Latency)
+		}
+	}
+	if requests != 9 || non2xx != 3 || socketErrors != 3 {
+		t.Fatalf("requests=%d non2xx=%d socketErrors=%d, want 9, 3, 3", requests, non2xx, socketErrors)
+	}
+}
+
+func readSeries(t *testing.T, path string) []summary.SeriesSample {
+	t.Helper()
+	runDir := t.TempDir()
+	stageDir := filepath.Join(runDir, "wrk2-results", "stage")
+	if err := os.MkdirAll(stageDir, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(stageDir, summary.SeriesFile), raw, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	samples, err := summary.LoadSeries(runDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return samples
+}
@@ -1,24 +1,29 @@
 package harness
 
 import (
+	"bytes"
 	"context"
 	"encoding/json"
 	"errors"
 	"fmt"
 	"io"
 	"log"
+	"math"
 	"net/http"
 	"net/url"
 	"os"
 	"path/filepath"
 	"sort"
 	"strings"
+	"sync/atomic"
 	"time"
 
 	"github.com/compose-spec/compose-go/v2/types"
+	"github.com/d-iii-s/slsbench/internal/service/activator"
 	"github.com/d-iii-s/slsbench/internal/service/datagen"
 	"github.com/d-iii-s/slsbench/internal/service/docker"
 	"github.com/d-iii-s/slsbench/internal/service/flowgen"
+	"github.com/d-iii-s/slsbench/internal/service/summary"
 	"github.com/d-iii-s/slsbench/internal/utils"
 	"github.com/docker/cli/cli/command"
 	"github.com/docker/cli/cli/flags"
@@ -28,6 +33,7 @@ import (
 	"github.com/docker/docker/api/types/filters"
 	"github.com/docker/docker/api/types/mount"
 	"github.com/docker/docker/client"
+	"github.com/docker/docker/pkg/stdcopy"
 	"gopkg.in/yaml.v3"
 )
 
@@ -36,6 +42,9 @@ const (
 	defaultFirstResponseInterval = 200 * time.Millisecond
 	defaultAPIBasePathPrefix     = "/"
 	wrkFlowImage                 = "aape2k/wrk2-flow:v3.0"
+	wrk2ContainerLogFile         = "wrk_container.log"
+	wrk2OutputFile               = "wrk2-output.txt"
+	wrk2StatsService             = "wrk2-flow"
 )
 
 type EventProcessor struct{}
@@ -120,6 +129,10 @@ type benchmarkContainerStatsSample struct {
 	TimestampUTC     time.Time `json:"timestampUtc"`
 	ContainerID      string    `json:"containerId"`
 	ContainerName    string    `json:"containerName,omitempty"`
+	Service          string    `json:"service,omitempty"`
+	Role             string    `json:"role,omitempty"`
+	Phase            string    `json:"phase,omitempty"`
+	Stage            string    `json:"stage,omitempty"`
 	CPUPercent       float64   `json:"cpuPercent"`
 	MemoryUsageBytes uint64    `json:"memoryUsageBytes"`
 	MemoryLimitBytes uint64    `json:"memoryLimitBytes"`
@@ -133,74 +146,212 @@ type benchmarkContainerStatsSample struct {
 	TotalCPUUsage    uint64    `json:"totalCpuUsage"`
 	PreSystemUsage   uint64    `json:"preSystemUsage"`
 	PreTotalCPUUsage uint64    `json:"preTotalCpuUsage"`
+
+	// CPU time in user and kernel mode and the CFS throttling counters; all
+	// cumulative, times in nanoseconds.
+	UserCPUUsage          uint64 `json:"userCpuUsage"`
+	KernelCPUUsage        uint64 `json:"kernelCpuUsage"`
+	CPUPeriods            uint64 `json:"cpuPeriods"`
+	CPUThrottledPeriods   uint64 `json:"cpuThrottledPeriods"`
+	CPUThrottledTimeNanos uint64 `json:"cpuThrottledTimeNanos"`
+	// CPUThrottledPercent is the share of CFS periods since the previous
+	// sample in which the container was throttled.
+	CPUThrottledPercent float64 `json:"cpuThrottledPercent"`
+
+	// Cumulative block I/O over all devices.
+	BlockReadBytes  uint64 `json:"blockReadBytes"`
+	BlockWriteBytes uint64 `json:"blockWriteBytes"`
+	BlockReadOps    uint64 `json:"blockReadOps"`
+	BlockWriteOps   uint64 `json:"blockWriteOps"`
+
+	// Memory breakdown. Cache is page cache ("cache" on cgroup v1, "file" on
+	// v2), RSS anonymous memory ("rss" or "anon"). Failcnt counts OOM events
+	// on cgroup v2 and limit hits on v1. MemoryStat is the raw memory.stat
+	// map as reported by Docker.
+	MemoryCacheBytes      uint64            `json:"memoryCacheBytes"`
+	MemoryRSSBytes        uint64            `json:"memoryRssBytes"`
+	MemoryPageFaults      uint64            `json:"memoryPageFaults"`
+	MemoryMajorPageFaults uint64            `json:"memoryMajorPageFaults"`
+	MemoryFailcnt         uint64            `json:"memoryFailcnt"`
+	MemoryStat            map[string]uint64 `json:"memoryStat,omitempty"`
+
+	// Networks holds the cumulative counters per interface.
+	Networks map[string]networkInterfaceStats `json:"networks,omitempty"`
 }
 
-func Run(
-	ctx context.Context,
-	flowPath, resultPath, openAPISpecPath, dockerComposePath, serviceName string,
-	port int,
-	serviceMountPaths []string,
-	probeBodiesPath, dockerSocketPath string,
-	debugNon2xx bool,
-	readinessPathOverride string,
-) error {
-	if strings.TrimSpace(flowPath) == "" {
+// Options configures a single harness run.
+type Options struct {
+	FlowPath          string
+	ResultPath        string
+	OpenAPISpecPath   string
+	DockerComposePath string
+	ServiceName       string
+	Port              int
+	ServiceMountPaths []string
+	ProbeBodiesPath   string
+	DockerSocketPath  string
+	DebugNon2xx       bool
+	// ReadinessPath overrides the readiness probe path derived from the
+	// OpenAPI spec and probe data when non-empty.
+	ReadinessPath string
+	// Session, when set, is an already running compose project owned by the
+	// caller. The harness benchmarks it instead of creating its own project
+	// and leaves it running on return.
+	Session *ComposeSession
+	// Activator, when set, routes the stages through the scale-to-zero
+	// activator. First-response time is still measured on the service.
+	Activator *ActivatorOptions
+	// Executor selects the load generator of the stages: ExecutorWrk2Flow
+	// (the default when empty) or ExecutorNative.
+	Executor string
+	// EventPolicies decides what oom, die and unhealthy events of the
+	// compose containers do to the run; nil uses DefaultEventPolicies.
+	EventPolicies EventPolicies
+	// SeriesInterval is the interval of the per-stage time series; zero
+	// uses DefaultSeriesInterval. Only ExecutorNative writes a series.
+	SeriesInterval time.Duration
+}
+
+func (o Options) validate() error {
+	if err := o.validateInputs(true); err != nil {
+		return err
+	}
+	dsl, err := flowgen.ParseDSL(o.FlowPath)
+	if err != nil {
+		return fmt.Errorf("failed to parse flow: %w", err)
+	}
+	return checkExecutorSupport(dsl, o.Executor)
+}
+
+// checkExecutorSupport rejects stage settings the executor cannot honour,
+// so that a run fails before the compose project starts instead of
+// silently ignoring them.
+func checkExecutorSupport(dsl *flowgen.DSL, executor string) error {
+	if executor == ExecutorNative {
+		return nil
+	}
+	for _, stageName := range sortedStageNames(dsl) {
+		stage := dsl.Stages[stageName]
+		load, err := stage.LoadProfile()
+		if err != nil {
+			return fmt.Errorf("stage %q has an invalid load: %w", stageName, err)
+		}
+		if !load.Constant() {
+			return fmt.Errorf("stage %q has rate segments or a warmup, which need --executor %s", stageName, ExecutorNative)
+		}
+		// The wrk2-flow image sends the steps of an iteration back to back.
+		if stage.HasThinkTime() {
+			return fmt.Errorf("stage %q has a thinktime, which needs --executor %s", stageName, ExecutorNative)
+		}
+		// Only the native executor reports its running request totals.
+		if stage.Guards != nil && guardsNeedProgress(*stage.Guards) {
+			return fmt.Errorf("stage %q has a maxNon2xxRatio or minRateRatio guard, which needs --executor %s", stageName, ExecutorNative)
+		}
+	}
+	return nil
+}
+
+// validateInputs checks the options. Callers that replay no probe-bodies
+// iterations pass probeBodies=false and may leave ProbeBodiesPath empty.
+func (o Options) validateInputs(probeBodies bool) error {
+	if strings.TrimSpace(o.FlowPath) == "" {
 		return fmt.Errorf("flow path must be non-empty")
 	}
-	if strings.TrimSpace(resultPath) == "" {
+	if strings.TrimSpace(o.ResultPath) == "" {
 		return fmt.Errorf("result path must be non-empty")
 	}
-	if strings.TrimSpace(openAPISpecPath) == "" {
+	if strings.TrimSpace(o.OpenAPISpecPath) == "" {
 		return fmt.Errorf("openapi spec path must be non-empty")
 	}
-	if strings.TrimSpace(dockerComposePath) == "" {
+	if strings.TrimSpace(o.DockerComposePath) == "" {
 		return fmt.Errorf("docker compose path must be non-empty")
 	}
-	if strings.TrimSpace(serviceName) == "" {
+	if strings.TrimSpace(o.ServiceName) == "" {
 		return fmt.Errorf("service name must be non-empty")
 	}
-	if strings.TrimSpace(probeBodiesPath) == "" {
+	if probeBodies && strings.TrimSpace(o.ProbeBodiesPath) == "" {
 		return fmt.Errorf("probe-bodies path must be non-empty")
 	}
-	if port <= 0 {
-		return fmt.Errorf("port must be positive, got %d", port)
+	if o.Port <= 0 {
+		return fmt.Errorf("port must be positive, got %d", o.Port)
 	}
-	if err := validateReadableFile(flowPath); err != nil {
+	if err := validateReadableFile(o.FlowPath); err != nil {
 		return fmt.Errorf("invalid flow path: %w", err)
 	}
-	if err := validateReadableFile(openAPISpecPath); err != nil {
+	if err := validateReadableFile(o.OpenAPISpecPath); err != nil {
 		return fmt.Errorf("invalid openapi spec path: %w", err)
 	}
-	if err := validateReadableFile(dockerComposePath); err != nil {
+	if err := validateReadableFile(o.DockerComposePath); err != nil {
 		return fmt.Errorf("invalid docker compose path: %w", err)
 	}
-	if err := validateReadableDir(probeBodiesPath); err != nil {
-		return fmt.Errorf("invalid probe-bodies path: %w", err)
+	if probeBodies {
+		if err := validateReadableDir(o.ProbeBodiesPath); err != nil {
+			return fmt.Errorf("invalid probe-bodies path: %w", err)
+		}
 	}
-	if strings.TrimSpace(dockerSocketPath) != "" {
-		if err := validateReadableFile(dockerSocketPath); err != nil {
+	if strings.TrimSpace(o.DockerSocketPath) != "" {
+		if err := validateReadableFile(o.DockerSocketPath); err != nil {
 			return fmt.Errorf("invalid docker socket path: %w", err)
 		}
 	}
-
-	runDir, err := utils.CreateResultSubdirWithPrefix(resultPath, "harness-result")
-	if err != nil {
-		return fmt.Errorf("failed to create result directory: %w", err)
+	switch o.Executor {
+	case "", ExecutorWrk2Flow:
+	case ExecutorNative:
+		if o.Activator != nil {
+			return fmt.Errorf("the %s executor cannot reach the activator, which is only on the compose network", ExecutorNative)
+		}
+	default:
+		return fmt.Errorf("unknown executor %q (expected %s or %s)", o.Executor, ExecutorWrk2Flow, ExecutorNative)
 	}
-	log.Printf("Harness output run directory: %s", runDir)
-
-	dockerCli, dockerHost, err := NewDockerClientWithSocket(dockerSocketPath)
-	if err != nil {
-		return err
+	if o.SeriesInterval < 0 {
+		return fmt.Errorf("series interval must not be negative, got %s", o.SeriesInterval)
 	}
-	defer dockerCli.Close()
+	// wrk2-flow prints its numbers only when the stage ends.
+	if o.SeriesInterval > 0 && o.Executor != ExecutorNative {
+		return fmt.Errorf("a series interval needs --executor %s; wrk2-flow reports no per-interval latency", ExecutorNative)
+	}
+	for event, action := range o.EventPolicies {
+		if _, err := ParseEventPolicies([]string{event + "=" + action}); err != nil {
+			return err
+		}
+	}
+	if o.Activator != nil {
+		if o.Activator.Mode != activator.ModePause && o.Activator.Mode != activator.ModeStop {
+			return fmt.Errorf("unknown activator mode %q (expected %s or %s)", o.Activator.Mode, activator.ModePause, activator.ModeStop)
+		}
+		if strings.TrimSpace(o.Activator.Image) == "" {
+			return fmt.Errorf("the activator needs an image with the activator command, e.g. one built from this repository with docker build -t slsbench:dev .")
+		}
+		if o.Activator.MaxConcurrency < 0 {
+			return fmt.Errorf("activator max concurrency must not be negative, got %d", o.Activator.MaxConcurrency)
+		}
+	}
+	return nil
+}
 
+// ComposeSession is a created and started compose project.
+type ComposeSession struct {
+	Name    string
+	Project *types.Project
+	// Timeline records when the project resources were created and started.
+	Timeline []summary.TimelineEntry
+	compose  api.Compose
+}
+
+// StartComposeSession loads the compose file under projectName, checks that
+// serviceName is defined, then creates and starts all project resources.
+func StartComposeSession(ctx context.Context, dockerHost, dockerComposePath, projectName, serviceName string) (*ComposeSession, error) {
+	return startComposeSession(ctx, dockerHost, dockerComposePath, projectName, serviceName, nil)
+}
+
+// startComposeSession is StartComposeSession with startFailed, if set, called
+// when the containers were created but failed to start, before they are torn
+// down.
+func startComposeSession(ctx context.Context, dockerHost, dockerComposePath, projectName, serviceName string, startFailed func(projectName string)) (*ComposeSession, error) {
 	composeService, err := NewComposeServiceWithDockerHost(dockerHost)
 	if err != nil {
-		return err
+		return nil, err
 	}
-
-	projectName := fmt.Sprintf("harness-%d", time.Now().UnixNano())
 	log.Printf("[harness][compose] context project=%s composePath=%s service=%s dockerHost=%s", projectName, dockerComposePath, serviceName, dockerHost)
 
 	loadStartedAt := time.Now()
@@ -211,73 +362,197 @@ func Run(
 	})
 	if err != nil {
 		log.Printf("[harness][compose] phase=load failed project=%s elapsed=%s error=%v", projectName, time.Since(loadStartedAt), err)
-		return fmt.Errorf("failed to load compose project: %w", err)
+		return nil, fmt.Errorf("failed to load compose project: %w", err)
 	}
 	log.Printf("[harness][compose] phase=load done project=%s elapsed=%s services=%d", projectName, time.Since(loadStartedAt), len(project.Services))
 	if len(project.Services) == 0 {
-		return fmt.Errorf("compose project %q has no services", projectName)
+		return nil, fmt.Errorf("compose project %q has no services", projectName)
 	}
 	if !containsComposeService(project, serviceName) {
-		return fmt.Errorf("service %q is not present in compose file %q", serviceName, dockerComposePath)
+		return nil, fmt.Errorf("service %q is not present in compose file %q", serviceName, dockerComposePath)
 	}
 
 	createStartedAt := time.Now()
 	log.Printf("[harness][compose] phase=create begin project=%s", projectName)
 	if err := composeService.Create(ctx, project, api.CreateOptions{Build: &api.BuildOptions{}}); err != nil {
 		log.Printf("[harness][compose] phase=create failed project=%s elapsed=%s error=%v", projectName, time.Since(createStartedAt), err)
-		return fmt.Errorf("failed to create compose resources: %w", err)
+		return nil, fmt.Errorf("failed to create compose resources: %w", err)
 	}
 	log.Printf("[harness][compose] phase=create done project=%s elapsed=%s", projectName, time.Since(createStartedAt))
 
+	session := &ComposeSession{Name: projectName, Project: project, compose: composeService}
+	session.Timeline = append(session.Timeline, summary.TimelineEntry{Phase: summary.TimelineComposeCreate, StartedAt: createStartedAt.UTC(), FinishedAt: time.Now().UTC()})
 	startStartedAt := time.Now()
 	log.Printf("[harness][compose] phase=start begin project=%s", projectName)
 	if err := composeService.Start(ctx, projectName, api.StartOptions{Project: project}); err != nil {
 		log.Printf("[harness][compose] phase=start failed project=%s elapsed=%s error=%v", projectName, time.Since(startStartedAt), err)
-		return fmt.Errorf("failed to start compose resources: %w", err)
+		if startFailed != nil {
+			startFailed(projectName)
+		}
+		_ = session.Down(context.Background(), "failure")
+		return nil, fmt.Errorf("failed to start compose resources: %w", err)
 	}
 	log.Printf("[harness][compose] phase=start done project=%s elapsed=%s", projectName, time.Since(startStartedAt))
+	session.Timeline = append(session.Timeline, summary.TimelineEntry{Phase: summary.TimelineComposeStart, StartedAt: startStartedAt.UTC(), FinishedAt: time.Now().UTC()})
+	return session, nil
+}
+
+// Down tears down the compose project. reason is only used for logging.
+func (s *ComposeSession) Down(ctx context.Context, reason string) error {
+	downStartedAt := time.Now()
+	log.Printf("[harness][compose] phase=down begin project=%s reason=%s", s.Name, reason)
+	if err := s.compose.Down(ctx, s.Name, api.DownOptions{Project: s.Project}); err != nil {
+		log.Printf("[harness][compose] phase=down failed project=%s elapsed=%s error=%v", s.Name, time.Since(downStartedAt), err)
+		return err
+	}
+	log.Printf("[harness][compose] phase=down done project=%s elapsed=%s", s.Name, time.Since(downStartedAt))
+	return nil
+}
+
+// Run executes one harness run: it starts the compose project (unless
+// opts.Session is set), measures the first response, replays every stage
+// with wrk2-flow and collects the results under a timestamped directory.
+func Run(ctx context.Context, opts Options) (runErr error) {
+	if err := opts.validate(); err != nil {
+		return err
+	}
+	flowPath := opts.FlowPath
+	serviceName := opts.ServiceName
+	port := opts.Port
+	probeBodiesPath := opts.ProbeBodiesPath
+	debugNon2xx := opts.DebugNon2xx
+
+	runDir, err := utils.CreateResultSubdirWithPrefix(opts.ResultPath, "harness-result")
+	if err != nil {
+		return fmt.Errorf("failed to create result directory: %w", err)
+	}
+	log.Printf("Harness output run directory: %s", runDir)
 
-	var runErr error
+	runSummary := &summary.RunSummary{
+		SchemaVersion: summary.RunSummarySchema,
+		StartedAt:     time.Now().UTC(),
+		FlowPath:      flowPath,
+		ServiceName:   serviceName,
+		Port:          port,
+		ComposeFile:   opts.DockerComposePath,
+		ProbeBodies:   probeBodiesPath,
+		Stages:        []summary.StageSummary{},
+	}
+	phases := newTimeline()
+	// An abort event policy cancels ctx with an ErrRunAborted cause.
+	ctx, abortRun := context.WithCancelCause(ctx)
+	defer abortRun(nil)
+	// Registered first so it runs last and records the final outcome,
+	// including teardown errors.
 	defer func() {
-		downStartedAt := time.Now()
-		reason := "success"
-		if runErr != nil {
-			reason = "failure"
+		if err := summary.WriteJSON(filepath.Join(runDir, summary.TimelineFile), phases.snapshot()); err != nil {
+			log.Printf("[harness][summary] failed to write timeline: %v", err)
 		}
-		log.Printf("[harness][compose] phase=down begin project=%s reason=%s", projectName, reason)
-		downErr := composeService.Down(context.Background(), projectName, api.DownOptions{Project: project})
-		if downErr != nil {
-			log.Printf("[harness][compose] phase=down failed project=%s elapsed=%s error=%v", projectName, time.Since(downStartedAt), downErr)
-		} else {
-			log.Printf("[harness][compose] phase=down done project=%s elapsed=%s", projectName, time.Since(downStartedAt))
+		if cause := context.Cause(ctx); errors.Is(cause, ErrRunAborted) {
+			runErr = cause
 		}
-		if downErr != nil && runErr == nil {
-			runErr = fmt.Errorf("failed to tear down compose project: %w", downErr)
+		runSummary.FinishedAt = time.Now().UTC()
+		runSummary.Status = summary.StatusCompleted
+		if runErr != nil {
+			runSummary.Status = summary.StatusFailed
+			runSummary.Error = runErr.Error()
+		}
+		if err := summary.WriteJSON(filepath.Join(runDir, summary.RunSummaryFile), runSummary); err != nil {
+			log.Printf("[harness][summary] failed to write run summary: %v", err)
+			if runErr == nil {
+				runErr = fmt.Errorf("failed to write run summary: %w", err)
+			}
 		}
 	}()
 
+	dockerCli, dockerHost, err := NewDockerClientWithSocket(opts.DockerSocketPath)
+	if err != nil {
+		return err
+	}
+	defer dockerCli.Close()
+
+	// Stopped after teardown so the shutdown output is captured too.
+	containerLogs := newServiceLogs(dockerCli, runDir)
+	defer containerLogs.stop()
+
+	session := opts.Session
+	if session == nil {
+		// A service that crashes on start is only known by its logs.
+		session, err = startComposeSession(ctx, dockerHost, opts.DockerComposePath, fmt.Sprintf("harness-%d", time.Now().UnixNano()), serviceName, func(projectName string) {
+			if err := containerLogs.dump(context.Background(), projectName); err != nil {
+				log.Printf("[harness][logs] failed to dump service logs: %v", err)
+			}
+		})
+		if err != nil {
+			return err
+		}
+		defer func() {
+			reason := "success"
+			if runErr != nil {
+				reason = "failure"
+			}
+			endTeardown := phases.begin(summary.TimelineTeardown, "")
+			downErr := session.Down(context.Background(), reason)
+			endTeardown(downErr)
+			if downErr != nil && runErr == nil {
+				runErr = fmt.Errorf("failed to tear down compose project: %w", downErr)
+			}
+		}()
+	} else {
+		if !containsComposeService(session.Project, serviceName) {
+			return fmt.Errorf("service %q is not present in compose project %q", serviceName, session.Name)
+		}
+		log.Printf("[harness][compose] reusing running project=%s; first-response time is measured against an already started service", session.Name)
+	}
+	phases.add(session.Timeline...)
+	projectName := session.Name
+	project := session.Project
+	runSummary.ComposeName = projectName
+	if err := containerLogs.start(ctx, projectName); err != nil {
+		return fmt.Errorf("failed to capture service logs: %w", err)
+	}
+	// Stopped before teardown, whose die events are expected.
+	watcher := newEventWatcher(opts.EventPolicies, phases, abortRun)
+	if err := watcher.start(ctx, dockerCli, projectName, filepath.Join(runDir, summary.DockerEventsFile)); err != nil {
+		return err
+	}
+	defer watcher.stop()
+
 	networkName := getProjectNetworkName(project)
 	log.Printf("[harness][compose] resolved network project=%s network=%s", projectName, networkName)
 	serviceContainerID, err := findContainerIDByServiceName(ctx, dockerCli, projectName, serviceName)
 	if err != nil {
 		return fmt.Errorf("failed to find service container id: %w", err)
 	}
-	statsOutputPath := filepath.Join(runDir, "benchmark-container-stats.jsonl")
-	log.Printf("[harness][stats] streaming begin container=%s output=%s", serviceContainerID, statsOutputPath)
-	stopStatsCollector, err := startBenchmarkContainerStatsCollector(ctx, dockerCli, serviceContainerID, statsOutputPath)
-	if err != nil {
-		return fmt.Errorf("failed to start benchmark container stats collector: %w", err)
+	if opts.Activator != nil && opts.Activator.Mode == activator.ModeStop {
+		watcher.expectedDie = serviceContainerID
 	}
+	// Runs once the collectors below have stopped and flushed their files.
 	defer func() {
-		if stopErr := stopStatsCollector(); stopErr != nil {
-			log.Printf("[harness][stats] streaming failed container=%s error=%v", serviceContainerID, stopErr)
-			if runErr == nil {
-				runErr = fmt.Errorf("failed to finalize benchmark container stats collector: %w", stopErr)
-			}
-			return
+		if err := writeResourceSummaries(runDir, runSummary.Stages); err != nil {
+			log.Printf("[harness][summary] failed to write resource summaries: %v", err)
+		}
+	}()
+	collectors := newStatsCollectors(dockerCli, phases)
+	// Routes the service samples to the guard rails of the running stage.
+	var activeGuard atomic.Pointer[stageGuard]
+	collectors.observe = func(sample benchmarkContainerStatsSample) {
+		if guard := activeGuard.Load(); guard != nil {
+			guard.observeSample(sample)
+		}
+	}
+	defer func() {
+		if stopErr := collectors.stopAll(); stopErr != nil && runErr == nil {
+			runErr = fmt.Errorf("failed to finalize benchmark container stats collector: %w", stopErr)
 		}
-		log.Printf("[harness][stats] streaming done container=%s output=%s", serviceContainerID, statsOutputPath)
 	}()
+	serviceTag := statsTag{Service: serviceName, Role: summary.RoleService}
+	if err := collectors.start(ctx, serviceContainerID, filepath.Join(runDir, summary.StatsFile), serviceTag); err != nil {
+		return err
+	}
+	if err := collectors.startDependencies(ctx, projectName, serviceContainerID, runDir); err != nil {
+		return err
+	}
 
 	dsl, err := flowgen.ParseDSL(flowPath)
 	if err != nil {
@@ -286,34 +561,82 @@ func Run(
 	if len(dsl.Stages) == 0 {
 		return fmt.Errorf("flow has no stages")
 	}
-
-	apiBasePath := DeriveAPIBasePath(openAPISpecPath)
-	var effectiveReadinessPath string
-	if strings.TrimSpace(readinessPathOverride) != "" {
-		effectiveReadinessPath = readinessPathOverride
-		log.Printf("Using explicit readiness path override: %s", effectiveReadinessPath)
-	} else {
-		effectiveReadinessPath = readinessPath(apiBasePath, firstResolvedPathFromProbeData(probeBodiesPath))
-		log.Printf("Auto-derived readiness path: %s", effectiveReadinessPath)
+	loads := make(map[string]flowgen.Load, len(dsl.Stages))
+	for stageName, stage := range dsl.Stages {
+		load, err := stage.LoadProfile()
+		if err != nil {
+			return fmt.Errorf("stage %q has an invalid load: %w", stageName, err)
+		}
+		loads[stageName] = load
 	}
-	firstResult, err := measureFirstResponse(ctx, serviceName, port, effectiveReadinessPath)
+
+	readyPath := opts.effectiveReadinessPath()
+	endReadiness := phases.begin(summary.TimelineReadiness, "")
+	firstResult, err := measureFirstResponse(ctx, serviceName, port, readyPath)
+	endReadiness(err)
 	if err != nil {
 		return fmt.Errorf("failed to measure first response: %w", err)
 	}
 	if err := writeJSON(filepath.Join(runDir, "first_request_result.json"), firstResult); err != nil {
 		return fmt.Errorf("failed to write first request result: %w", err)
 	}
+	runSummary.FirstResponse = &summary.FirstResponse{
+		TargetURL:        firstResult.TargetURL,
+		StartedAt:        firstResult.StartedAt,
+		FinishedAt:       firstResult.FinishedAt,
+		DurationSeconds:  firstResult.DurationSeconds,
+		DurationMillis:   firstResult.DurationMillis,
+		Attempts:         firstResult.Attempts,
+		StatusCode:       firstResult.StatusCode,
+		ResolvedPathUsed: firstResult.ResolvedPathUsed,
+	}
+
+	// wrk2 reaches the service through targetHost.
+	targetHost := serviceName
+	var activatorProxy *activatorContainer
+	if opts.Activator != nil {
+		endActivatorStart := phases.begin(summary.TimelineActivatorStart, "")
+		activatorProxy, err = startActivatorContainer(ctx, dockerCli, opts.Activator, networkName, serviceName, port, serviceContainerID, readyPath, opts.DockerSocketPath, runDir)
+		endActivatorStart(err)
+		if err != nil {
+			return err
+		}
+		targetHost = activatorProxy.Name
+		// Runs before teardown; the happy path stops it earlier.
+		defer func() {
+			if err := activatorProxy.stop(context.Background()); err != nil && runErr == nil {
+				runErr = err
+			}
+		}()
+	}
 
+	// The native executor sends from this process, so it uses the address
+	// the first response was measured on.
+	serviceURL, err := url.Parse(firstResult.TargetURL)
+	if err != nil {
+		return fmt.Errorf("invalid readiness target %q: %w", firstResult.TargetURL, err)
+	}
+	stageExecutor, err := newExecutor(opts, dockerCli, networkName, serviceURL.Scheme+"://"+serviceURL.Host)
+	if err != nil {
+		return err
+	}
 	stageNames := sortedStageNames(dsl)
 	for _, stageName := range stageNames {
-		stage := dsl.Stages[stageName]
-		if _, err := flowgen.ParseWrk2Params(stage.Wrk2Params); err != nil {
-			return fmt.Errorf("stage %q has invalid wrk2params: %w", stageName, err)
-		}
+		stage, load := dsl.Stages[stageName], loads[stageName]
 		stageIterations, err := loadStageIterations(probeBodiesPath, stageName)
 		if err != nil {
 			return err
 		}
+		stageIterations = interleaveEntries(stageName, stage, stageIterations)
+		applyThinkTimes(stage, stageIterations)
+		var thinkSeed int64
+		if stage.HasThinkTime() {
+			thinkSeed = thinkTimeSeed(stageName, stage)
+			estimate := estimateThinkTime(stageIterations, load.MeanRate(), load.Connections, thinkSeed)
+			if offered := estimate.OfferedRequestsPerSecond; offered < thinkTimeRateTolerance*float64(load.MeanRate()) {
+				log.Printf("[harness][thinktime] stage=%s think time limits the offered rate to about %.0f req/s of the target %d req/s; raise the connections to keep the target rate", stageName, offered, load.MeanRate())
+			}
+		}
 		stageRoot := filepath.Join(runDir, "wrk2-input", sanitizePathPart(stageName))
 		stageDataDir := filepath.Join(stageRoot, stageName)
 		if err := os.MkdirAll(stageDataDir, 0o755); err != nil {
@@ -327,52 +650,103 @@ func Run(
 		if err := os.MkdirAll(stageOutputDir, 0o755); err != nil {
 			return fmt.Errorf("failed to create stage output directory: %w", err)
 		}
-		log.Printf("Starting wrk2-flow run for stage=%s", stageName)
+		// interrupt ends the stage early once a guard rail is broken.
+		interrupt := make(chan struct{})
+		guardCtx, stopGuard := context.WithCancel(ctx)
+		var guard *stageGuard
+		if stage.Guards != nil {
+			guard = newStageGuard(*stage.Guards, load, time.Now(), filepath.Join(stageOutputDir, executorProgressFile))
+			activeGuard.Store(guard)
+			go guard.watch(guardCtx, func() { close(interrupt) })
+		}
+		log.Printf("Starting %s run for stage=%s", stageExecutor.name(), stageName)
 		log.Printf("Stage wrk2 debug mode stage=%s flowDebugNon2xx=%t", stageName, debugNon2xx)
-		if err := runWrk2FlowContainer(
-			ctx,
-			dockerCli,
-			networkName,
-			stage.Wrk2Params,
-			stageName,
-			serviceName,
-			port,
-			stageRoot,
-			stageOutputDir,
-			debugNon2xx,
-		); err != nil {
+		execution, err := stageExecutor.run(ctx, stageRun{
+			name:           stageName,
+			load:           load,
+			targetHost:     targetHost,
+			port:           port,
+			iterations:     stageIterations,
+			inputDir:       stageRoot,
+			outputDir:      stageOutputDir,
+			statsPath:      filepath.Join(runDir, summary.ContainerStatsDir, "wrk2-"+sanitizePathPart(stageName)+".jsonl"),
+			phases:         phases,
+			interrupt:      interrupt,
+			seriesInterval: opts.SeriesInterval,
+			thinkTime:      stage.HasThinkTime(),
+			thinkTimeSeed:  thinkSeed,
+		})
+		stopGuard()
+		activeGuard.Store(nil)
+		if err != nil {
 			return err
 		}
-		log.Printf("Completed wrk2-flow run for stage=%s", stageName)
+		execution.GuardReason = guard.tripped()
+		if err := os.WriteFile(filepath.Join(stageOutputDir, "exit_code.txt"), []byte(fmt.Sprintf("%d\n", execution.ExitCode)), 0o644); err != nil {
+			return fmt.Errorf("failed to write exit code for stage=%s: %w", stageName, err)
+		}
+		stageSummary := buildStageSummary(stageName, stage.Wrk2Params, load, stageOutputDir, execution)
+		if activatorProxy != nil {
+			if stageSummary.Activator, err = activatorProxy.summary(execution.StartedAt, execution.FinishedAt); err != nil {
+				log.Printf("[harness][activator] failed to summarize stage=%s: %v", stageName, err)
+			}
+		}
+		stageSummary.SteadyState = detectSteadyState(stageName, stageOutputDir, filepath.Join(runDir, summary.StatsFile), execution)
+		if reasons := watcher.invalidReasons(stageName); len(reasons) > 0 {
+			stageSummary.Invalid = true
+			stageSummary.InvalidReasons = reasons
+			log.Printf("[harness][events] stage=%s marked invalid: %s", stageName, strings.Join(reasons, "; "))
+		}
+		if err := summary.WriteJSON(filepath.Join(stageOutputDir, summary.StageSummaryFile), stageSummary); err != nil {
+			return fmt.Errorf("failed to write stage summary for stage=%s: %w", stageName, err)
+		}
+		runSummary.Stages = append(runSummary.Stages, *stageSummary)
+		if execution.GuardReason != "" {
+			log.Printf("Stopped %s run for stage=%s early: %s", stageExecutor.name(), stageName, execution.GuardReason)
+			continue
+		}
+		if execution.ExitCode != 0 {
+			return fmt.Errorf("%s failed for stage=%s with exit code %d", stageExecutor.name(), stageName, execution.ExitCode)
+		}
+		log.Printf("Completed %s run for stage=%s", stageExecutor.name(), stageName)
 	}
 
-	for _, serviceMountPath := range serviceMountPaths {
-		serviceMountPath = strings.TrimSpace(serviceMountPath)
-		if serviceMountPath == "" {
-			continue
+	if activatorProxy != nil {
+		// Scales the service back up before files are copied out of it.
+		if err := activatorProxy.stop(ctx); err != nil {
+			return err
 		}
-		if err := docker.CopyFromContainer(ctx, dockerCli, serviceContainerID, serviceMountPath, runDir); err != nil {
-			log.Printf("Warning: failed to copy %q from service container: %v", serviceMountPath, err)
+		if runSummary.Activator, err = activatorProxy.summary(time.Time{}, time.Time{}); err != nil {
+			log.Printf("[harness][activator] failed to summarize run: %v", err)
 		}
 	}
 
-	runErr = nil
+	if len(opts.ServiceMountPaths) > 0 {
+		endCopy := phases.begin(summary.TimelineArtifactCopy, "")
+		for _, serviceMountPath := range opts.ServiceMountPaths {
+			serviceMountPath = strings.TrimSpace(serviceMountPath)
+			if serviceMountPath == "" {
+				continue
+			}
+			if err := docker.CopyFromContainer(ctx, dockerCli, serviceContainerID, serviceMountPath, runDir); err != nil {
+				log.Printf("Warning: failed to copy %q from service container: %v", serviceMountPath, err)
+			}
+		}
+		endCopy(nil)
+	}
+
 	return nil
 }
 
-func runWrk2FlowContainer(
-	ctx context.Context,
-	dockerCli *client.Client,
-	networkName, wrk2Params, stageName, serviceName string,
-	port int,
-	dataRootPath, outputPath string,
-	debugNon2xx bool,
-) error {
-	args := buildWrk2Args(wrk2Params)
-	if len(args) == 0 {
-		return fmt.Errorf("empty wrk2 params for stage %q", stageName)
+// run starts one wrk2-flow container for the stage and waits for it to exit.
+func (e *wrk2FlowExecutor) run(ctx context.Context, stage stageRun) (*stageExecution, error) {
+	dockerCli := e.dockerCli
+	stageName := stage.name
+	args, err := stage.load.Wrk2Args()
+	if err != nil {
+		return nil, fmt.Errorf("stage %q: %w", stageName, err)
 	}
-	args = append(args, fmt.Sprintf("http://%s:%d/", serviceName, port))
+	args = append(args, fmt.Sprintf("http://%s:%d/", stage.targetHost, stage.port))
 
 	containerConfig := &dockertypes.Config{
 		Image: wrkFlowImage,
@@ -381,22 +755,22 @@ func runWrk2FlowContainer(
 			"FLOW_DATA_DIR=/flowdata",
 			fmt.Sprintf("FLOW_STAGE=%s", stageName),
 			"FLOW_STATS_OUT_DIR=/stats",
-			fmt.Sprintf("FLOW_DEBUG_NON2XX=%d", boolToInt(debugNon2xx)),
+			fmt.Sprintf("FLOW_DEBUG_NON2XX=%d", boolToInt(e.debugNon2xx)),
 		},
 		Cmd: args,
 	}
 	hostConfig := &dockertypes.HostConfig{
-		NetworkMode: dockertypes.NetworkMode(networkName),
+		NetworkMode: dockertypes.NetworkMode(e.networkName),
 		Mounts: []mount.Mount{
 			{
 				Type:     mount.TypeBind,
-				Source:   dataRootPath,
+				Source:   stage.inputDir,
 				Target:   "/flowdata",
 				ReadOnly: true,
 			},
 			{
 				Type:   mount.TypeBind,
-				Source: outputPath,
+				Source: stage.outputDir,
 				Target: "/stats",
 			},
 		},
@@ -405,77 +779,186 @@ func runWrk2FlowContainer(
 	containerName := fmt.Sprintf("harness-%s-%d", sanitizePathPart(stageName), time.Now().UnixNano())
 	resp, err := dockerCli.ContainerCreate(ctx, containerConfig, hostConfig, nil, nil, containerName)
 	if err != nil {
-		return fmt.Errorf("failed to create wrk2 container for stage=%s: %w", stageName, err)
+		return nil, fmt.Errorf("failed to create wrk2 container for stage=%s: %w", stageName, err)
 	}
 
 	defer func() {
 		_ = dockerCli.ContainerRemove(context.Background(), resp.ID, dockertypes.RemoveOptions{Force: true})
 	}()
 
+	execution := &stageExecution{Executor: e.name(), StartedAt: time.Now().UTC()}
+	endStage := stage.phases.begin(summary.TimelineStage, stageName)
 	if err := dockerCli.ContainerStart(ctx, resp.ID, dockertypes.StartOptions{}); err != nil {
-		return fmt.Errorf("failed to start wrk2 container for stage=%s: %w", stageName, err)
+		endStage(err)
+		return nil, fmt.Errorf("failed to start wrk2 container for stage=%s: %w", stageName, err)
+	}
+	// The load generator's own stats show whether it, rather than the
+	// service, was the bottleneck.
+	stopStats, err := startBenchmarkContainerStatsCollector(ctx, dockerCli, resp.ID, stage.statsPath, statsTag{Service: wrk2StatsService, Role: summary.RoleLoadGenerator}, stage.phases, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to start wrk2 stats collector for stage=%s: %w", stageName, err)
 	}
+	defer func() {
+		if err := stopStats(); err != nil {
+			log.Printf("[harness][stats] wrk2 stats failed stage=%s error=%v", stageName, err)
+		}
+	}()
 
+	var waitErr error
 	statusCh, errCh := dockerCli.ContainerWait(ctx, resp.ID, dockertypes.WaitConditionNotRunning)
-	var exitCode int64
+	exited := make(chan struct{})
+	defer close(exited)
+	go func() {
+		select {
+		case <-stage.interrupt:
+			interruptWrk2Container(dockerCli, resp.ID, stageName)
+		case <-exited:
+		}
+	}()
 	select {
-	case waitErr := <-errCh:
-		if waitErr != nil {
-			return fmt.Errorf("wrk2 container wait failed for stage=%s: %w", stageName, waitErr)
+	case err := <-errCh:
+		if err != nil {
+			waitErr = fmt.Errorf("wrk2 container wait failed for stage=%s: %w", stageName, err)
 		}
 	case status := <-statusCh:
 		if status.Error != nil {
-			return fmt.Errorf("wrk2 container exited with error for stage=%s: %s", stageName, status.Error.Message)
+			waitErr = fmt.Errorf("wrk2 container exited with error for stage=%s: %s", stageName, status.Error.Message)
 		}
-		exitCode = status.StatusCode
+		execution.ExitCode = status.StatusCode
+	}
+	endStage(waitErr)
+	if waitErr != nil {
+		return nil, waitErr
 	}
+	execution.FinishedAt = time.Now().UTC()
 
-	if err := writeContainerLogs(ctx, dockerCli, resp.ID, filepath.Join(outputPath, "wrk_container.log")); err != nil {
-		return fmt.Errorf("failed to write wrk2 container logs for stage=%s: %w", stageName, err)
+	stdout, err := writeContainerLogs(ctx, dockerCli, resp.ID, stage.outputDir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to write wrk2 container logs for stage=%s: %w", stageName, err)
 	}
-	if err := os.WriteFile(filepath.Join(outputPath, "exit_code.txt"), []byte(fmt.Sprintf("%d\n", exitCode)), 0o644); err != nil {
-		return fmt.Errorf("failed to write wrk2 exit code for stage=%s: %w", stageName, err)
+	execution.Stdout = stdout
+	return execution, nil
+}
+
+// buildStageSummary parses the wrk2 report of a finished stage and collects
+// the files the flow executor wrote next to it. A report that cannot be
+// parsed is recorded in the summary instead of failing the run.
+func buildStageSummary(stageName, wrk2Params string, load flowgen.Load, stageOutputDir string, execution *stageExecution) *summary.StageSummary {
+	stageSummary := &summary.StageSummary{
+		SchemaVersion: summary.StageSummarySchema,
+		Stage:         stageName,
+		Executor:      execution.Executor,
+		Wrk2Params:    wrk2Params,
+		StartedAt:     execution.StartedAt,
+		FinishedAt:    execution.FinishedAt,
+		ExitCode:      execution.ExitCode,
+		Status:        summary.StatusCompleted,
+	}
+	switch {
+	case execution.GuardReason != "":
+		stageSummary.Status = summary.StatusFailed
+		stageSummary.Error = "stopped by guard rail: " + execution.GuardReason
+	case execution.ExitCode != 0:
+		stageSummary.Status = summary.StatusFailed
+		stageSummary.Error = fmt.Sprintf("wrk2 exited with code %d", execution.ExitCode)
+	}
+	stageSummary.TargetRate = load.MeanRate()
+	stageSummary.TargetSeconds = int(math.Round(load.MeasuredDuration().Seconds()))
+	stageSummary.WarmupSeconds = int(math.Round(load.Warmup.Seconds()))
+	if segments := load.RateSegments(); len(segments) > 1 || segments[0].Ramp() {
+		for _, segment := range segments {
+			from, to := segment.Rate, segment.Rate
+			if segment.Ramp() {
+				from, to = segment.From, segment.To
+			}
+			stageSummary.RateSegments = append(stageSummary.RateSegments, summary.RateSegment{DurationSeconds: segment.Duration.Seconds(), FromRate: from, ToRate: to})
+		}
 	}
-	if exitCode != 0 {
-		return fmt.Errorf("wrk2 container failed for stage=%s with exit code %d", stageName, exitCode)
+	if result, err := summary.ParseWrk2Output(execution.Stdout); err != nil {
+		stageSummary.ParseError = err.Error()
+		log.Printf("[harness][summary] stage=%s wrk2 output not parsed: %v", stageName, err)
+	} else {
+		stageSummary.Wrk2 = result
 	}
-	return nil
+	stats, files, err := summary.CollectExecutorStats(stageOutputDir, harnessStageFiles...)
+	if err != nil {
+		log.Printf("[harness][summary] stage=%s failed to read executor stats: %v", stageName, err)
+	}
+	stageSummary.ExecutorStats = stats
+	stageSummary.ExecutorFiles = files
+	stageSummary.ThinkTime = execution.ThinkTime
+	return stageSummary
 }
 
-func buildWrk2Args(wrk2Params string) []string {
-	args := strings.Fields(strings.TrimSpace(wrk2Params))
-	if len(args) == 0 {
-		return nil
-	}
-	for _, arg := range args {
-		if arg == "--latency" {
-			return args
-		}
+// detectSteadyState runs the steady-state detector on the time series of a
+// stage and the service stats taken while it ran.
+func detectSteadyState(stageName, stageOutputDir, statsPath string, execution *stageExecution) *summary.SteadyState {
+	series, err := summary.LoadSeriesFile(filepath.Join(stageOutputDir, summary.SeriesFile))
+	if err != nil {
+		log.Printf("[harness][steady-state] stage=%s failed to read series: %v", stageName, err)
 	}
-	return append(args, "--latency")
+	samples, err := summary.LoadStatsSamples(statsPath)
+	if err != nil {
+		log.Printf("[harness][steady-state] stage=%s failed to read stats: %v", stageName, err)
+	}
+	steady := summary.DetectSteadyState(execution.StartedAt, series, summary.SamplesBetween(samples, execution.StartedAt, execution.FinishedAt))
+	switch {
+	case steady == nil:
+		log.Printf("[harness][steady-state] stage=%s has no series or stats to detect steady state", stageName)
+	case steady.Undetermined:
+		log.Printf("[harness][steady-state] stage=%s steady state undetermined: %s", stageName, steady.Reason)
+	case steady.Detected:
+		log.Printf("[harness][steady-state] stage=%s steady after %.1fs from %s", stageName, steady.TimeToSteadyStateSeconds, strings.Join(steady.DecidedBy, ", "))
+	default:
+		log.Printf("[harness][steady-state] stage=%s did not reach steady state", stageName)
+	}
+	return steady
+}
+
+// harnessStageFiles are the files the harness itself writes into a stage
+// output directory, as opposed to those written by the flow executor.
+var harnessStageFiles = []string{
+	wrk2ContainerLogFile,
+	wrk2OutputFile,
+	"exit_code.txt",
+	summary.StageSummaryFile,
+	summary.OperationsFile,
+	summary.SeriesFile,
 }
 
-func writeContainerLogs(ctx context.Context, dockerCli *client.Client, containerID, outputPath string) error {
+// writeContainerLogs demultiplexes the container output into outputDir:
+// stdout and stderr combined into wrk_container.log and stdout alone (the
+// wrk2 report) into wrk2-output.txt. It returns stdout.
+func writeContainerLogs(ctx context.Context, dockerCli *client.Client, containerID, outputDir string) (string, error) {
 	reader, err := dockerCli.ContainerLogs(ctx, containerID, dockertypes.LogsOptions{
 		ShowStdout: true,
 		ShowStderr: true,
 	})
 	if err != nil {
-		return err
+		return "", err
 	}
 	defer reader.Close()
 
-	data, err := io.ReadAll(reader)
-	if err != nil {
-		return err
+	var combined, stdout bytes.Buffer
+	if _, err := stdcopy.StdCopy(io.MultiWriter(&combined, &stdout), &combined, reader); err != nil {
+		return "", err
+	}
+	if err := os.WriteFile(filepath.Join(outputDir, wrk2ContainerLogFile), combined.Bytes(), 0o644); err != nil {
+		return "", err
 	}
-	return os.WriteFile(outputPath, data, 0o644)
+	if err := os.WriteFile(filepath.Join(outputDir, wrk2OutputFile), stdout.Bytes(), 0o644); err != nil {
+		return "", err
+	}
+	return stdout.String(), nil
 }
 
 func startBenchmarkContainerStatsCollector(
 	ctx context.Context,
 	dockerCli *client.Client,
 	containerID, outputPath string,
+	tag statsTag,
+	phases *timeline,
+	observe func(benchmarkContainerStatsSample),
 ) (func() error, error) {
 	if err := os.MkdirAll(filepath.Dir(outputPath), 0o755); err != nil {
 		return nil, err
@@ -483,7 +966,7 @@ func startBenchmarkContainerStatsCollector(
 	collectorCtx, cancel := context.WithCancel(ctx)
 	errCh := make(chan error, 1)
 	go func() {
-		errCh <- streamBenchmarkContainerStats(collectorCtx, dockerCli, containerID, outputPath)
+		errCh <- streamBenchmarkContainerStats(collectorCtx, dockerCli, containerID, outputPath, tag, phases, observe)
 	}()
 	return func() error {
 		cancel()
@@ -499,6 +982,9 @@ func streamBenchmarkContainerStats(
 	ctx context.Context,
 	dockerCli *client.Client,
 	containerID, outputPath string,
+	tag statsTag,
+	phases *timeline,
+	observe func(benchmarkContainerStatsSample),
 ) error {
 	statsResp, err := dockerCli.ContainerStats(ctx, containerID, true)
 	if err != nil {
@@ -522,14 +1008,18 @@ func streamBenchmarkContainerStats(
 			}
 			return err
 		}
-		sample := statsSampleFromDockerPayload(containerID, payload)
+		sample := statsSampleFromDockerPayload(containerID, tag, payload)
+		sample.Phase, sample.Stage = phases.phaseAt(sample.TimestampUTC)
 		if err := encoder.Encode(sample); err != nil {
 			return err
 		}
+		if observe != nil {
+			observe(sample)
+		}
 	}
 }
 
-func statsSampleFromDockerPayload(containerID string, payload dockertypes.StatsResponse) benchmarkContainerStatsSample {
+func statsSampleFromDockerPayload(containerID string, tag statsTag, payload dockertypes.StatsResponse) benchmarkContainerStatsSample {
 	onlineCPUs := payload.CPUStats.OnlineCPUs
 	if onlineCPUs == 0 {
 		onlineCPUs = uint32(len(payload.CPUStats.CPUUsage.PercpuUsage))
@@ -543,10 +1033,17 @@ func statsSampleFromDockerPayload(containerID string, payload dockertypes.StatsR
 
 	rxBytes := uint64(0)
 	txBytes := uint64(0)
-	for _, stats := range payload.Networks {
+	var networks map[string]networkInterfaceStats
+	for name, stats := range payload.Networks {
 		rxBytes += stats.RxBytes
 		txBytes += stats.TxBytes
+		if networks == nil {
+			networks = make(map[string]networkInterfaceStats, len(payload.Networks))
+		}
+		networks[name] = networkInterfaceStatsFromDocker(stats)
 	}
+	blkio := blkioTotalsFromDocker(payload.BlkioStats)
+	memoryStat := payload.MemoryStats.Stats
 
 	cpuPercent := calcCPUPercent(payload)
 	ts := payload.Read.UTC()
@@ -557,6 +1054,8 @@ func statsSampleFromDockerPayload(containerID string, payload dockertypes.StatsR
 		TimestampUTC:     ts,
 		ContainerID:      containerID,
 		ContainerName:    strings.TrimPrefix(payload.Name, "/"),
+		Service:          tag.Service,
+		Role:             tag.Role,
 		CPUPercent:       cpuPercent,
 		MemoryUsageBytes: memoryUsage,
 		MemoryLimitBytes: memoryLimit,
@@ -570,6 +1069,27 @@ func statsSampleFromDockerPayload(containerID string, payload dockertypes.StatsR
 		TotalCPUUsage:    payload.CPUStats.CPUUsage.TotalUsage,
 		PreSystemUsage:   payload.PreCPUStats.SystemUsage,
 		PreTotalCPUUsage: payload.PreCPUStats.CPUUsage.TotalUsage,
+
+		UserCPUUsage:          payload.CPUStats.CPUUsage.UsageInUsermode,
+		KernelCPUUsage:        payload.CPUStats.CPUUsage.UsageInKernelmode,
+		CPUPeriods:            payload.CPUStats.ThrottlingData.Periods,
+		CPUThrottledPeriods:   payload.CPUStats.ThrottlingData.ThrottledPeriods,
+		CPUThrottledTimeNanos: payload.CPUStats.ThrottlingData.ThrottledTime,
+		CPUThrottledPercent:   calcThrottledPercent(payload),
+
+		BlockReadBytes:  blkio.readBytes,
+		BlockWriteBytes: blkio.writeBytes,
+		BlockReadOps:    blkio.readOps,
+		BlockWriteOps:   blkio.writeOps,
+
+		MemoryCacheBytes:      memoryStatValue(memoryStat, "cache", "file"),
+		MemoryRSSBytes:        memoryStatValue(memoryStat, "rss", "anon"),
+		MemoryPageFaults:      memoryStatValue(memoryStat, "pgfault"),
+		MemoryMajorPageFaults: memoryStatValue(memoryStat, "pgmajfault"),
+		MemoryFailcnt:         payload.MemoryStats.Failcnt,
+		MemoryStat:            memoryStat,
+
+		Networks: networks,
 	}
 }
 
@@ -589,6 +1109,18 @@ func calcCPUPercent(payload dockertypes.StatsResponse) float64 {
 	return (cpuDelta / systemDelta) * float64(onlineCPUs) * 100.0
 }
 
+// effectiveReadinessPath returns the explicit readiness path, or derives one
+// from the OpenAPI base path and the probe data.
+func (o Options) effectiveReadinessPath() string {
+	if strings.TrimSpace(o.ReadinessPath) != "" {
+		log.Printf("Using explicit readiness path override: %s", o.ReadinessPath)
+		return o.ReadinessPath
+	}
+	path := readinessPath(DeriveAPIBasePath(o.OpenAPISpecPath), firstResolvedPathFromProbeData(o.ProbeBodiesPath))
+	log.Printf("Auto-derived readiness path: %s", path)
+	return path
+}
+
 func measureFirstResponse(
 	ctx context.Context,
 	serviceName string,
@@ -722,6 +1254,66 @@ func normalizeAPIBasePath(path string) string {
 	return base
 }
 
+// entryMixTolerance is how far the share of iterations of an entry node may
+// be from its entry weight before the harness warns.
+const entryMixTolerance = 0.05
+
+// interleaveEntries orders the iterations of a stage with several entry
+// nodes by entry weight, so any prefix of them, and therefore a stage that
+// ends before it has replayed them all, has the weighted mix. Iterations are
+// matched to entry nodes by the operationId of their first step; those that
+// match none go last.
+func interleaveEntries(stageName string, stage flowgen.Stage, iterations []datagen.MinimalIteration) []datagen.MinimalIteration {
+	entries := stage.Entries()
+	if len(entries) < 2 {
+		return iterations
+	}
+	chooser, err := flowgen.NewWeightedRoundRobin(entries)
+	if err != nil {
+		log.Printf("[harness][entries] stage=%s iterations keep their order: %v", stageName, err)
+		return iterations
+	}
+	entryOf := map[string]string{}
+	for _, node := range stage.Flow {
+		if _, seen := entryOf[node.OperationID]; node.EntryNode && !seen {
+			entryOf[node.OperationID] = node.Name
+		}
+	}
+	groups := map[string][]datagen.MinimalIteration{}
+	var unmatched []datagen.MinimalIteration
+	for _, iteration := range iterations {
+		if len(iteration.Steps) > 0 {
+			if entry, ok := entryOf[iteration.Steps[0].FlowID]; ok {
+				groups[entry] = append(groups[entry], iteration)
+				continue
+			}
+		}
+		unmatched = append(unmatched, iteration)
+	}
+
+	matched := len(iterations) - len(unmatched)
+	totalWeight := 0.0
+	for _, entry := range entries {
+		totalWeight += entry.Weight
+	}
+	for _, entry := range entries {
+		share, want := float64(len(groups[entry.To]))/float64(max(matched, 1)), entry.Weight/totalWeight
+		if math.Abs(share-want) > entryMixTolerance {
+			log.Printf("[harness][entries] stage=%s entry node %q starts %.0f%% of the probed iterations, its entryweight asks for %.0f%%", stageName, entry.To, 100*share, 100*want)
+		}
+	}
+
+	ordered := make([]datagen.MinimalIteration, 0, len(iterations))
+	for len(ordered) < matched {
+		entry := chooser.Next()
+		if group := groups[entry]; len(group) > 0 {
+			ordered = append(ordered, group[0])
+			groups[entry] = group[1:]
+		}
+	}
+	return append(ordered, unmatched...)
+}
+
 func loadStageIterations(probeBodiesPath, stageName string) ([]datagen.MinimalIteration, error) {
 	stageDir := filepath.Join(probeBodiesPath, stageName)
 	if err := validateReadableDir(stageDir); err != nil {
@@ -920,6 +1512,12 @@ func getProjectNetworkName(project *types.Project) string {
 
 // findContainerIDByServiceName finds the container ID for a given service name in a Docker Compose project.
 func findContainerIDByServiceName(ctx context.Context, cli *client.Client, projectName, serviceName string) (string, error) {
+	return findServiceContainerID(ctx, cli, projectName, serviceName, false)
+}
+
+// findServiceContainerID is findContainerIDByServiceName that can also
+// return created or stopped containers.
+func findServiceContainerID(ctx context.Context, cli *client.Client, projectName, serviceName string, all bool) (string, error) {
 	filterArgs := filters.NewArgs(
 		filters.Arg("label", fmt.Sprintf("%s=%s", "com.docker.compose.project", projectName)),
 		filters.Arg("label", fmt.Sprintf("%s=%s", "com.docker.compose.service", serviceName)),
@@ -927,7 +1525,7 @@ func findContainerIDByServiceName(ctx context.Context, cli *client.Client, proje
 
 	containers, err := cli.ContainerList(ctx, dockertypes.ListOptions{
 		Filters: filterArgs,
-		All:     false,
+		All:     all,
 	})
 	if err != nil {
 		return "", fmt.Errorf("failed to list containers: %w", err)
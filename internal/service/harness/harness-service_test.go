@@ -4,40 +4,14 @@ import (
 	"os"
 	"path/filepath"
 	"slices"
+	"strings"
 	"testing"
-)
-
-const latencyArg = "--latency"
-
-func TestBuildWrk2ArgsAddsLatencyWhenMissing(t *testing.T) {
-	args := buildWrk2Args("-t2 -c100 -d30s -R2000")
-	if !slices.Contains(args, latencyArg) {
-		t.Fatalf("expected %s to be injected, got args=%v", latencyArg, args)
-	}
-	if args[len(args)-1] != latencyArg {
-		t.Fatalf("expected %s as trailing arg, got args=%v", latencyArg, args)
-	}
-}
-
-func TestBuildWrk2ArgsKeepsSingleLatencyWhenAlreadyPresent(t *testing.T) {
-	args := buildWrk2Args("-t2 -c100 --latency -d30s -R2000")
-	latencyCount := 0
-	for _, arg := range args {
-		if arg == latencyArg {
-			latencyCount++
-		}
-	}
-	if latencyCount != 1 {
-		t.Fatalf("expected one %s argument, got %d in args=%v", latencyArg, latencyCount, args)
-	}
-}
+	"time"
 
-func TestBuildWrk2ArgsEmptyInput(t *testing.T) {
-	args := buildWrk2Args("   ")
-	if args != nil {
-		t.Fatalf("expected nil args for empty wrk2 params, got %v", args)
-	}
-}
+	"github.com/d-iii-s/slsbench/internal/service/datagen"
+	"github.com/d-iii-s/slsbench/internal/service/flowgen"
+	"github.com/d-iii-s/slsbench/internal/service/summary"
+)
 
 func TestDeriveAPIBasePath_FromYAMLSpec(t *testing.T) {
 	specPath := filepath.Join(t.TempDir(), "openapi.yml")
@@ -85,3 +59,157 @@ func TestNormalizeAPIBasePath_Defaults(t *testing.T) {
 		}
 	}
 }
+
+func TestOptionsValidate_ActivatorNeedsImage(t *testing.T) {
+	dir := t.TempDir()
+	path := func(name string) string {
+		p := filepath.Join(dir, name)
+		if err := os.WriteFile(p, nil, 0o644); err != nil {
+			t.Fatal(err)
+		}
+		return p
+	}
+	opts := Options{
+		FlowPath:          path("flow.yaml"),
+		ResultPath:        dir,
+		OpenAPISpecPath:   path("openapi.yml"),
+		DockerComposePath: path("docker-compose.yml"),
+		ServiceName:       "petclinic",
+		Port:              9966,
+		ProbeBodiesPath:   dir,
+		Activator:         &ActivatorOptions{Mode: "pause"},
+	}
+	if err := opts.validate(); err == nil || !strings.Contains(err.Error(), "image") {
+		t.Fatalf("err = %v, want the activator image to be required", err)
+	}
+	opts.Activator.Image = "slsbench:dev"
+	if err := opts.validate(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+}
+
+func TestBuildStageSummary_ParsesReportAndExecutorFiles(t *testing.T) {
+	outputDir := t.TempDir()
+	for name, content := range map[string]string{
+		"flow-stats.json":   `{"iterations": 3}`,
+		"wrk_container.log": "ignored",
+		"exit_code.txt":     "0\n",
+	} {
+		if err := os.WriteFile(filepath.Join(outputDir, name), []byte(content), 0o644); err != nil {
+			t.Fatalf("failed to write %s: %v", name, err)
+		}
+	}
+	execution := &stageExecution{Stdout: "  100 requests in 10.00s, 1.00KB read\nRequests/sec:     10.00\nTransfer/sec:    102.40B\n"}
+
+	stageSummary := buildStageSummary("stage1", "-t1 -c1 -d10s -R10", flowgen.Load{Threads: 1, Connections: 1, Rate: 10, Duration: 10 * time.Second}, outputDir, execution)
+	if stageSummary.ParseError != "" || stageSummary.Wrk2 == nil || stageSummary.Wrk2.Requests != 100 {
+		t.Fatalf("expected parsed wrk2 report, got %+v", stageSummary)
+	}
+	if stageSummary.TargetRate != 10 || stageSummary.TargetSeconds != 10 {
+		t.Fatalf("unexpected targets: rate=%d duration=%d", stageSummary.TargetRate, stageSummary.TargetSeconds)
+	}
+	if _, ok := stageSummary.ExecutorStats["flow-stats.json"]; !ok || len(stageSummary.ExecutorFiles) != 0 {
+		t.Fatalf("expected only executor stats, got stats=%v files=%v", stageSummary.ExecutorStats, stageSummary.ExecutorFiles)
+	}
+}
+
+func TestBuildStageSummary_RecordsRateSegments(t *testing.T) {
+	load := flowgen.Load{Warmup: 30 * time.Second, Segments: []flowgen.RateSegment{
+		{Duration: time.Minute, From: 100, To: 300},
+		{Duration: time.Minute, Rate: 300},
+	}}
+	stageSummary := buildStageSummary("ramp", "", load, t.TempDir(), &stageExecution{})
+	want := []summary.RateSegment{{DurationSeconds: 60, FromRate: 100, ToRate: 300}, {DurationSeconds: 60, FromRate: 300, ToRate: 300}}
+	if stageSummary.TargetRate != 250 || stageSummary.TargetSeconds != 120 || stageSummary.WarmupSeconds != 30 || !slices.Equal(stageSummary.RateSegments, want) {
+		t.Fatalf("targets = %d req/s over %ds after %ds, segments %+v", stageSummary.TargetRate, stageSummary.TargetSeconds, stageSummary.WarmupSeconds, stageSummary.RateSegments)
+	}
+}
+
+func TestBuildStageSummary_RecordsParseError(t *testing.T) {
+	stageSummary := buildStageSummary("stage1", "-d10s -R10", flowgen.Load{Rate: 10, Duration: 10 * time.Second}, t.TempDir(), &stageExecution{ExitCode: 1, Stdout: "connection refused\n"})
+	if stageSummary.Wrk2 != nil || stageSummary.ParseError == "" || stageSummary.ExitCode != 1 {
+		t.Fatalf("expected a parse error, got %+v", stageSummary)
+	}
+	if stageSummary.Status != summary.StatusFailed || stageSummary.Error != "wrk2 exited with code 1" {
+		t.Fatalf("status = %q (%q), want failed", stageSummary.Status, stageSummary.Error)
+	}
+
+	guarded := buildStageSummary("stage1", "-d10s -R10", flowgen.Load{Rate: 10, Duration: 10 * time.Second}, t.TempDir(), &stageExecution{GuardReason: "non-2xx/3xx ratio 0.90 above 0.50"})
+	if guarded.Status != summary.StatusFailed || !strings.Contains(guarded.Error, "guard rail: non-2xx") {
+		t.Fatalf("status = %q (%q), want failed by the guard rail", guarded.Status, guarded.Error)
+	}
+}
+
+func TestInterleaveEntries_FollowsEntryWeights(t *testing.T) {
+	browseWeight := 2.0
+	stage := flowgen.Stage{Flow: []flowgen.FlowNode{
+		{Name: "browse", OperationID: "listOwners", EntryNode: true, EntryWeight: &browseWeight},
+		{Name: "checkout", OperationID: "addOwner", EntryNode: true},
+		{Name: "pay", OperationID: "addVisit"},
+	}}
+	iteration := func(flowIDs ...string) datagen.MinimalIteration {
+		var steps []datagen.MinimalIterationStep
+		for _, id := range flowIDs {
+			steps = append(steps, datagen.MinimalIterationStep{FlowID: id})
+		}
+		return datagen.MinimalIteration{Steps: steps}
+	}
+	// Probed in blocks, as a journey that failed often leaves them.
+	iterations := []datagen.MinimalIteration{
+		iteration("addOwner", "addVisit"), iteration("addOwner", "addVisit"), iteration("addOwner", "addVisit"),
+		iteration("listOwners"), iteration("listOwners"), iteration("listOwners"), iteration("listOwners"),
+		iteration("unknownOp"),
+	}
+	var firstIDs []string
+	for _, it := range interleaveEntries("mix", stage, iterations) {
+		firstIDs = append(firstIDs, it.Steps[0].FlowID)
+	}
+	want := []string{"listOwners", "addOwner", "listOwners", "listOwners", "addOwner", "listOwners", "addOwner", "unknownOp"}
+	if !slices.Equal(firstIDs, want) {
+		t.Fatalf("order = %v, want %v", firstIDs, want)
+	}
+}
+
+func TestOptionsValidate_RejectsRequestGuardsWithoutNativeExecutor(t *testing.T) {
+	dir := t.TempDir()
+	path := func(name, content string) string {
+		p := filepath.Join(dir, name)
+		if err := os.WriteFile(p, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		return p
+	}
+	opts := Options{
+		FlowPath: path("flow.yaml", `
+stages:
+  browse:
+    load:
+      rate: 10
+      duration: 30s
+    guards:
+      maxNon2xxRatio: 0.5
+    flow:
+      - list:
+        operationId: listItems
+        entrynode: true
+`),
+		ResultPath:        dir,
+		OpenAPISpecPath:   path("openapi.yml", ""),
+		DockerComposePath: path("docker-compose.yml", ""),
+		ServiceName:       "petclinic",
+		Port:              9966,
+		ProbeBodiesPath:   dir,
+	}
+	if err := opts.validate(); err == nil || !strings.Contains(err.Error(), "maxNon2xxRatio") {
+		t.Fatalf("err = %v, want the request guard to need the native executor", err)
+	}
+	opts.Executor = ExecutorNative
+	opts.SeriesInterval = time.Second
+	if err := opts.validate(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	opts.Executor = ExecutorWrk2Flow
+	if err := opts.validate(); err == nil || !strings.Contains(err.Error(), "series interval") {
+		t.Fatalf("err = %v, want a series to need the native executor", err)
+	}
+}
@@ -0,0 +1,241 @@
+package harness
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/d-iii-s/slsbench/internal/service/summary"
+	dockertypes "github.com/docker/docker/api/types/container"
+	"github.com/docker/docker/api/types/filters"
+	"github.com/docker/docker/client"
+)
+
+// statsTag identifies the container a stats sample belongs to.
+type statsTag struct {
+	Service string
+	Role    string
+}
+
+// composeContainer is one container of a compose project.
+type composeContainer struct {
+	ID      string
+	Service string
+	Name    string
+}
+
+// listComposeContainers returns the running containers of a compose project,
+// or all of them, ordered by service and name.
+func listComposeContainers(ctx context.Context, cli *client.Client, projectName string, all bool) ([]composeContainer, error) {
+	containers, err := cli.ContainerList(ctx, dockertypes.ListOptions{
+		All:     all,
+		Filters: filters.NewArgs(filters.Arg("label", fmt.Sprintf("%s=%s", "com.docker.compose.project", projectName))),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list containers: %w", err)
+	}
+	out := make([]composeContainer, 0, len(containers))
+	for _, c := range containers {
+		name := c.ID
+		if len(c.Names) > 0 {
+			name = strings.TrimPrefix(c.Names[0], "/")
+		}
+		out = append(out, composeContainer{ID: c.ID, Service: c.Labels["com.docker.compose.service"], Name: name})
+	}
+	sort.Slice(out, func(i, j int) bool {
+		if out[i].Service != out[j].Service {
+			return out[i].Service < out[j].Service
+		}
+		return out[i].Name < out[j].Name
+	})
+	return out, nil
+}
+
+// statsCollectors streams the stats of several containers, one JSONL file
+// each, and stops them together.
+type statsCollectors struct {
+	dockerCli *client.Client
+	phases    *timeline
+	active    []activeStatsCollector
+	// observe, when set, receives every sample of every collector.
+	observe func(benchmarkContainerStatsSample)
+}
+
+type activeStatsCollector struct {
+	containerID string
+	outputPath  string
+	stop        func() error
+}
+
+func newStatsCollectors(dockerCli *client.Client, phases *timeline) *statsCollectors {
+	return &statsCollectors{dockerCli: dockerCli, phases: phases}
+}
+
+// start begins streaming the stats of containerID into outputPath.
+func (s *statsCollectors) start(ctx context.Context, containerID, outputPath string, tag statsTag) error {
+	log.Printf("[harness][stats] streaming begin container=%s service=%s role=%s output=%s", containerID, tag.Service, tag.Role, outputPath)
+	stop, err := startBenchmarkContainerStatsCollector(ctx, s.dockerCli, containerID, outputPath, tag, s.phases, s.observe)
+	if err != nil {
+		return fmt.Errorf("failed to start stats collector for %s: %w", tag.Service, err)
+	}
+	s.active = append(s.active, activeStatsCollector{containerID: containerID, outputPath: outputPath, stop: stop})
+	return nil
+}
+
+// startDependencies streams every compose container except the benchmarked
+// one into <runDir>/container-stats/<service>.jsonl. Replicas of a service
+// get a -2, -3, ... suffix.
+func (s *statsCollectors) startDependencies(ctx context.Context, projectName, serviceContainerID, runDir string) error {
+	containers, err := listComposeContainers(ctx, s.dockerCli, projectName, false)
+	if err != nil {
+		return err
+	}
+	used := map[string]int{}
+	for _, c := range containers {
+		if c.ID == serviceContainerID {
+			continue
+		}
+		base := sanitizePathPart(c.Service)
+		used[base]++
+		fileName := base
+		if used[base] > 1 {
+			fileName = fmt.Sprintf("%s-%d", base, used[base])
+		}
+		outputPath := filepath.Join(runDir, summary.ContainerStatsDir, fileName+".jsonl")
+		if err := s.start(ctx, c.ID, outputPath, statsTag{Service: c.Service, Role: summary.RoleDependency}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// stopAll stops every collector and returns their errors joined.
+func (s *statsCollectors) stopAll() error {
+	var errs []error
+	for _, collector := range s.active {
+		if err := collector.stop(); err != nil {
+			log.Printf("[harness][stats] streaming failed container=%s error=%v", collector.containerID, err)
+			errs = append(errs, err)
+			continue
+		}
+		log.Printf("[harness][stats] streaming done container=%s output=%s", collector.containerID, collector.outputPath)
+	}
+	s.active = nil
+	return errors.Join(errs...)
+}
+
+// writeResourceSummaries aggregates the finished stats streams per stage and
+// for the idle periods into <runDir>/resources/. Containers without samples
+// in a period are left out.
+func writeResourceSummaries(runDir string, stages []summary.StageSummary) error {
+	streams, err := summary.LoadStatsStreams(runDir)
+	if err != nil {
+		return err
+	}
+	dir := filepath.Join(runDir, summary.ResourcesDir)
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return fmt.Errorf("failed to create resources directory: %w", err)
+	}
+
+	write := func(fileName string, resources summary.ResourceSummary) error {
+		resources.SchemaVersion = summary.ResourceSummarySchema
+		resources.Containers = []summary.ContainerResources{}
+		for _, stream := range streams {
+			if container := summary.SummarizeResources(stream, resources.Period, resources.Requests); container != nil {
+				resources.Containers = append(resources.Containers, *container)
+			}
+		}
+		return summary.WriteJSON(filepath.Join(dir, fileName), resources)
+	}
+	for _, stage := range stages {
+		resources := summary.ResourceSummary{Period: stage.Stage, StartedAt: stage.StartedAt, FinishedAt: stage.FinishedAt}
+		if stage.Wrk2 != nil {
+			resources.Requests = stage.Wrk2.Requests
+		}
+		if err := write(sanitizePathPart(stage.Stage)+".json", resources); err != nil {
+			return fmt.Errorf("failed to write resource summary for stage=%s: %w", stage.Stage, err)
+		}
+	}
+	if err := write(summary.IdleResourcesFile, summary.ResourceSummary{Period: summary.TimelineIdle}); err != nil {
+		return fmt.Errorf("failed to write idle resource summary: %w", err)
+	}
+	return nil
+}
+
+// networkInterfaceStats are the cumulative counters of one interface.
+type networkInterfaceStats struct {
+	RxBytes   uint64 `json:"rxBytes"`
+	RxPackets uint64 `json:"rxPackets"`
+	RxErrors  uint64 `json:"rxErrors"`
+	RxDropped uint64 `json:"rxDropped"`
+	TxBytes   uint64 `json:"txBytes"`
+	TxPackets uint64 `json:"txPackets"`
+	TxErrors  uint64 `json:"txErrors"`
+	TxDropped uint64 `json:"txDropped"`
+}
+
+func networkInterfaceStatsFromDocker(stats dockertypes.NetworkStats) networkInterfaceStats {
+	return networkInterfaceStats{
+		RxBytes:   stats.RxBytes,
+		RxPackets: stats.RxPackets,
+		RxErrors:  stats.RxErrors,
+		RxDropped: stats.RxDropped,
+		TxBytes:   stats.TxBytes,
+		TxPackets: stats.TxPackets,
+		TxErrors:  stats.TxErrors,
+		TxDropped: stats.TxDropped,
+	}
+}
+
+type blkioTotals struct {
+	readBytes, writeBytes, readOps, writeOps uint64
+}
+
+// blkioTotalsFromDocker sums the recursive block I/O counters over all
+// devices. cgroup v1 spells the operations "Read"/"Write", v2 "read"/"write".
+func blkioTotalsFromDocker(stats dockertypes.BlkioStats) blkioTotals {
+	var totals blkioTotals
+	for _, entry := range stats.IoServiceBytesRecursive {
+		switch strings.ToLower(entry.Op) {
+		case "read":
+			totals.readBytes += entry.Value
+		case "write":
+			totals.writeBytes += entry.Value
+		}
+	}
+	for _, entry := range stats.IoServicedRecursive {
+		switch strings.ToLower(entry.Op) {
+		case "read":
+			totals.readOps += entry.Value
+		case "write":
+			totals.writeOps += entry.Value
+		}
+	}
+	return totals
+}
+
+// memoryStatValue returns the first of keys present in the memory.stat map;
+// cgroup v1 and v2 name the same counters differently.
+func memoryStatValue(stat map[string]uint64, keys ...string) uint64 {
+	for _, key := range keys {
+		if value, ok := stat[key]; ok {
+			return value
+		}
+	}
+	return 0
+}
+
+// calcThrottledPercent is the share of CFS periods since the previous sample
+// in which the container was throttled.
+func calcThrottledPercent(payload dockertypes.StatsResponse) float64 {
+	current, previous := payload.CPUStats.ThrottlingData, payload.PreCPUStats.ThrottlingData
+	if current.Periods <= previous.Periods || current.ThrottledPeriods < previous.ThrottledPeriods {
+		return 0
+	}
+	return float64(current.ThrottledPeriods-previous.ThrottledPeriods) / float64(current.Periods-previous.Periods) * 100
+}
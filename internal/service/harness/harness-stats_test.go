@@ -0,0 +1,75 @@
+package harness
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/d-iii-s/slsbench/internal/service/summary"
+	dockertypes "github.com/docker/docker/api/types/container"
+)
+
+func TestStatsSampleFromDockerPayload_TagsServiceAndRole(t *testing.T) {
+	payload := dockertypes.StatsResponse{
+		Name: "/harness-1-postgres-1",
+		Read: time.Date(2026, 5, 1, 12, 0, 0, 0, time.UTC),
+	}
+	payload.MemoryStats.Usage = 512
+	payload.MemoryStats.Limit = 1024
+	payload.Networks = map[string]dockertypes.NetworkStats{
+		"eth0": {RxBytes: 10, TxBytes: 20},
+		"eth1": {RxBytes: 1, TxBytes: 2},
+	}
+
+	sample := statsSampleFromDockerPayload("abc", statsTag{Service: "postgres", Role: summary.RoleDependency}, payload)
+	if sample.ContainerName != "harness-1-postgres-1" || sample.MemoryPercent != 50 {
+		t.Fatalf("unexpected sample: %+v", sample)
+	}
+	if sample.NetworkRxBytes != 11 || sample.NetworkTxBytes != 22 {
+		t.Fatalf("network totals = %d/%d, want 11/22", sample.NetworkRxBytes, sample.NetworkTxBytes)
+	}
+
+	// The tags must survive the round trip through the JSONL stream.
+	raw, err := json.Marshal(sample)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var loaded summary.StatsSample
+	if err := json.Unmarshal(raw, &loaded); err != nil {
+		t.Fatal(err)
+	}
+	if loaded.Service != "postgres" || loaded.Role != summary.RoleDependency {
+		t.Fatalf("loaded tags = %q/%q, want postgres/%s", loaded.Service, loaded.Role, summary.RoleDependency)
+	}
+}
+
+func TestStatsSampleFromDockerPayload_CgroupBreakdown(t *testing.T) {
+	var payload dockertypes.StatsResponse
+	payload.CPUStats.ThrottlingData = dockertypes.ThrottlingData{Periods: 300, ThrottledPeriods: 60, ThrottledTime: 5e9}
+	payload.PreCPUStats.ThrottlingData = dockertypes.ThrottlingData{Periods: 200, ThrottledPeriods: 35}
+	payload.BlkioStats.IoServiceBytesRecursive = []dockertypes.BlkioStatEntry{
+		{Major: 8, Op: "Read", Value: 100},
+		{Major: 8, Op: "Write", Value: 40},
+		{Major: 259, Op: "read", Value: 5},
+		{Major: 259, Op: "Total", Value: 145},
+	}
+	payload.BlkioStats.IoServicedRecursive = []dockertypes.BlkioStatEntry{{Op: "write", Value: 3}}
+	// cgroup v2 names.
+	payload.MemoryStats.Stats = map[string]uint64{"file": 10, "anon": 20, "pgfault": 7, "pgmajfault": 1}
+	payload.MemoryStats.Failcnt = 2
+	payload.Networks = map[string]dockertypes.NetworkStats{"eth0": {RxBytes: 10, RxDropped: 1, TxPackets: 4}}
+
+	sample := statsSampleFromDockerPayload("abc", statsTag{}, payload)
+	if sample.CPUThrottledPercent != 25 || sample.CPUThrottledTimeNanos != 5e9 {
+		t.Fatalf("throttled percent/time = %v/%d, want 25/5e9", sample.CPUThrottledPercent, sample.CPUThrottledTimeNanos)
+	}
+	if sample.BlockReadBytes != 105 || sample.BlockWriteBytes != 40 || sample.BlockWriteOps != 3 {
+		t.Fatalf("blkio read/write/ops = %d/%d/%d", sample.BlockReadBytes, sample.BlockWriteBytes, sample.BlockWriteOps)
+	}
+	if sample.MemoryCacheBytes != 10 || sample.MemoryRSSBytes != 20 || sample.MemoryPageFaults != 7 || sample.MemoryMajorPageFaults != 1 || sample.MemoryFailcnt != 2 {
+		t.Fatalf("unexpected memory breakdown: %+v", sample)
+	}
+	if eth0 := sample.Networks["eth0"]; eth0.RxBytes != 10 || eth0.RxDropped != 1 || eth0.TxPackets != 4 {
+		t.Fatalf("unexpected interface counters: %+v", sample.Networks)
+	}
+}
@@ -0,0 +1,137 @@
+package harness
+
+import (
+	"log"
+	"math/rand/v2"
+	"sync"
+	"time"
+
+	"github.com/d-iii-s/slsbench/internal/service/datagen"
+	"github.com/d-iii-s/slsbench/internal/service/flowgen"
+	"github.com/d-iii-s/slsbench/internal/service/summary"
+)
+
+// thinkTimeRateTolerance is how far below the target rate the offered rate
+// of a stage may be estimated before the harness warns.
+const thinkTimeRateTolerance = 0.95
+
+// applyThinkTimes sets the think time of every step after the first of an
+// iteration from the edge between the operations of the two steps, or the
+// stage think time.
+func applyThinkTimes(stage flowgen.Stage, iterations []datagen.MinimalIteration) {
+	if !stage.HasThinkTime() {
+		return
+	}
+	for _, iteration := range iterations {
+		for i := 1; i < len(iteration.Steps); i++ {
+			thinkTime := stage.ThinkTimeBefore(iteration.Steps[i-1].FlowID, iteration.Steps[i].FlowID)
+			if thinkTime == nil {
+				continue
+			}
+			iteration.Steps[i].ThinkTime = &datagen.StepThinkTime{
+				Distribution: thinkTime.Distribution,
+				MeanMillis:   millis(thinkTime.Mean),
+				MinMillis:    millis(thinkTime.Min),
+				MaxMillis:    millis(thinkTime.Max),
+			}
+		}
+	}
+}
+
+// thinkTimeSeed returns the seed of the stage think time, or a new one that
+// is logged so the run can be repeated.
+func thinkTimeSeed(stageName string, stage flowgen.Stage) int64 {
+	if stage.ThinkTime != nil && stage.ThinkTime.Seed != 0 {
+		return stage.ThinkTime.Seed
+	}
+	seed := time.Now().UnixNano()
+	log.Printf("[harness][thinktime] stage=%s drawing think times with seed=%d", stageName, seed)
+	return seed
+}
+
+// newThinkTimeRand returns the random source of one connection.
+func newThinkTimeRand(seed int64, connection int) *rand.Rand {
+	return rand.New(rand.NewPCG(uint64(seed), uint64(connection)))
+}
+
+// drawThinkTime draws one pause from the distribution of a step.
+func drawThinkTime(thinkTime *datagen.StepThinkTime, rng *rand.Rand) time.Duration {
+	if thinkTime == nil {
+		return 0
+	}
+	var pause float64
+	switch thinkTime.Distribution {
+	case flowgen.ThinkTimeUniform:
+		pause = thinkTime.MinMillis + rng.Float64()*(thinkTime.MaxMillis-thinkTime.MinMillis)
+	case flowgen.ThinkTimeExponential:
+		pause = rng.ExpFloat64() * thinkTime.MeanMillis
+	default:
+		pause = thinkTime.MeanMillis
+	}
+	return time.Duration(pause * float64(time.Millisecond))
+}
+
+// estimateThinkTime estimates the pacing of a stage from one pass over its
+// iterations: a connection sends a step no sooner than one connections/rate
+// interval after the previous one, nor before the drawn pause has passed.
+// Response times are left out, so the offered rate is an upper bound. The
+// harness checks it before the stage runs.
+func estimateThinkTime(iterations []datagen.MinimalIteration, rate, connections int, seed int64) *summary.ThinkTimeSummary {
+	result := &summary.ThinkTimeSummary{Seed: seed}
+	if rate <= 0 || connections <= 0 {
+		return result
+	}
+	interval := time.Duration(float64(time.Second) * float64(connections) / float64(rate))
+	rng := newThinkTimeRand(seed, 0)
+	var steps int64
+	var busy, paused time.Duration
+	for _, iteration := range iterations {
+		for _, step := range iteration.Steps {
+			steps++
+			if step.ThinkTime == nil {
+				busy += interval
+				continue
+			}
+			pause := drawThinkTime(step.ThinkTime, rng)
+			result.Pauses++
+			paused += pause
+			busy += max(interval, pause)
+		}
+	}
+	if result.Pauses > 0 {
+		result.MeanMillis = millis(paused) / float64(result.Pauses)
+	}
+	if busy > 0 {
+		result.OfferedRequestsPerSecond = float64(connections) * float64(steps) / busy.Seconds()
+	}
+	return result
+}
+
+// thinkTimeRecorder collects the pauses the native executor took.
+type thinkTimeRecorder struct {
+	mu     sync.Mutex
+	pauses int64
+	total  time.Duration
+}
+
+func (r *thinkTimeRecorder) record(pause time.Duration) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.pauses++
+	r.total += pause
+}
+
+// summary reports the pauses and the rate requests were due at over the
+// stage.
+func (r *thinkTimeRecorder) summary(seed, requests int64, elapsed time.Duration) *summary.ThinkTimeSummary {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	result := &summary.ThinkTimeSummary{Seed: seed, Pauses: r.pauses}
+	if r.pauses > 0 {
+		result.MeanMillis = millis(r.total) / float64(r.pauses)
+	}
+	if elapsed > 0 {
+		result.OfferedRequestsPerSecond = float64(requests) / elapsed.Seconds()
+	}
+	return result
+}
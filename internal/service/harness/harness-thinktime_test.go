@@ -0,0 +1,101 @@
+package harness
+
+import (
+	"context"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/d-iii-s/slsbench/internal/service/datagen"
+	"github.com/d-iii-s/slsbench/internal/service/flowgen"
+	"github.com/d-iii-s/slsbench/internal/service/summary"
+)
+
+func TestApplyThinkTimes_EdgeOverridesStage(t *testing.T) {
+	stage := flowgen.Stage{
+		ThinkTime: &flowgen.ThinkTime{Distribution: flowgen.ThinkTimeConstant, Mean: 100 * time.Millisecond},
+		Flow: []flowgen.FlowNode{
+			{Name: "list", OperationID: "listItems", EntryNode: true, Edges: []flowgen.Edge{{
+				To:        "detail",
+				Weight:    1,
+				ThinkTime: &flowgen.ThinkTime{Distribution: flowgen.ThinkTimeUniform, Min: time.Second, Max: 2 * time.Second},
+			}}},
+			{Name: "detail", OperationID: "getItem", Edges: []flowgen.Edge{{To: "list", Weight: 1}}},
+		},
+	}
+	iterations := []datagen.MinimalIteration{{Steps: []datagen.MinimalIterationStep{
+		{FlowID: "listItems"}, {FlowID: "getItem"}, {FlowID: "listItems"},
+	}}}
+	applyThinkTimes(stage, iterations)
+	steps := iterations[0].Steps
+	if steps[0].ThinkTime != nil {
+		t.Fatalf("the first step of an iteration must not pause, got %+v", steps[0].ThinkTime)
+	}
+	if got := steps[1].ThinkTime; got == nil || got.Distribution != flowgen.ThinkTimeUniform || got.MinMillis != 1000 || got.MaxMillis != 2000 {
+		t.Fatalf("edge think time = %+v", got)
+	}
+	if got := steps[2].ThinkTime; got == nil || got.Distribution != flowgen.ThinkTimeConstant || got.MeanMillis != 100 {
+		t.Fatalf("stage think time = %+v", got)
+	}
+
+	first, second := newThinkTimeRand(42, 1), newThinkTimeRand(42, 1)
+	for range 100 {
+		a, b := drawThinkTime(steps[1].ThinkTime, first), drawThinkTime(steps[1].ThinkTime, second)
+		if a != b || a < time.Second || a > 2*time.Second {
+			t.Fatalf("uniform draws %s and %s, want equal values in [1s, 2s]", a, b)
+		}
+	}
+
+	// One 20 ms slot for the first step and a 100 ms pause for the second.
+	estimate := estimateThinkTime([]datagen.MinimalIteration{{Steps: steps[1:]}}, 100, 2, 42)
+	if estimate.Pauses != 2 || estimate.Seed != 42 {
+		t.Fatalf("estimate = %+v", estimate)
+	}
+	steps[1].ThinkTime = nil
+	estimate = estimateThinkTime([]datagen.MinimalIteration{{Steps: steps[1:]}}, 100, 2, 42)
+	if math.Abs(estimate.OfferedRequestsPerSecond-2*2/0.12) > 1e-6 || estimate.MeanMillis != 100 {
+		t.Fatalf("estimate = %+v, want %.2f req/s with a 100 ms mean pause", estimate, 2*2/0.12)
+	}
+}
+
+func TestNativeExecutor_PausesBetweenSteps(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	pause := &datagen.StepThinkTime{Distribution: flowgen.ThinkTimeConstant, MeanMillis: 200}
+	iterations := []datagen.MinimalIteration{{Steps: []datagen.MinimalIterationStep{
+		{FlowID: "listItems", Method: "GET", ResolvedPath: "/items"},
+		{FlowID: "getItem", Method: "GET", ResolvedPath: "/items/1", ThinkTime: pause},
+	}}}
+	exec := &nativeExecutor{baseURL: server.URL}
+	execution, err := exec.run(context.Background(), stageRun{
+		name:          "browse",
+		load:          flowgen.Load{Threads: 1, Connections: 1, Rate: 100, Duration: time.Second},
+		iterations:    iterations,
+		outputDir:     t.TempDir(),
+		thinkTime:     true,
+		thinkTimeSeed: 7,
+	})
+	if err != nil {
+		t.Fatalf("run: %v", err)
+	}
+	thinkTime := execution.ThinkTime
+	if thinkTime == nil || thinkTime.Seed != 7 || thinkTime.Pauses < 3 || thinkTime.MeanMillis != 200 {
+		t.Fatalf("think time = %+v, want about 5 pauses of 200 ms", thinkTime)
+	}
+	// About one iteration of two requests per 210 ms instead of -R 100.
+	if thinkTime.OfferedRequestsPerSecond > 20 {
+		t.Fatalf("offered %.1f req/s, think time must hold the connection back", thinkTime.OfferedRequestsPerSecond)
+	}
+	result, err := summary.ParseWrk2Output(execution.Stdout)
+	if err != nil {
+		t.Fatalf("report not parsed: %v", err)
+	}
+	if p99, ok := result.Latency.Percentile(99); !ok || p99 >= 150 {
+		t.Fatalf("p99 = %v ms, the pause must not count as latency", p99)
+	}
+}
@@ -0,0 +1,79 @@
+package harness
+
+import (
+	"sync"
+	"time"
+
+	"github.com/d-iii-s/slsbench/internal/service/summary"
+)
+
+// timeline records the phases of a run while it happens. The stats
+// collectors read it concurrently to tag every sample with the phase and
+// stage it was taken in. A nil timeline records nothing.
+type timeline struct {
+	mu      sync.Mutex
+	entries []summary.TimelineEntry
+}
+
+func newTimeline() *timeline {
+	return &timeline{}
+}
+
+// add appends phases that already finished, e.g. those of a compose session.
+func (t *timeline) add(entries ...summary.TimelineEntry) {
+	if t == nil {
+		return
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.entries = append(t.entries, entries...)
+}
+
+// begin opens a phase now. The returned function closes it; err, when not
+// nil, is recorded on the entry.
+func (t *timeline) begin(phase, stage string) func(err error) {
+	if t == nil {
+		return func(error) {}
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	index := len(t.entries)
+	t.entries = append(t.entries, summary.TimelineEntry{Phase: phase, Stage: stage, StartedAt: time.Now().UTC()})
+	return func(err error) {
+		t.mu.Lock()
+		defer t.mu.Unlock()
+		t.entries[index].FinishedAt = time.Now().UTC()
+		if err != nil {
+			t.entries[index].Error = err.Error()
+		}
+	}
+}
+
+// phaseAt returns the phase and stage active at ts. The latest phase that
+// covers ts wins; an open phase covers everything after its start.
+func (t *timeline) phaseAt(ts time.Time) (string, string) {
+	if t == nil {
+		return "", ""
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	for i := len(t.entries) - 1; i >= 0; i-- {
+		entry := t.entries[i]
+		if ts.Before(entry.StartedAt) {
+			continue
+		}
+		if entry.FinishedAt.IsZero() || !ts.After(entry.FinishedAt) {
+			return entry.Phase, entry.Stage
+		}
+	}
+	return summary.TimelineIdle, ""
+}
+
+// snapshot returns the recorded phases as a timeline.json document.
+func (t *timeline) snapshot() summary.Timeline {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	phases := make([]summary.TimelineEntry, len(t.entries))
+	copy(phases, t.entries)
+	return summary.Timeline{SchemaVersion: summary.TimelineSchema, Phases: phases}
+}
@@ -0,0 +1,53 @@
+package harness
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/d-iii-s/slsbench/internal/service/summary"
+)
+
+func TestTimeline_PhaseAt(t *testing.T) {
+	base := time.Date(2026, 5, 1, 12, 0, 0, 0, time.UTC)
+	phases := newTimeline()
+	phases.add(
+		summary.TimelineEntry{Phase: summary.TimelineComposeStart, StartedAt: base, FinishedAt: base.Add(2 * time.Second)},
+		summary.TimelineEntry{Phase: summary.TimelineStage, Stage: "warmup", StartedAt: base.Add(5 * time.Second), FinishedAt: base.Add(10 * time.Second)},
+	)
+
+	cases := []struct {
+		at    time.Duration
+		phase string
+		stage string
+	}{
+		{1 * time.Second, summary.TimelineComposeStart, ""},
+		{3 * time.Second, summary.TimelineIdle, ""},
+		{10 * time.Second, summary.TimelineStage, "warmup"},
+		{11 * time.Second, summary.TimelineIdle, ""},
+	}
+	for _, tc := range cases {
+		phase, stage := phases.phaseAt(base.Add(tc.at))
+		if phase != tc.phase || stage != tc.stage {
+			t.Fatalf("phaseAt(+%s) = %q/%q, want %q/%q", tc.at, phase, stage, tc.phase, tc.stage)
+		}
+	}
+
+	// An open phase covers everything after its start.
+	end := phases.begin(summary.TimelineStage, "soak")
+	if phase, stage := phases.phaseAt(time.Now().Add(time.Hour)); phase != summary.TimelineStage || stage != "soak" {
+		t.Fatalf("open phase not active: %q/%q", phase, stage)
+	}
+	end(errors.New("wrk2 exited"))
+	snapshot := phases.snapshot()
+	last := snapshot.Phases[len(snapshot.Phases)-1]
+	if snapshot.SchemaVersion != summary.TimelineSchema || last.FinishedAt.IsZero() || last.Error != "wrk2 exited" {
+		t.Fatalf("unexpected closed phase: %+v", last)
+	}
+
+	var none *timeline
+	if phase, stage := none.phaseAt(base); phase != "" || stage != "" {
+		t.Fatalf("nil timeline tagged %q/%q", phase, stage)
+	}
+	none.begin(summary.TimelineStage, "x")(nil)
+}
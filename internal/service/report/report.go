@@ -0,0 +1,282 @@
+// Package report renders a harness result directory into a single
+// self-contained HTML file: run parameters, first-response time, per-stage
+// latency percentile curves, throughput and latency per interval, and the
+// service container resource time series with stage boundaries marked.
+// Charts are inline SVG, so the file opens offline and can be attached to
+// reviews as is.
+package report
+
+import (
+	_ "embed"
+	"fmt"
+	"html/template"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/d-iii-s/slsbench/internal/service/summary"
+	"github.com/d-iii-s/slsbench/internal/utils"
+)
+
+// DefaultFileName is written into the result directory unless another output
+// path is given.
+const DefaultFileName = "report.html"
+
+//go:embed report.html.tmpl
+var reportTemplateText string
+
+var reportTemplate = template.Must(template.New("report").Funcs(template.FuncMap{
+	"ms":    func(v float64) string { return fmt.Sprintf("%.2f", v) },
+	"bytes": formatBytes,
+	"join":  strings.Join,
+}).Parse(reportTemplateText))
+
+// reportPercentiles are the columns of the per-stage latency table.
+var reportPercentiles = []float64{50, 90, 99, 99.9}
+
+// Generate renders the report for the harness result directory runDir and
+// returns the path it was written to. An empty outputPath writes
+// report.html into the result directory. runDir may also be a `run` command
+// directory, in which case its latest harness result is used.
+func Generate(runDir, outputPath string) (string, error) {
+	harnessDir, err := ResolveHarnessDir(runDir)
+	if err != nil {
+		return "", err
+	}
+	run, err := summary.LoadRunDir(harnessDir)
+	if err != nil {
+		return "", err
+	}
+	samples, err := summary.LoadStatsSamples(filepath.Join(harnessDir, summary.StatsFile))
+	if err != nil {
+		return "", err
+	}
+	stageSeries, err := summary.LoadSeries(harnessDir)
+	if err != nil {
+		return "", err
+	}
+
+	if strings.TrimSpace(outputPath) == "" {
+		outputPath = filepath.Join(harnessDir, DefaultFileName)
+	}
+	file, err := os.Create(outputPath)
+	if err != nil {
+		return "", fmt.Errorf("failed to create report %q: %w", outputPath, err)
+	}
+	defer file.Close()
+
+	view := buildView(harnessDir, run, samples, stageSeries)
+	if err := reportTemplate.Execute(file, view); err != nil {
+		return "", fmt.Errorf("failed to render report: %w", err)
+	}
+	return outputPath, nil
+}
+
+// ResolveHarnessDir returns dir when it is a harness result directory, or the
+// latest harness-result-* directory inside it (the layout of `run`).
+func ResolveHarnessDir(dir string) (string, error) {
+	if info, err := os.Stat(dir); err != nil || !info.IsDir() {
+		return "", fmt.Errorf("result directory %q does not exist", dir)
+	}
+	if summary.IsRunDir(dir) {
+		return dir, nil
+	}
+	nested, err := utils.LatestResultSubdir(dir, "harness-result")
+	if err != nil || !summary.IsRunDir(nested) {
+		return "", fmt.Errorf("%q is not a harness result directory", dir)
+	}
+	return nested, nil
+}
+
+type reportView struct {
+	Title        string
+	GeneratedAt  time.Time
+	Dir          string
+	Run          *summary.RunSummary
+	Percentiles  []float64
+	Stages       []stageRow
+	LatencyChart template.HTML
+	CPUChart     template.HTML
+	MemoryChart  template.HTML
+	NetworkChart template.HTML
+	// ThroughputChart and IntervalLatencyChart plot the stage time series
+	// on the time axis of the resource charts.
+	ThroughputChart      template.HTML
+	IntervalLatencyChart template.HTML
+	SeriesCount          int
+	SampleCount          int
+	PeakCPU              float64
+	PeakMemory           uint64
+	StatsDuration        float64
+}
+
+type stageRow struct {
+	Name          string
+	Color         string
+	Summary       summary.StageSummary
+	Percentiles   []string
+	DurationLabel string
+	// SteadyLabel is the time to steady state, "not reached" or empty.
+	SteadyLabel string
+}
+
+func buildView(dir string, run *summary.RunSummary, samples []summary.StatsSample, stageSeries []summary.SeriesSample) reportView {
+	view := reportView{
+		Title:       "slsbench report — " + filepath.Base(dir),
+		GeneratedAt: time.Now().UTC(),
+		Dir:         dir,
+		Run:         run,
+		Percentiles: reportPercentiles,
+		SampleCount: len(samples),
+		SeriesCount: len(stageSeries),
+	}
+
+	latency := lineChart{
+		Title:  "Latency by percentile (corrected for coordinated omission)",
+		XLabel: "percentile",
+		YLabel: "latency (ms)",
+		XTicks: percentileTicks,
+	}
+	for i, stage := range run.Stages {
+		color := palette[i%len(palette)]
+		row := stageRow{Name: stage.Stage, Color: color, Summary: stage}
+		if !stage.StartedAt.IsZero() && !stage.FinishedAt.IsZero() {
+			row.DurationLabel = stage.FinishedAt.Sub(stage.StartedAt).Round(time.Millisecond).String()
+		}
+		if steady := stage.SteadyState; steady != nil {
+			row.SteadyLabel = "not reached"
+			if steady.Undetermined {
+				row.SteadyLabel = "undetermined"
+			} else if steady.Detected {
+				row.SteadyLabel = fmt.Sprintf("%.1f s", steady.TimeToSteadyStateSeconds)
+			}
+		}
+		if stage.Wrk2 != nil {
+			for _, p := range reportPercentiles {
+				if v, ok := stage.Wrk2.Latency.Percentile(p); ok {
+					row.Percentiles = append(row.Percentiles, fmt.Sprintf("%.2f", v))
+				} else {
+					row.Percentiles = append(row.Percentiles, "—")
+				}
+			}
+			latency.Series = append(latency.Series, series{Name: stage.Stage, Color: color, Points: latencyCurve(&stage.Wrk2.Latency)})
+		}
+		view.Stages = append(view.Stages, row)
+	}
+	view.LatencyChart = latency.SVG()
+
+	var origin time.Time
+	if len(samples) > 0 {
+		origin = samples[0].TimestampUTC
+		view.StatsDuration = samples[len(samples)-1].TimestampUTC.Sub(origin).Seconds()
+	} else if len(stageSeries) > 0 {
+		origin = stageSeries[0].TimestampUTC
+	}
+	var bands []band
+	for i, stage := range run.Stages {
+		if origin.IsZero() || stage.StartedAt.IsZero() || stage.FinishedAt.IsZero() {
+			continue
+		}
+		bands = append(bands, band{
+			From:  stage.StartedAt.Sub(origin).Seconds(),
+			To:    stage.FinishedAt.Sub(origin).Seconds(),
+			Label: stage.Stage,
+			Color: palette[i%len(palette)],
+		})
+	}
+
+	cpu := series{Name: "CPU %", Color: palette[0]}
+	memory := series{Name: "memory usage (MiB)", Color: palette[1]}
+	memoryLimit := series{Name: "memory limit (MiB)", Color: palette[7]}
+	rx := series{Name: "rx (KiB/s)", Color: palette[2]}
+	tx := series{Name: "tx (KiB/s)", Color: palette[3]}
+	for i, sample := range samples {
+		x := sample.TimestampUTC.Sub(origin).Seconds()
+		cpu.Points = append(cpu.Points, point{x, sample.CPUPercent})
+		memory.Points = append(memory.Points, point{x, float64(sample.MemoryUsageBytes) / (1 << 20)})
+		if sample.MemoryLimitBytes > 0 && sample.MemoryLimitBytes < 1<<50 {
+			memoryLimit.Points = append(memoryLimit.Points, point{x, float64(sample.MemoryLimitBytes) / (1 << 20)})
+		}
+		view.PeakCPU = max(view.PeakCPU, sample.CPUPercent)
+		view.PeakMemory = max(view.PeakMemory, sample.MemoryUsageBytes)
+		if i == 0 {
+			continue
+		}
+		prev := samples[i-1]
+		elapsed := sample.TimestampUTC.Sub(prev.TimestampUTC).Seconds()
+		// Counters restart with the container; skip those intervals.
+		if elapsed <= 0 || sample.NetworkRxBytes < prev.NetworkRxBytes || sample.NetworkTxBytes < prev.NetworkTxBytes {
+			continue
+		}
+		rx.Points = append(rx.Points, point{x, float64(sample.NetworkRxBytes-prev.NetworkRxBytes) / elapsed / 1024})
+		tx.Points = append(tx.Points, point{x, float64(sample.NetworkTxBytes-prev.NetworkTxBytes) / elapsed / 1024})
+	}
+	// A limit far above the usage would flatten the usage curve.
+	if len(memoryLimit.Points) > 0 && memoryLimit.Points[0].Y > 4*float64(view.PeakMemory)/(1<<20) {
+		memoryLimit.Points = nil
+	}
+
+	// One line per stage; a point sits at the end of its interval.
+	stageIndex := map[string]int{}
+	throughput := make([]series, len(run.Stages))
+	p99 := make([]series, len(run.Stages))
+	for i, stage := range run.Stages {
+		stageIndex[stage.Stage] = i
+		throughput[i] = series{Name: stage.Stage, Color: palette[i%len(palette)]}
+		p99[i] = series{Name: stage.Stage + " p99", Color: palette[i%len(palette)]}
+	}
+	for _, sample := range stageSeries {
+		i, ok := stageIndex[sample.Stage]
+		if !ok {
+			continue
+		}
+		x := sample.TimestampUTC.Sub(origin).Seconds() + sample.DurationSeconds
+		throughput[i].Points = append(throughput[i].Points, point{x, sample.RequestsPerSecond})
+		if sample.Latency != nil {
+			p99[i].Points = append(p99[i].Points, point{x, sample.Latency.P99Millis})
+		}
+	}
+	view.ThroughputChart = lineChart{Title: "Throughput per interval", XLabel: "seconds since first sample", YLabel: "requests/s", Series: throughput, Bands: bands}.SVG()
+	view.IntervalLatencyChart = lineChart{Title: "p99 latency per interval", XLabel: "seconds since first sample", YLabel: "latency (ms)", Series: p99, Bands: bands}.SVG()
+
+	view.CPUChart = lineChart{Title: "Service CPU", XLabel: "seconds since first sample", YLabel: "CPU %", Series: []series{cpu}, Bands: bands}.SVG()
+	view.MemoryChart = lineChart{Title: "Service memory", XLabel: "seconds since first sample", YLabel: "MiB", Series: []series{memory, memoryLimit}, Bands: bands}.SVG()
+	view.NetworkChart = lineChart{Title: "Service network throughput", XLabel: "seconds since first sample", YLabel: "KiB/s", Series: []series{rx, tx}, Bands: bands}.SVG()
+	return view
+}
+
+// latencyCurve prefers the HdrHistogram spectrum and falls back to the
+// coarse distribution block.
+func latencyCurve(latency *summary.LatencySummary) []point {
+	var points []point
+	if len(latency.Spectrum) > 0 {
+		for _, row := range latency.Spectrum {
+			points = append(points, point{percentileX(row.Quantile), row.LatencyMillis})
+		}
+		return points
+	}
+	for _, entry := range latency.Percentiles {
+		points = append(points, point{percentileX(entry.Percentile / 100), entry.LatencyMillis})
+	}
+	return points
+}
+
+func formatBytes(v any) string {
+	var f float64
+	switch n := v.(type) {
+	case int64:
+		f = float64(n)
+	case uint64:
+		f = float64(n)
+	case float64:
+		f = n
+	}
+	units := []string{"B", "KiB", "MiB", "GiB", "TiB"}
+	i := 0
+	for f >= 1024 && i < len(units)-1 {
+		f /= 1024
+		i++
+	}
+	return fmt.Sprintf("%.2f %s", f, units[i])
+}
@@ -0,0 +1,121 @@
+package report
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/d-iii-s/slsbench/internal/service/summary"
+)
+
+func writeHarnessDir(t *testing.T, dir string) {
+	t.Helper()
+	start := time.Date(2026, 5, 4, 10, 0, 0, 0, time.UTC)
+	run := summary.RunSummary{
+		SchemaVersion: summary.RunSummarySchema,
+		Status:        summary.StatusCompleted,
+		StartedAt:     start,
+		FinishedAt:    start.Add(40 * time.Second),
+		FlowPath:      "flow.yaml",
+		ServiceName:   "petclinic",
+		Port:          9966,
+		FirstResponse: &summary.FirstResponse{TargetURL: "http://localhost:9966/", DurationMillis: 1834, Attempts: 9, StatusCode: 200},
+		Stages: []summary.StageSummary{{
+			SchemaVersion: summary.StageSummarySchema,
+			Stage:         "browse<&>",
+			Wrk2Params:    "-t1 -c4 -d10s -R100",
+			TargetRate:    100,
+			StartedAt:     start.Add(5 * time.Second),
+			FinishedAt:    start.Add(15 * time.Second),
+			SteadyState:   &summary.SteadyState{Detector: summary.DetectorMSER5, Detected: true, TimeToSteadyStateSeconds: 3},
+			Wrk2: &summary.Wrk2Result{
+				Requests:          1000,
+				RequestsPerSecond: 99.8,
+				Latency: summary.LatencySummary{
+					MaxMillis:   12.5,
+					Percentiles: []summary.Percentile{{Percentile: 50, LatencyMillis: 1.2}, {Percentile: 99, LatencyMillis: 7.7}},
+					Spectrum:    []summary.SpectrumRow{{LatencyMillis: 0.4, Quantile: 0}, {LatencyMillis: 1.2, Quantile: 0.5}, {LatencyMillis: 12.5, Quantile: 1}},
+				},
+			},
+		}},
+	}
+	if err := summary.WriteJSON(filepath.Join(dir, summary.RunSummaryFile), run); err != nil {
+		t.Fatalf("failed to write run summary: %v", err)
+	}
+	stats := ""
+	for i := 0; i < 20; i++ {
+		ts := start.Add(time.Duration(i*2) * time.Second).Format(time.RFC3339Nano)
+		stats += `{"timestampUtc":"` + ts + `","cpuPercent":` + []string{"12.5", "80"}[i%2] + `,"memoryUsageBytes":104857600,"networkRxBytes":` + string(rune('1'+i%9)) + `000,"networkTxBytes":1000}` + "\n"
+	}
+	stats += "{truncated\n"
+	stageDir := filepath.Join(dir, "wrk2-results", "browse")
+	if err := os.MkdirAll(stageDir, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	series := ""
+	for i := 0; i < 10; i++ {
+		ts := start.Add(time.Duration(5+i) * time.Second).Format(time.RFC3339Nano)
+		series += `{"timestampUtc":"` + ts + `","stage":"browse<&>","durationSeconds":1,"requests":100,"requestsPerSecond":100,"latency":{"p50Millis":1.2,"p90Millis":3,"p99Millis":7.7,"maxMillis":12.5}}` + "\n"
+	}
+	if err := os.WriteFile(filepath.Join(stageDir, summary.SeriesFile), []byte(series), 0o644); err != nil {
+		t.Fatalf("failed to write series: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, summary.StatsFile), []byte(stats), 0o644); err != nil {
+		t.Fatalf("failed to write stats: %v", err)
+	}
+}
+
+func TestGenerate_WritesSelfContainedReport(t *testing.T) {
+	dir := t.TempDir()
+	writeHarnessDir(t, dir)
+
+	path, err := Generate(dir, "")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if path != filepath.Join(dir, DefaultFileName) {
+		t.Fatalf("unexpected report path %q", path)
+	}
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("failed to read report: %v", err)
+	}
+	html := string(raw)
+	for _, want := range []string{"1834 ms", "petclinic:9966", "browse&lt;&amp;&gt;", "7.70", "<polyline", "Service CPU", "peak memory 100.00 MiB", "Throughput per interval", "10 intervals", "<td>3.0 s</td>"} {
+		if !strings.Contains(html, want) {
+			t.Errorf("report does not contain %q", want)
+		}
+	}
+	for _, unwanted := range []string{"<script", "<link", "ZgotmplZ", "browse<&>"} {
+		if strings.Contains(html, unwanted) {
+			t.Errorf("report unexpectedly contains %q", unwanted)
+		}
+	}
+}
+
+func TestResolveHarnessDir_FindsNestedHarnessResult(t *testing.T) {
+	runDir := t.TempDir()
+	nested := filepath.Join(runDir, "harness-result-2026-05-04-10:00:00")
+	if err := os.MkdirAll(filepath.Join(nested, "wrk2-results"), 0o755); err != nil {
+		t.Fatalf("failed to create harness dir: %v", err)
+	}
+	got, err := ResolveHarnessDir(runDir)
+	if err != nil || got != nested {
+		t.Fatalf("ResolveHarnessDir = %q, %v; want %q", got, err, nested)
+	}
+	if _, err := ResolveHarnessDir(t.TempDir()); err == nil {
+		t.Fatalf("expected an error for an empty directory")
+	}
+}
+
+func TestNiceTicks_CoversRange(t *testing.T) {
+	ticks := niceTicks(0, 87, 5)
+	if ticks[0].Value != 0 || ticks[len(ticks)-1].Value < 87 {
+		t.Fatalf("ticks do not cover range: %+v", ticks)
+	}
+	if ticks[1].Value != 20 {
+		t.Fatalf("expected a step of 20, got %+v", ticks)
+	}
+}
@@ -0,0 +1,205 @@
+package report
+
+import (
+	"fmt"
+	"html"
+	"html/template"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// palette is used for series in order; stages keep their color across
+// charts because they are always added in execution order.
+var palette = []string{"#1f77b4", "#ff7f0e", "#2ca02c", "#d62728", "#9467bd", "#8c564b", "#e377c2", "#7f7f7f", "#bcbd22", "#17becf"}
+
+const (
+	chartWidth   = 860
+	chartHeight  = 300
+	marginLeft   = 70
+	marginRight  = 20
+	marginTop    = 30
+	marginBottom = 45
+)
+
+type point struct {
+	X, Y float64
+}
+
+type series struct {
+	Name   string
+	Color  string
+	Points []point
+}
+
+// band is a shaded x range, used to mark stage boundaries on time series.
+type band struct {
+	From, To float64
+	Label    string
+	Color    string
+}
+
+type tick struct {
+	Value float64
+	Label string
+}
+
+// lineChart is a minimal server-side SVG line chart, so reports render
+// offline without any JavaScript.
+type lineChart struct {
+	Title  string
+	XLabel string
+	YLabel string
+	Series []series
+	Bands  []band
+	// XTicks overrides the automatically computed x axis ticks.
+	XTicks []tick
+}
+
+// SVG renders the chart. A chart without data renders a placeholder.
+func (c lineChart) SVG() template.HTML {
+	var b strings.Builder
+	fmt.Fprintf(&b, `<svg xmlns="http://www.w3.org/2000/svg" viewBox="0 0 %d %d" class="chart" role="img" aria-label="%s">`, chartWidth, chartHeight, html.EscapeString(c.Title))
+	fmt.Fprintf(&b, `<text x="%d" y="18" class="chart-title">%s</text>`, marginLeft, html.EscapeString(c.Title))
+
+	minX, maxX, minY, maxY, ok := c.bounds()
+	if !ok {
+		fmt.Fprintf(&b, `<text x="%d" y="%d" class="chart-empty">no data</text></svg>`, chartWidth/2, chartHeight/2)
+		return template.HTML(b.String())
+	}
+	yTicks := niceTicks(minY, maxY, 5)
+	minY, maxY = yTicks[0].Value, yTicks[len(yTicks)-1].Value
+	xTicks := c.XTicks
+	if len(xTicks) == 0 {
+		xTicks = niceTicks(minX, maxX, 8)
+		minX, maxX = xTicks[0].Value, xTicks[len(xTicks)-1].Value
+	}
+
+	plotW := float64(chartWidth - marginLeft - marginRight)
+	plotH := float64(chartHeight - marginTop - marginBottom)
+	sx := func(x float64) float64 { return float64(marginLeft) + (x-minX)/(maxX-minX)*plotW }
+	sy := func(y float64) float64 { return float64(marginTop) + plotH - (y-minY)/(maxY-minY)*plotH }
+
+	for _, bd := range c.Bands {
+		from, to := math.Max(bd.From, minX), math.Min(bd.To, maxX)
+		if to <= from {
+			continue
+		}
+		fmt.Fprintf(&b, `<rect x="%.1f" y="%d" width="%.1f" height="%.1f" fill="%s" fill-opacity="0.12"/>`, sx(from), marginTop, sx(to)-sx(from), plotH, bd.Color)
+		fmt.Fprintf(&b, `<line x1="%.1f" y1="%d" x2="%.1f" y2="%.1f" class="boundary"/>`, sx(from), marginTop, sx(from), float64(marginTop)+plotH)
+		fmt.Fprintf(&b, `<text x="%.1f" y="%d" class="band-label">%s</text>`, sx(from)+3, marginTop+12, html.EscapeString(bd.Label))
+	}
+
+	for _, t := range yTicks {
+		y := sy(t.Value)
+		fmt.Fprintf(&b, `<line x1="%d" y1="%.1f" x2="%.1f" y2="%.1f" class="grid"/>`, marginLeft, y, float64(marginLeft)+plotW, y)
+		fmt.Fprintf(&b, `<text x="%d" y="%.1f" class="tick" text-anchor="end">%s</text>`, marginLeft-6, y+4, html.EscapeString(t.Label))
+	}
+	for _, t := range xTicks {
+		if t.Value < minX || t.Value > maxX {
+			continue
+		}
+		x := sx(t.Value)
+		fmt.Fprintf(&b, `<line x1="%.1f" y1="%d" x2="%.1f" y2="%.1f" class="grid"/>`, x, marginTop, x, float64(marginTop)+plotH)
+		fmt.Fprintf(&b, `<text x="%.1f" y="%.1f" class="tick" text-anchor="middle">%s</text>`, x, float64(marginTop)+plotH+16, html.EscapeString(t.Label))
+	}
+	fmt.Fprintf(&b, `<rect x="%d" y="%d" width="%.1f" height="%.1f" class="frame"/>`, marginLeft, marginTop, plotW, plotH)
+	fmt.Fprintf(&b, `<text x="%.1f" y="%d" class="axis-label" text-anchor="middle">%s</text>`, float64(marginLeft)+plotW/2, chartHeight-6, html.EscapeString(c.XLabel))
+	fmt.Fprintf(&b, `<text x="14" y="%.1f" class="axis-label" text-anchor="middle" transform="rotate(-90 14 %.1f)">%s</text>`, float64(marginTop)+plotH/2, float64(marginTop)+plotH/2, html.EscapeString(c.YLabel))
+
+	for i, s := range c.Series {
+		if len(s.Points) == 0 {
+			continue
+		}
+		coords := make([]string, 0, len(s.Points))
+		for _, p := range s.Points {
+			coords = append(coords, fmt.Sprintf("%.1f,%.1f", sx(p.X), sy(p.Y)))
+		}
+		fmt.Fprintf(&b, `<polyline points="%s" fill="none" stroke="%s" stroke-width="1.6"><title>%s</title></polyline>`, strings.Join(coords, " "), s.Color, html.EscapeString(s.Name))
+		lx := float64(marginLeft) + 10 + float64(i%4)*190
+		ly := float64(marginTop) + 14 + float64(i/4)*14
+		if len(c.Bands) > 0 {
+			ly += 14
+		}
+		fmt.Fprintf(&b, `<rect x="%.1f" y="%.1f" width="10" height="3" fill="%s"/>`, lx, ly-4, s.Color)
+		fmt.Fprintf(&b, `<text x="%.1f" y="%.1f" class="legend">%s</text>`, lx+14, ly, html.EscapeString(s.Name))
+	}
+	b.WriteString(`</svg>`)
+	return template.HTML(b.String())
+}
+
+func (c lineChart) bounds() (minX, maxX, minY, maxY float64, ok bool) {
+	minX, minY = math.Inf(1), 0
+	maxX, maxY = math.Inf(-1), math.Inf(-1)
+	for _, s := range c.Series {
+		for _, p := range s.Points {
+			minX, maxX = math.Min(minX, p.X), math.Max(maxX, p.X)
+			minY, maxY = math.Min(minY, p.Y), math.Max(maxY, p.Y)
+			ok = true
+		}
+	}
+	if !ok {
+		return 0, 0, 0, 0, false
+	}
+	for _, t := range c.XTicks {
+		minX, maxX = math.Min(minX, t.Value), math.Max(maxX, t.Value)
+	}
+	if maxX == minX {
+		maxX = minX + 1
+	}
+	if maxY == minY {
+		maxY = minY + 1
+	}
+	return minX, maxX, minY, maxY, true
+}
+
+// niceTicks returns about n evenly spaced ticks on 1/2/5 multiples covering
+// [min, max].
+func niceTicks(min, max float64, n int) []tick {
+	if max <= min {
+		max = min + 1
+	}
+	raw := (max - min) / float64(n)
+	magnitude := math.Pow(10, math.Floor(math.Log10(raw)))
+	step := magnitude
+	for _, factor := range []float64{1, 2, 5, 10} {
+		step = factor * magnitude
+		if step >= raw {
+			break
+		}
+	}
+	start := math.Floor(min/step) * step
+	end := math.Ceil(max/step) * step
+	var ticks []tick
+	for v := start; v <= end+step/2; v += step {
+		ticks = append(ticks, tick{Value: v, Label: formatNumber(v)})
+	}
+	return ticks
+}
+
+func formatNumber(v float64) string {
+	if math.Abs(v) < 1e-9 {
+		return "0"
+	}
+	return strconv.FormatFloat(v, 'g', 4, 64)
+}
+
+// percentileTicks are the usual HdrHistogram plot ticks on a
+// log10(1/(1-q)) axis.
+var percentileTicks = []tick{
+	{0, "0%"},
+	{1, "90%"},
+	{2, "99%"},
+	{3, "99.9%"},
+	{4, "99.99%"},
+	{5, "99.999%"},
+}
+
+// percentileX maps a quantile (0-1) onto the percentile axis; the final
+// 100% row is clamped to the last tick.
+func percentileX(quantile float64) float64 {
+	if quantile >= 0.99999 {
+		return 5
+	}
+	return math.Log10(1 / (1 - quantile))
+}
@@ -0,0 +1,184 @@
+package runstats
+
+import (
+	"math"
+	"math/rand/v2"
+	"sort"
+	"time"
+)
+
+const (
+	// AggregateSchema identifies the aggregate.json layout.
+	AggregateSchema = "slsbench.aggregate/v1"
+	// AggregateFile is written into the root of a repeated run.
+	AggregateFile = "aggregate.json"
+
+	// DefaultResamples and DefaultConfidence apply when Bootstrap leaves them
+	// unset.
+	DefaultResamples  = 10000
+	DefaultConfidence = 0.95
+)
+
+// Interval is a confidence interval.
+type Interval struct {
+	Low  float64 `json:"low"`
+	High float64 `json:"high"`
+}
+
+// Estimate describes one metric over the repetitions of a variant. The
+// intervals are percentile bootstrap intervals; with a single repetition
+// they collapse to that value.
+type Estimate struct {
+	N        int       `json:"n"`
+	Mean     float64   `json:"mean"`
+	Median   float64   `json:"median"`
+	StdDev   float64   `json:"stdDev"`
+	Min      float64   `json:"min"`
+	Max      float64   `json:"max"`
+	MeanCI   Interval  `json:"meanCi"`
+	MedianCI Interval  `json:"medianCi"`
+	Values   []float64 `json:"values"`
+}
+
+// Bootstrap configures the resampling behind Estimate intervals. A fixed
+// seed keeps aggregates reproducible.
+type Bootstrap struct {
+	Resamples  int     `json:"resamples"`
+	Confidence float64 `json:"confidence"`
+	Seed       uint64  `json:"seed"`
+}
+
+// Estimate summarizes values. It returns nil for no values.
+func (b Bootstrap) Estimate(values []float64) *Estimate {
+	if len(values) == 0 {
+		return nil
+	}
+	est := &Estimate{
+		N:      len(values),
+		Mean:   mean(values),
+		Median: Median(values),
+		Min:    values[0],
+		Max:    values[0],
+		Values: values,
+	}
+	for _, v := range values {
+		est.Min = math.Min(est.Min, v)
+		est.Max = math.Max(est.Max, v)
+	}
+	if len(values) > 1 {
+		sum := 0.0
+		for _, v := range values {
+			sum += (v - est.Mean) * (v - est.Mean)
+		}
+		est.StdDev = math.Sqrt(sum / float64(len(values)-1))
+	}
+
+	resamples := b.Resamples
+	if resamples <= 0 {
+		resamples = DefaultResamples
+	}
+	confidence := b.Confidence
+	if confidence <= 0 || confidence >= 1 {
+		confidence = DefaultConfidence
+	}
+	rng := rand.New(rand.NewPCG(b.Seed, uint64(len(values))))
+	means := make([]float64, resamples)
+	medians := make([]float64, resamples)
+	sample := make([]float64, len(values))
+	for i := 0; i < resamples; i++ {
+		for j := range sample {
+			sample[j] = values[rng.IntN(len(values))]
+		}
+		means[i] = mean(sample)
+		medians[i] = Median(sample)
+	}
+	est.MeanCI = percentileInterval(means, confidence)
+	est.MedianCI = percentileInterval(medians, confidence)
+	return est
+}
+
+func percentileInterval(values []float64, confidence float64) Interval {
+	sort.Float64s(values)
+	tail := (1 - confidence) / 2
+	at := func(q float64) float64 {
+		i := int(math.Floor(q * float64(len(values)-1)))
+		return values[max(0, min(len(values)-1, i))]
+	}
+	return Interval{Low: at(tail), High: at(1 - tail)}
+}
+
+func mean(values []float64) float64 {
+	sum := 0.0
+	for _, v := range values {
+		sum += v
+	}
+	return sum / float64(len(values))
+}
+
+// StageAggregate holds the estimates of every stage metric, keyed by metric
+// name. Metrics no repetition produced are left out.
+type StageAggregate struct {
+	Stage   string               `json:"stage"`
+	Metrics map[string]*Estimate `json:"metrics"`
+}
+
+// VariantAggregate summarizes the completed repetitions of one variant.
+type VariantAggregate struct {
+	Variant  string               `json:"variant"`
+	Runs     []string             `json:"runs"`
+	RunLevel map[string]*Estimate `json:"runLevel"`
+	Stages   []StageAggregate     `json:"stages"`
+}
+
+// Aggregate summarizes runs (the repetitions of one variant).
+func (b Bootstrap) Aggregate(variant string, runs []*Run) VariantAggregate {
+	agg := VariantAggregate{Variant: variant, Runs: []string{}, RunLevel: map[string]*Estimate{}, Stages: []StageAggregate{}}
+	for _, run := range runs {
+		agg.Runs = append(agg.Runs, run.Dir)
+	}
+	for _, metric := range Metrics {
+		if !metric.RunLevel {
+			continue
+		}
+		if est := b.Estimate(Values(metric, "", runs)); est != nil {
+			agg.RunLevel[metric.Name] = est
+		}
+	}
+	for _, stage := range StageNames(runs) {
+		stageAgg := StageAggregate{Stage: stage, Metrics: map[string]*Estimate{}}
+		for _, metric := range Metrics {
+			if metric.RunLevel {
+				continue
+			}
+			if est := b.Estimate(Values(metric, stage, runs)); est != nil {
+				stageAgg.Metrics[metric.Name] = est
+			}
+		}
+		agg.Stages = append(agg.Stages, stageAgg)
+	}
+	return agg
+}
+
+// Repetition records one harness run of a repeated run.
+type Repetition struct {
+	Variant    string    `json:"variant"`
+	Index      int       `json:"index"`
+	Dir        string    `json:"dir"`
+	StartedAt  time.Time `json:"startedAt"`
+	FinishedAt time.Time `json:"finishedAt"`
+	Status     string    `json:"status"`
+	Error      string    `json:"error,omitempty"`
+}
+
+// AggregateSummary is the content of aggregate.json. Schedule lists the
+// repetitions in execution order; only completed ones are aggregated.
+type AggregateSummary struct {
+	SchemaVersion string             `json:"schemaVersion"`
+	StartedAt     time.Time          `json:"startedAt"`
+	FinishedAt    time.Time          `json:"finishedAt"`
+	Repetitions   int                `json:"repetitions"`
+	Interleaved   bool               `json:"interleaved"`
+	Bootstrap     Bootstrap          `json:"bootstrap"`
+	Schedule      []Repetition       `json:"schedule"`
+	Variants      []VariantAggregate `json:"variants"`
+}
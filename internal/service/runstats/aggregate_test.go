@@ -0,0 +1,55 @@
+package runstats
+
+import (
+	"path/filepath"
+	"testing"
+)
+
+func TestBootstrapEstimate_IntervalsContainTheEstimate(t *testing.T) {
+	values := []float64{10, 12, 11, 13, 9, 10.5, 11.5, 12.5}
+	est := Bootstrap{Resamples: 2000, Seed: 1}.Estimate(values)
+	if est.N != len(values) || est.Min != 9 || est.Max != 13 {
+		t.Fatalf("unexpected estimate: %+v", est)
+	}
+	if est.MeanCI.Low > est.Mean || est.MeanCI.High < est.Mean || est.MeanCI.Low == est.MeanCI.High {
+		t.Fatalf("mean %v outside its interval %+v", est.Mean, est.MeanCI)
+	}
+	if est.MedianCI.Low > est.Median || est.MedianCI.High < est.Median {
+		t.Fatalf("median %v outside its interval %+v", est.Median, est.MedianCI)
+	}
+	again := Bootstrap{Resamples: 2000, Seed: 1}.Estimate(values)
+	if again.MeanCI != est.MeanCI {
+		t.Fatalf("same seed gave different intervals: %+v and %+v", est.MeanCI, again.MeanCI)
+	}
+}
+
+func TestBootstrapEstimate_SingleValueCollapses(t *testing.T) {
+	est := Bootstrap{}.Estimate([]float64{42})
+	if est.StdDev != 0 || est.MeanCI != (Interval{42, 42}) || est.MedianCI != (Interval{42, 42}) {
+		t.Fatalf("unexpected estimate: %+v", est)
+	}
+	if (Bootstrap{}).Estimate(nil) != nil {
+		t.Fatalf("expected nil for no values")
+	}
+}
+
+func TestBootstrapAggregate_CoversStagesAndFirstResponse(t *testing.T) {
+	root := t.TempDir()
+	for i, p99 := range []float64{10, 11, 12} {
+		writeRunSummary(t, filepath.Join(root, []string{"a", "b", "c"}[i]), p99)
+	}
+	runs, err := LoadRuns(root)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	agg := Bootstrap{Resamples: 500}.Aggregate("baseline", runs)
+	if len(agg.Runs) != 3 || agg.RunLevel["first-response"].Mean != 1500 {
+		t.Fatalf("unexpected run-level aggregate: %+v", agg)
+	}
+	if len(agg.Stages) != 1 || agg.Stages[0].Metrics["p99"].Median != 11 {
+		t.Fatalf("unexpected stage aggregate: %+v", agg.Stages)
+	}
+	if _, ok := agg.Stages[0].Metrics["p50"]; ok {
+		t.Fatalf("metrics without values should be left out")
+	}
+}
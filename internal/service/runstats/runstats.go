@@ -0,0 +1,233 @@
+// Package runstats loads repeated harness results and extracts comparable
+// per-stage and per-run metrics from them. It is shared by compare (baseline
+// vs candidate) and by the aggregate written after repeated runs.
+package runstats
+
+import (
+	"fmt"
+	"math"
+	"path/filepath"
+	"slices"
+	"sort"
+
+	"github.com/d-iii-s/slsbench/internal/service/summary"
+)
+
+// Metric is a comparable quantity of a stage (or of the whole run when
+// RunLevel is set).
+type Metric struct {
+	Name          string
+	Unit          string
+	HigherIsWorse bool
+	RunLevel      bool
+	extract       func(run *Run, stage *summary.StageSummary) (float64, bool)
+}
+
+// Metrics lists every metric in report order.
+var Metrics = []Metric{
+	{Name: "first-response", Unit: "ms", HigherIsWorse: true, RunLevel: true, extract: func(run *Run, _ *summary.StageSummary) (float64, bool) {
+		if run.Summary.FirstResponse == nil {
+			return 0, false
+		}
+		return float64(run.Summary.FirstResponse.DurationMillis), true
+	}},
+	latencyMetric("p50", 50),
+	latencyMetric("p90", 90),
+	latencyMetric("p99", 99),
+	latencyMetric("p99.9", 99.9),
+	{Name: "mean", Unit: "ms", HigherIsWorse: true, extract: func(_ *Run, stage *summary.StageSummary) (float64, bool) {
+		if stage.Wrk2 == nil {
+			return 0, false
+		}
+		return stage.Wrk2.Latency.MeanMillis, true
+	}},
+	{Name: "max", Unit: "ms", HigherIsWorse: true, extract: func(_ *Run, stage *summary.StageSummary) (float64, bool) {
+		if stage.Wrk2 == nil {
+			return 0, false
+		}
+		return stage.Wrk2.Latency.MaxMillis, true
+	}},
+	{Name: "rps", Unit: "req/s", HigherIsWorse: false, extract: func(_ *Run, stage *summary.StageSummary) (float64, bool) {
+		if stage.Wrk2 == nil {
+			return 0, false
+		}
+		return stage.Wrk2.RequestsPerSecond, true
+	}},
+	{Name: "errors", Unit: "%", HigherIsWorse: true, extract: func(_ *Run, stage *summary.StageSummary) (float64, bool) {
+		if stage.Wrk2 == nil || stage.Wrk2.Requests == 0 {
+			return 0, false
+		}
+		failed := stage.Wrk2.Non2xx3xx + stage.Wrk2.SocketErrors.Total()
+		return float64(failed) / float64(stage.Wrk2.Requests) * 100, true
+	}},
+	{Name: "cpu", Unit: "%", HigherIsWorse: true, extract: func(run *Run, stage *summary.StageSummary) (float64, bool) {
+		window := run.stageSamples(stage)
+		if len(window) == 0 {
+			return 0, false
+		}
+		total := 0.0
+		for _, sample := range window {
+			total += sample.CPUPercent
+		}
+		return total / float64(len(window)), true
+	}},
+	{Name: "memory", Unit: "MiB", HigherIsWorse: true, extract: func(run *Run, stage *summary.StageSummary) (float64, bool) {
+		window := run.stageSamples(stage)
+		if len(window) == 0 {
+			return 0, false
+		}
+		var peak uint64
+		for _, sample := range window {
+			peak = max(peak, sample.MemoryUsageBytes)
+		}
+		return float64(peak) / (1 << 20), true
+	}},
+	{Name: "steady-state", Unit: "s", HigherIsWorse: true, extract: func(_ *Run, stage *summary.StageSummary) (float64, bool) {
+		if stage.SteadyState == nil || !stage.SteadyState.Detected {
+			return 0, false
+		}
+		return stage.SteadyState.TimeToSteadyStateSeconds, true
+	}},
+	{Name: "steady-p99", Unit: "ms", HigherIsWorse: true, extract: func(_ *Run, stage *summary.StageSummary) (float64, bool) {
+		steady := steadySummary(stage)
+		if steady == nil || steady.Latency == nil {
+			return 0, false
+		}
+		return steady.Latency.Percentile(99)
+	}},
+	{Name: "steady-rps", Unit: "req/s", HigherIsWorse: false, extract: func(_ *Run, stage *summary.StageSummary) (float64, bool) {
+		steady := steadySummary(stage)
+		if steady == nil || steady.DurationSeconds == 0 {
+			return 0, false
+		}
+		return steady.RequestsPerSecond, true
+	}},
+}
+
+// steadySummary returns the steady part of a stage, or nil when the stage
+// did not reach steady state.
+func steadySummary(stage *summary.StageSummary) *summary.SteadyStateSummary {
+	if stage.SteadyState == nil || !stage.SteadyState.Detected {
+		return nil
+	}
+	return stage.SteadyState.Summary
+}
+
+func latencyMetric(name string, percentile float64) Metric {
+	return Metric{Name: name, Unit: "ms", HigherIsWorse: true, extract: func(_ *Run, stage *summary.StageSummary) (float64, bool) {
+		if stage.Wrk2 == nil {
+			return 0, false
+		}
+		return stage.Wrk2.Latency.Percentile(percentile)
+	}}
+}
+
+// LookupMetric returns the metric with the given name.
+func LookupMetric(name string) (Metric, bool) {
+	for _, metric := range Metrics {
+		if metric.Name == name {
+			return metric, true
+		}
+	}
+	return Metric{}, false
+}
+
+// MetricNames returns the metric names in report order.
+func MetricNames() []string {
+	names := make([]string, 0, len(Metrics))
+	for _, metric := range Metrics {
+		names = append(names, metric.Name)
+	}
+	return names
+}
+
+// Value extracts the metric from a run; stage is nil for run-level metrics.
+func (m Metric) Value(run *Run, stage *summary.StageSummary) (float64, bool) {
+	return m.extract(run, stage)
+}
+
+// Run is one harness result with its stats stream.
+type Run struct {
+	Dir     string
+	Summary *summary.RunSummary
+	Samples []summary.StatsSample
+}
+
+func (r *Run) stageSamples(stage *summary.StageSummary) []summary.StatsSample {
+	if stage.StartedAt.IsZero() || stage.FinishedAt.IsZero() {
+		return nil
+	}
+	return summary.SamplesBetween(r.Samples, stage.StartedAt, stage.FinishedAt)
+}
+
+// LoadRuns loads every harness result at or below the given directories.
+func LoadRuns(dirs ...string) ([]*Run, error) {
+	var runs []*Run
+	for _, root := range dirs {
+		found, err := summary.FindRunDirs(root)
+		if err != nil {
+			return nil, err
+		}
+		if len(found) == 0 {
+			return nil, fmt.Errorf("no harness results found in %q", root)
+		}
+		for _, dir := range found {
+			runSummary, err := summary.LoadRunDir(dir)
+			if err != nil {
+				return nil, err
+			}
+			samples, err := summary.LoadStatsSamples(filepath.Join(dir, summary.StatsFile))
+			if err != nil {
+				return nil, err
+			}
+			runs = append(runs, &Run{Dir: dir, Summary: runSummary, Samples: samples})
+		}
+	}
+	return runs, nil
+}
+
+// Values returns the metric of the named stage (ignored for run-level
+// metrics) for every run that has it. Stages marked invalid by an event
+// policy are left out.
+func Values(metric Metric, stageName string, runs []*Run) []float64 {
+	values := []float64{}
+	for _, run := range runs {
+		var stage *summary.StageSummary
+		if !metric.RunLevel {
+			if stage = run.Summary.Stage(stageName); stage == nil || stage.Invalid {
+				continue
+			}
+		}
+		if value, ok := metric.extract(run, stage); ok && !math.IsNaN(value) {
+			values = append(values, value)
+		}
+	}
+	return values
+}
+
+// StageNames returns the stage names of the runs in first-seen order.
+func StageNames(runs []*Run) []string {
+	var names []string
+	for _, run := range runs {
+		for _, stage := range run.Summary.Stages {
+			if !slices.Contains(names, stage.Stage) {
+				names = append(names, stage.Stage)
+			}
+		}
+	}
+	return names
+}
+
+// Median returns the median of values, or NaN when there are none.
+func Median(values []float64) float64 {
+	if len(values) == 0 {
+		return math.NaN()
+	}
+	sorted := append([]float64(nil), values...)
+	sort.Float64s(sorted)
+	mid := len(sorted) / 2
+	if len(sorted)%2 == 1 {
+		return sorted[mid]
+	}
+	return (sorted[mid-1] + sorted[mid]) / 2
+}
@@ -0,0 +1,110 @@
+package runstats
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/d-iii-s/slsbench/internal/service/summary"
+)
+
+func writeRunSummary(t *testing.T, dir string, p99 float64) {
+	t.Helper()
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		t.Fatalf("failed to create %s: %v", dir, err)
+	}
+	run := summary.RunSummary{
+		SchemaVersion: summary.RunSummarySchema,
+		FirstResponse: &summary.FirstResponse{DurationMillis: 1500},
+		Stages: []summary.StageSummary{{
+			Stage: "browse",
+			Wrk2:  &summary.Wrk2Result{Requests: 1000, Latency: summary.LatencySummary{Percentiles: []summary.Percentile{{Percentile: 99, LatencyMillis: p99}}}},
+		}},
+	}
+	if err := summary.WriteJSON(filepath.Join(dir, summary.RunSummaryFile), run); err != nil {
+		t.Fatalf("failed to write summary: %v", err)
+	}
+}
+
+func TestLoadRuns_FindsRepetitions(t *testing.T) {
+	root := t.TempDir()
+	for _, rep := range []string{"rep-001", "rep-002"} {
+		writeRunSummary(t, filepath.Join(root, rep, "harness-result-x"), 10)
+	}
+	runs, err := LoadRuns(root)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(runs) != 2 {
+		t.Fatalf("expected 2 runs, got %d", len(runs))
+	}
+	if _, err := LoadRuns(t.TempDir()); err == nil {
+		t.Fatalf("expected an error for a directory without results")
+	}
+}
+
+func TestValues_SkipsRunsWithoutTheStage(t *testing.T) {
+	root := t.TempDir()
+	writeRunSummary(t, filepath.Join(root, "a"), 10)
+	writeRunSummary(t, filepath.Join(root, "b"), 12)
+	runs, err := LoadRuns(root)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	runs[1].Summary.Stages[0].Stage = "other"
+
+	p99, _ := LookupMetric("p99")
+	if values := Values(p99, "browse", runs); len(values) != 1 || values[0] != 10 {
+		t.Fatalf("unexpected p99 values: %v", values)
+	}
+	firstResponse, _ := LookupMetric("first-response")
+	if values := Values(firstResponse, "", runs); len(values) != 2 {
+		t.Fatalf("expected a first-response value per run, got %v", values)
+	}
+	if names := StageNames(runs); len(names) != 2 || names[0] != "browse" || names[1] != "other" {
+		t.Fatalf("unexpected stage names: %v", names)
+	}
+}
+
+func TestValues_SkipsInvalidStages(t *testing.T) {
+	root := t.TempDir()
+	writeRunSummary(t, filepath.Join(root, "a"), 10)
+	writeRunSummary(t, filepath.Join(root, "b"), 12)
+	runs, err := LoadRuns(root)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	runs[0].Summary.Stages[0].Invalid = true
+
+	p99, _ := LookupMetric("p99")
+	if values := Values(p99, "browse", runs); len(values) != 1 || values[0] != 12 {
+		t.Fatalf("unexpected p99 values: %v", values)
+	}
+}
+
+func TestValues_SteadyStateMetrics(t *testing.T) {
+	root := t.TempDir()
+	writeRunSummary(t, filepath.Join(root, "a"), 10)
+	writeRunSummary(t, filepath.Join(root, "b"), 12)
+	runs, err := LoadRuns(root)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	runs[0].Summary.Stages[0].SteadyState = &summary.SteadyState{
+		Detected:                 true,
+		TimeToSteadyStateSeconds: 7.5,
+		Summary: &summary.SteadyStateSummary{
+			DurationSeconds:   50,
+			RequestsPerSecond: 99,
+			Latency:           &summary.LatencySummary{Percentiles: []summary.Percentile{{Percentile: 99, LatencyMillis: 8}}},
+		},
+	}
+	runs[1].Summary.Stages[0].SteadyState = &summary.SteadyState{Detected: false}
+
+	for name, want := range map[string]float64{"steady-state": 7.5, "steady-p99": 8, "steady-rps": 99} {
+		metric, _ := LookupMetric(name)
+		if values := Values(metric, "browse", runs); len(values) != 1 || values[0] != want {
+			t.Fatalf("%s values = %v, want only the steady run's %v", name, values, want)
+		}
+	}
+}
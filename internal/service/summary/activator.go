@@ -0,0 +1,23 @@
+package summary
+
+// ActivatorSummary describes what the scale-to-zero activator did in front
+// of the service, for one stage or the whole run.
+type ActivatorSummary struct {
+	Mode               string  `json:"mode"`
+	IdleTimeoutSeconds float64 `json:"idleTimeoutSeconds"`
+	MaxConcurrency     int     `json:"maxConcurrency"`
+	Requests           int     `json:"requests"`
+	// ColdRequests waited for the service to scale up from zero.
+	ColdRequests int `json:"coldRequests"`
+	// QueuedRequests waited for a concurrency slot.
+	QueuedRequests int `json:"queuedRequests"`
+	ScaleUps       int `json:"scaleUps"`
+	ScaleDowns     int `json:"scaleDowns"`
+	FailedRequests int `json:"failedRequests"`
+	// ScaleFromZero is the activation wait of the cold requests.
+	ScaleFromZero *PhaseSummary `json:"scaleFromZero,omitempty"`
+	// QueueDelay is the concurrency queue wait of all requests.
+	QueueDelay *PhaseSummary `json:"queueDelay,omitempty"`
+	// Upstream is the service latency behind the activator.
+	Upstream *PhaseSummary `json:"upstream,omitempty"`
+}
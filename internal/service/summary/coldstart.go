@@ -0,0 +1,148 @@
+package summary
+
+import (
+	"math"
+	"sort"
+	"strconv"
+	"time"
+)
+
+const (
+	// ColdStartSchema identifies the cold-start.json layout.
+	ColdStartSchema = "slsbench.cold-start/v1"
+	// ColdStartFile is written into the cold-start result directory.
+	ColdStartFile = "cold-start.json"
+)
+
+// Cold-start phases. Request phases are named RequestPhase(k).
+const (
+	// PhaseCreate is the removal and re-creation of the service container
+	// (recreate mode only).
+	PhaseCreate = "create"
+	// PhaseStart is the container start call.
+	PhaseStart = "start"
+	// PhaseFirst2xx runs from the return of the start call to the first 2xx
+	// response.
+	PhaseFirst2xx = "first-2xx"
+	// PhaseTotal runs from the first container operation to the first 2xx
+	// response.
+	PhaseTotal = "total"
+)
+
+// RequestPhase names the phase holding the latency of the k-th (1-based)
+// flow request after the first 2xx response.
+func RequestPhase(k int) string {
+	return "request-" + strconv.Itoa(k)
+}
+
+// ColdStartSummary is written to cold-start.json by the cold-start command.
+type ColdStartSummary struct {
+	SchemaVersion string            `json:"schemaVersion"`
+	Status        string            `json:"status"`
+	Error         string            `json:"error,omitempty"`
+	StartedAt     time.Time         `json:"startedAt"`
+	FinishedAt    time.Time         `json:"finishedAt"`
+	ComposeFile   string            `json:"composeFile"`
+	ComposeName   string            `json:"composeProject"`
+	ServiceName   string            `json:"serviceName"`
+	Port          int               `json:"port"`
+	Mode          string            `json:"mode"`
+	Iterations    int               `json:"iterations"`
+	Stage         string            `json:"stage"`
+	FirstRequests int               `json:"firstRequests"`
+	TargetURL     string            `json:"targetUrl"`
+	Phases        []PhaseSummary    `json:"phases"`
+	Samples       []ColdStartSample `json:"samples"`
+}
+
+// ColdStartSample is one stop/start (or recreate) cycle. PhasesMillis holds
+// the phases this cycle reached.
+type ColdStartSample struct {
+	Index        int                `json:"index"`
+	ContainerID  string             `json:"containerId"`
+	StartedAt    time.Time          `json:"startedAt"`
+	StopMillis   float64            `json:"stopMillis"`
+	PhasesMillis map[string]float64 `json:"phasesMillis"`
+	Attempts     int                `json:"attempts"`
+	Requests     []RequestTiming    `json:"requests"`
+	Error        string             `json:"error,omitempty"`
+}
+
+// RequestTiming is one replayed flow request.
+type RequestTiming struct {
+	FlowID        string  `json:"flowId"`
+	Method        string  `json:"method"`
+	Path          string  `json:"path"`
+	StatusCode    int     `json:"statusCode,omitempty"`
+	LatencyMillis float64 `json:"latencyMillis"`
+	Error         string  `json:"error,omitempty"`
+}
+
+// PhaseSummary is the distribution of one phase over all samples.
+type PhaseSummary struct {
+	Phase      string  `json:"phase"`
+	N          int     `json:"n"`
+	MinMillis  float64 `json:"minMillis"`
+	MeanMillis float64 `json:"meanMillis"`
+	P50Millis  float64 `json:"p50Millis"`
+	P90Millis  float64 `json:"p90Millis"`
+	P99Millis  float64 `json:"p99Millis"`
+	MaxMillis  float64 `json:"maxMillis"`
+}
+
+// SummarizePhases builds the percentile summary of every phase in order.
+// Phases no sample reached are left out.
+func SummarizePhases(samples []ColdStartSample, phases []string) []PhaseSummary {
+	out := []PhaseSummary{}
+	for _, phase := range phases {
+		var values []float64
+		for _, sample := range samples {
+			if v, ok := sample.PhasesMillis[phase]; ok {
+				values = append(values, v)
+			}
+		}
+		if summary := SummarizeValues(phase, values); summary != nil {
+			out = append(out, *summary)
+		}
+	}
+	return out
+}
+
+// SummarizeValues builds the percentile summary of millisecond values. It
+// returns nil for no values and does not modify values.
+func SummarizeValues(phase string, values []float64) *PhaseSummary {
+	if len(values) == 0 {
+		return nil
+	}
+	sorted := append([]float64(nil), values...)
+	sort.Float64s(sorted)
+	sum := 0.0
+	for _, v := range sorted {
+		sum += v
+	}
+	return &PhaseSummary{
+		Phase:      phase,
+		N:          len(sorted),
+		MinMillis:  sorted[0],
+		MeanMillis: sum / float64(len(sorted)),
+		P50Millis:  Quantile(sorted, 0.50),
+		P90Millis:  Quantile(sorted, 0.90),
+		P99Millis:  Quantile(sorted, 0.99),
+		MaxMillis:  sorted[len(sorted)-1],
+	}
+}
+
+// Quantile returns the q-quantile (0-1) of sorted values, interpolating
+// linearly between the closest ranks.
+func Quantile(sorted []float64, q float64) float64 {
+	if len(sorted) == 0 {
+		return 0
+	}
+	pos := q * float64(len(sorted)-1)
+	lower := int(math.Floor(pos))
+	upper := int(math.Ceil(pos))
+	if upper >= len(sorted) {
+		return sorted[len(sorted)-1]
+	}
+	return sorted[lower] + (sorted[upper]-sorted[lower])*(pos-float64(lower))
+}
@@ -0,0 +1,22 @@
+package summary
+
+import "time"
+
+// DockerEventsFile lists the Docker container events of the compose project
+// during a harness run, one JSON object per line.
+const DockerEventsFile = "docker-events.jsonl"
+
+// DockerEvent is one line of DockerEventsFile. Phase and Stage are the
+// timeline phase the event happened in; Action is the policy the harness
+// applied to it.
+type DockerEvent struct {
+	Time          time.Time `json:"time"`
+	Event         string    `json:"event"`
+	Service       string    `json:"service,omitempty"`
+	ContainerID   string    `json:"containerId"`
+	ContainerName string    `json:"containerName,omitempty"`
+	ExitCode      string    `json:"exitCode,omitempty"`
+	Phase         string    `json:"phase,omitempty"`
+	Stage         string    `json:"stage,omitempty"`
+	Action        string    `json:"action,omitempty"`
+}
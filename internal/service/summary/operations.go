@@ -0,0 +1,167 @@
+package summary
+
+import (
+	"math"
+	"sort"
+	"time"
+)
+
+const (
+	// OperationsSchema identifies the operations.json layout.
+	OperationsSchema = "slsbench.operations/v1"
+	// OperationsFile is the per-operation breakdown written into a stage
+	// output directory by executors that replay the flow themselves.
+	OperationsFile = "operations.json"
+)
+
+// OperationsSummary breaks the results of one stage down by flow node.
+// Operations are sorted by node name and flow id.
+type OperationsSummary struct {
+	SchemaVersion   string           `json:"schemaVersion"`
+	Stage           string           `json:"stage"`
+	DurationSeconds float64          `json:"durationSeconds"`
+	Operations      []OperationStats `json:"operations"`
+}
+
+// OperationStats is the outcome of one flow node. Latency is corrected for
+// coordinated omission like the stage report; failed requests count as
+// SocketErrors and are not part of the latency distribution.
+type OperationStats struct {
+	Node              string            `json:"node,omitempty"`
+	FlowID            string            `json:"flowId"`
+	Method            string            `json:"method"`
+	PathTemplate      string            `json:"pathTemplate"`
+	Requests          int64             `json:"requests"`
+	RequestsPerSecond float64           `json:"requestsPerSecond"`
+	Latency           LatencySummary    `json:"latency"`
+	Histogram         []HistogramBucket `json:"histogram,omitempty"`
+	StatusCodes       map[string]int64  `json:"statusCodes,omitempty"`
+	Non2xx3xx         int64             `json:"non2xx3xxResponses"`
+	SocketErrors      int64             `json:"socketErrors"`
+	// ErrorSamples holds the first few failed requests.
+	ErrorSamples []ErrorSample `json:"errorSamples,omitempty"`
+}
+
+// HistogramBucket counts the latencies recorded at LatencyMillis, the lowest
+// value of the bucket.
+type HistogramBucket struct {
+	LatencyMillis float64 `json:"latencyMillis"`
+	Count         int64   `json:"count"`
+}
+
+// ErrorSample is one non-2xx/3xx response or socket error.
+type ErrorSample struct {
+	Time       time.Time `json:"time"`
+	StatusCode int       `json:"statusCode,omitempty"`
+	Error      string    `json:"error,omitempty"`
+	// ResponseBody is truncated to a few hundred bytes.
+	ResponseBody string `json:"responseBody,omitempty"`
+}
+
+// LatencyHistogram records latencies in buckets of three significant
+// decimal digits, the default precision of HdrHistogram, so its size does
+// not grow with the number of requests. The zero value is ready to use.
+type LatencyHistogram struct {
+	// counts is keyed by the bucket floor in microseconds.
+	counts     map[int64]int64
+	total      int64
+	sum        float64
+	sumSquares float64
+	max        time.Duration
+}
+
+// histogramDigits is the number of significant digits kept per bucket.
+const histogramDigits = 3
+
+// Record adds one latency.
+func (h *LatencyHistogram) Record(d time.Duration) {
+	if h.counts == nil {
+		h.counts = map[int64]int64{}
+	}
+	h.counts[histogramBucket(d.Microseconds())]++
+	h.total++
+	millis := float64(d) / float64(time.Millisecond)
+	h.sum += millis
+	h.sumSquares += millis * millis
+	h.max = max(h.max, d)
+}
+
+// RecordBucket adds the latencies of a bucket written by Buckets. They are
+// recorded at the bucket floor, which also bounds the maximum.
+func (h *LatencyHistogram) RecordBucket(bucket HistogramBucket) {
+	if bucket.Count <= 0 {
+		return
+	}
+	if h.counts == nil {
+		h.counts = map[int64]int64{}
+	}
+	micros := int64(math.Round(bucket.LatencyMillis * 1000))
+	h.counts[histogramBucket(micros)] += bucket.Count
+	h.total += bucket.Count
+	millis := float64(micros) / 1000
+	h.sum += millis * float64(bucket.Count)
+	h.sumSquares += millis * millis * float64(bucket.Count)
+	h.max = max(h.max, time.Duration(micros)*time.Microsecond)
+}
+
+// Count returns the number of recorded latencies.
+func (h *LatencyHistogram) Count() int64 {
+	return h.total
+}
+
+// histogramBucket truncates micros to histogramDigits significant digits.
+func histogramBucket(micros int64) int64 {
+	step := int64(1)
+	for limit := int64(math.Pow10(histogramDigits)); micros >= limit*step; {
+		step *= 10
+	}
+	return micros / step * step
+}
+
+// Percentile returns the nearest-rank percentile p (0-100) in milliseconds.
+func (h *LatencyHistogram) Percentile(p float64) float64 {
+	if h.total == 0 {
+		return 0
+	}
+	if p >= 100 {
+		return float64(h.max) / float64(time.Millisecond)
+	}
+	rank := max(int64(math.Ceil(p/100*float64(h.total))), 1)
+	var seen int64
+	for _, bucket := range h.Buckets() {
+		seen += bucket.Count
+		if seen >= rank {
+			return bucket.LatencyMillis
+		}
+	}
+	return float64(h.max) / float64(time.Millisecond)
+}
+
+// Buckets returns the non-empty buckets in ascending order.
+func (h *LatencyHistogram) Buckets() []HistogramBucket {
+	keys := make([]int64, 0, len(h.counts))
+	for key := range h.counts {
+		keys = append(keys, key)
+	}
+	sort.Slice(keys, func(i, j int) bool { return keys[i] < keys[j] })
+	buckets := make([]HistogramBucket, len(keys))
+	for i, key := range keys {
+		buckets[i] = HistogramBucket{LatencyMillis: float64(key) / 1000, Count: h.counts[key]}
+	}
+	return buckets
+}
+
+// Summary returns the distribution at the given percentiles (0-100).
+func (h *LatencyHistogram) Summary(percentiles []float64) LatencySummary {
+	latency := LatencySummary{TotalCount: h.total, Percentiles: []Percentile{}}
+	if h.total > 0 {
+		n := float64(h.total)
+		latency.MeanMillis = h.sum / n
+		latency.StdDevMillis = math.Sqrt(max(h.sumSquares/n-latency.MeanMillis*latency.MeanMillis, 0))
+		latency.MaxMillis = float64(h.max) / float64(time.Millisecond)
+	}
+	for _, p := range percentiles {
+		latency.Percentiles = append(latency.Percentiles, Percentile{Percentile: p, LatencyMillis: h.Percentile(p)})
+	}
+	return latency
+}
@@ -0,0 +1,177 @@
+package summary
+
+import (
+	"sort"
+	"time"
+)
+
+const (
+	// ResourceSummarySchema identifies the resources/*.json layout.
+	ResourceSummarySchema = "slsbench.resource-summary/v1"
+	// ResourcesDir holds one resource summary per stage and IdleResourcesFile
+	// for the time between them.
+	ResourcesDir = "resources"
+	// IdleResourcesFile covers every sample tagged TimelineIdle.
+	IdleResourcesFile = "idle.json"
+
+	// LeakMinDuration is the shortest period whose memory growth is judged.
+	LeakMinDuration = 5 * time.Minute
+	// LeakMinGrowth is the fraction of the mean memory the fitted growth has
+	// to add over the period to be flagged as a possible leak.
+	LeakMinGrowth = 0.10
+)
+
+// ResourceSummary aggregates the stats samples of one stage, or of the idle
+// periods, per container.
+type ResourceSummary struct {
+	SchemaVersion string `json:"schemaVersion"`
+	// Period is the stage name, or TimelineIdle.
+	Period     string    `json:"period"`
+	StartedAt  time.Time `json:"startedAt,omitzero"`
+	FinishedAt time.Time `json:"finishedAt,omitzero"`
+	// Requests is the wrk2 request count of the stage; zero for idle.
+	Requests   int64                `json:"requests,omitempty"`
+	Containers []ContainerResources `json:"containers"`
+}
+
+// ContainerResources are the aggregates of one container's samples.
+type ContainerResources struct {
+	Service         string  `json:"service"`
+	Role            string  `json:"role"`
+	Samples         int     `json:"samples"`
+	DurationSeconds float64 `json:"durationSeconds"`
+
+	CPUPercentMean float64 `json:"cpuPercentMean"`
+	CPUPercentP95  float64 `json:"cpuPercentP95"`
+	CPUPercentMax  float64 `json:"cpuPercentMax"`
+	// CPUThrottledPercent* summarize the share of throttled CFS periods;
+	// non-zero only under a CPU quota such as --cpus.
+	CPUThrottledPercentMean float64 `json:"cpuThrottledPercentMean"`
+	CPUThrottledPercentMax  float64 `json:"cpuThrottledPercentMax"`
+
+	MemoryMeanBytes float64 `json:"memoryMeanBytes"`
+	MemoryPeakBytes uint64  `json:"memoryPeakBytes"`
+	// MemoryGrowthBytesPerSecond is the least-squares slope of memory usage
+	// over time.
+	MemoryGrowthBytesPerSecond float64 `json:"memoryGrowthBytesPerSecond"`
+	// PossibleLeak is set when the period lasted at least LeakMinDuration and
+	// the fitted growth over it exceeds LeakMinGrowth of the mean memory.
+	PossibleLeak bool `json:"possibleLeak"`
+	// MemoryFailcntDelta is the increase of memoryFailcnt over the period.
+	MemoryFailcntDelta uint64 `json:"memoryFailcntDelta"`
+
+	NetworkRxBytes uint64 `json:"networkRxBytes"`
+	NetworkTxBytes uint64 `json:"networkTxBytes"`
+	// NetworkBytesPerRequest is (rx + tx) / Requests; only set for stages.
+	NetworkBytesPerRequest float64 `json:"networkBytesPerRequest,omitempty"`
+
+	BlockReadBytes  uint64 `json:"blockReadBytes"`
+	BlockWriteBytes uint64 `json:"blockWriteBytes"`
+
+	PidsMean float64 `json:"pidsMean"`
+	PidsMax  uint64  `json:"pidsMax"`
+}
+
+// SummarizeResources aggregates the samples of one container that belong to
+// period. Samples must be sorted by time. It returns nil when none do.
+func SummarizeResources(samples []StatsSample, period string, requests int64) *ContainerResources {
+	var window []StatsSample
+	for _, sample := range samples {
+		if samplePeriod(sample) == period {
+			window = append(window, sample)
+		}
+	}
+	if len(window) == 0 {
+		return nil
+	}
+	out := &ContainerResources{
+		Service:         window[0].Service,
+		Role:            window[0].Role,
+		Samples:         len(window),
+		DurationSeconds: window[len(window)-1].TimestampUTC.Sub(window[0].TimestampUTC).Seconds(),
+	}
+
+	cpu := make([]float64, len(window))
+	var memoryTotal, pidsTotal float64
+	for i, sample := range window {
+		cpu[i] = sample.CPUPercent
+		out.CPUPercentMean += sample.CPUPercent
+		out.CPUPercentMax = max(out.CPUPercentMax, sample.CPUPercent)
+		out.CPUThrottledPercentMean += sample.CPUThrottledPercent
+		out.CPUThrottledPercentMax = max(out.CPUThrottledPercentMax, sample.CPUThrottledPercent)
+		memoryTotal += float64(sample.MemoryUsageBytes)
+		out.MemoryPeakBytes = max(out.MemoryPeakBytes, sample.MemoryUsageBytes)
+		pidsTotal += float64(sample.Pids)
+		out.PidsMax = max(out.PidsMax, sample.Pids)
+		if i > 0 {
+			// Counters restart with the container; only count increases.
+			out.NetworkRxBytes += counterDelta(window[i-1].NetworkRxBytes, sample.NetworkRxBytes)
+			out.NetworkTxBytes += counterDelta(window[i-1].NetworkTxBytes, sample.NetworkTxBytes)
+			out.BlockReadBytes += counterDelta(window[i-1].BlockReadBytes, sample.BlockReadBytes)
+			out.BlockWriteBytes += counterDelta(window[i-1].BlockWriteBytes, sample.BlockWriteBytes)
+			out.MemoryFailcntDelta += counterDelta(window[i-1].MemoryFailcnt, sample.MemoryFailcnt)
+		}
+	}
+	n := float64(len(window))
+	out.CPUPercentMean /= n
+	out.CPUThrottledPercentMean /= n
+	sort.Float64s(cpu)
+	out.CPUPercentP95 = Quantile(cpu, 0.95)
+	out.MemoryMeanBytes = memoryTotal / n
+	out.PidsMean = pidsTotal / n
+
+	out.MemoryGrowthBytesPerSecond = memorySlope(window)
+	duration := time.Duration(out.DurationSeconds * float64(time.Second))
+	if duration >= LeakMinDuration && out.MemoryMeanBytes > 0 {
+		out.PossibleLeak = out.MemoryGrowthBytesPerSecond*out.DurationSeconds > LeakMinGrowth*out.MemoryMeanBytes
+	}
+	if requests > 0 {
+		out.NetworkBytesPerRequest = float64(out.NetworkRxBytes+out.NetworkTxBytes) / float64(requests)
+	}
+	return out
+}
+
+// samplePeriod is the stage a sample was taken in, TimelineIdle between
+// stages, or "" during the other phases.
+func samplePeriod(sample StatsSample) string {
+	switch sample.Phase {
+	case TimelineStage:
+		return sample.Stage
+	case TimelineIdle:
+		return TimelineIdle
+	}
+	return ""
+}
+
+func counterDelta(previous, current uint64) uint64 {
+	if current < previous {
+		return current
+	}
+	return current - previous
+}
+
+// memorySlope fits memory usage against time by least squares and returns
+// bytes per second.
+func memorySlope(samples []StatsSample) float64 {
+	if len(samples) < 2 {
+		return 0
+	}
+	origin := samples[0].TimestampUTC
+	var sumX, sumY float64
+	for _, sample := range samples {
+		sumX += sample.TimestampUTC.Sub(origin).Seconds()
+		sumY += float64(sample.MemoryUsageBytes)
+	}
+	n := float64(len(samples))
+	meanX, meanY := sumX/n, sumY/n
+	var covariance, variance float64
+	for _, sample := range samples {
+		dx := sample.TimestampUTC.Sub(origin).Seconds() - meanX
+		covariance += dx * (float64(sample.MemoryUsageBytes) - meanY)
+		variance += dx * dx
+	}
+	if variance == 0 {
+		return 0
+	}
+	return covariance / variance
+}
@@ -0,0 +1,99 @@
+package summary
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// SeriesFile is the per-interval time series of a stage, one JSON sample
+// per line, written into the stage output directory while the stage runs.
+const SeriesFile = "series.jsonl"
+
+// SeriesSample covers one interval of a stage. Intervals end on multiples of
+// the interval on the UTC wall clock the stats samples are taken on, so
+// both streams share one time axis; the first and last interval of a stage
+// are shorter.
+type SeriesSample struct {
+	// TimestampUTC is the start of the interval.
+	TimestampUTC    time.Time `json:"timestampUtc"`
+	Stage           string    `json:"stage"`
+	DurationSeconds float64   `json:"durationSeconds"`
+	// Requests counts the requests completed in the interval, including
+	// those that failed.
+	Requests          int64   `json:"requests"`
+	RequestsPerSecond float64 `json:"requestsPerSecond"`
+	Non2xx3xx         int64   `json:"non2xx3xxResponses"`
+	SocketErrors      int64   `json:"socketErrors"`
+	// Latency is only set by executors that time each request, and only
+	// for intervals with at least one response.
+	Latency *SeriesLatency `json:"latency,omitempty"`
+	// Histogram holds the latency buckets of the interval, so intervals can
+	// be merged (see LatencyHistogram.RecordBucket).
+	Histogram []HistogramBucket `json:"histogram,omitempty"`
+}
+
+// SeriesLatency is the latency distribution of one interval.
+type SeriesLatency struct {
+	P50Millis float64 `json:"p50Millis"`
+	P90Millis float64 `json:"p90Millis"`
+	P99Millis float64 `json:"p99Millis"`
+	MaxMillis float64 `json:"maxMillis"`
+}
+
+// LoadSeries reads the series of every stage of a harness run directory,
+// sorted by time. Stages without a series are skipped.
+func LoadSeries(runDir string) ([]SeriesSample, error) {
+	paths, err := filepath.Glob(filepath.Join(runDir, "wrk2-results", "*", SeriesFile))
+	if err != nil {
+		return nil, err
+	}
+	var samples []SeriesSample
+	for _, path := range paths {
+		stage, err := LoadSeriesFile(path)
+		if err != nil {
+			return nil, err
+		}
+		samples = append(samples, stage...)
+	}
+	sort.SliceStable(samples, func(i, j int) bool { return samples[i].TimestampUTC.Before(samples[j].TimestampUTC) })
+	return samples, nil
+}
+
+// LoadSeriesFile reads one series file in order. A missing file yields no
+// samples; broken lines are skipped, as for stats streams.
+func LoadSeriesFile(path string) ([]SeriesSample, error) {
+	file, err := os.Open(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to open %q: %w", path, err)
+	}
+	defer file.Close()
+
+	var samples []SeriesSample
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 0, 64*1024), 4*1024*1024)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" {
+			continue
+		}
+		var sample SeriesSample
+		if err := json.Unmarshal([]byte(line), &sample); err != nil {
+			continue
+		}
+		samples = append(samples, sample)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read %q: %w", path, err)
+	}
+	return samples, nil
+}
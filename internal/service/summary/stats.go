@@ -0,0 +1,122 @@
+package summary
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// StatsFile is the service container stats stream in a harness run
+// directory, one JSON sample per line.
+const StatsFile = "benchmark-container-stats.jsonl"
+
+// ContainerStatsDir holds the stats streams of the other compose containers
+// and of the wrk2 container of every stage, one JSONL file per container.
+const ContainerStatsDir = "container-stats"
+
+// Roles a stats sample is tagged with.
+const (
+	// RoleService is the benchmarked service.
+	RoleService = "service"
+	// RoleDependency is any other container of the compose project.
+	RoleDependency = "dependency"
+	// RoleLoadGenerator is the wrk2 container of a stage.
+	RoleLoadGenerator = "load-generator"
+)
+
+// StatsSample is the subset of a stats line used for analysis.
+type StatsSample struct {
+	TimestampUTC     time.Time `json:"timestampUtc"`
+	Service          string    `json:"service,omitempty"`
+	Role             string    `json:"role,omitempty"`
+	Phase            string    `json:"phase,omitempty"`
+	Stage            string    `json:"stage,omitempty"`
+	CPUPercent       float64   `json:"cpuPercent"`
+	MemoryUsageBytes uint64    `json:"memoryUsageBytes"`
+	MemoryLimitBytes uint64    `json:"memoryLimitBytes"`
+	NetworkRxBytes   uint64    `json:"networkRxBytes"`
+	NetworkTxBytes   uint64    `json:"networkTxBytes"`
+	Pids             uint64    `json:"pids"`
+	// CPUThrottledPercent is the share of CFS periods since the previous
+	// sample in which the container was throttled.
+	CPUThrottledPercent float64 `json:"cpuThrottledPercent"`
+	// Cumulative block I/O and memory failcnt (OOM events on cgroup v2).
+	BlockReadBytes  uint64 `json:"blockReadBytes"`
+	BlockWriteBytes uint64 `json:"blockWriteBytes"`
+	MemoryFailcnt   uint64 `json:"memoryFailcnt"`
+}
+
+// LoadStatsSamples reads a stats JSONL file sorted by time. A missing file
+// yields no samples; broken lines (a collector interrupted mid-write) are
+// skipped.
+func LoadStatsSamples(path string) ([]StatsSample, error) {
+	file, err := os.Open(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to open %q: %w", path, err)
+	}
+	defer file.Close()
+
+	var samples []StatsSample
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 0, 64*1024), 4*1024*1024)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" {
+			continue
+		}
+		var sample StatsSample
+		if err := json.Unmarshal([]byte(line), &sample); err != nil {
+			continue
+		}
+		samples = append(samples, sample)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read %q: %w", path, err)
+	}
+	sort.SliceStable(samples, func(i, j int) bool { return samples[i].TimestampUTC.Before(samples[j].TimestampUTC) })
+	return samples, nil
+}
+
+// SamplesBetween returns the samples taken within [from, to].
+func SamplesBetween(samples []StatsSample, from, to time.Time) []StatsSample {
+	var window []StatsSample
+	for _, sample := range samples {
+		if !sample.TimestampUTC.Before(from) && !sample.TimestampUTC.After(to) {
+			window = append(window, sample)
+		}
+	}
+	return window
+}
+
+// LoadStatsStreams reads the service stream and every stream in
+// ContainerStatsDir of a harness run directory, service first.
+func LoadStatsStreams(runDir string) ([][]StatsSample, error) {
+	paths := []string{filepath.Join(runDir, StatsFile)}
+	others, err := filepath.Glob(filepath.Join(runDir, ContainerStatsDir, "*.jsonl"))
+	if err != nil {
+		return nil, err
+	}
+	sort.Strings(others)
+	paths = append(paths, others...)
+
+	var streams [][]StatsSample
+	for _, path := range paths {
+		samples, err := LoadStatsSamples(path)
+		if err != nil {
+			return nil, err
+		}
+		if len(samples) > 0 {
+			streams = append(streams, samples)
+		}
+	}
+	return streams, nil
+}
@@ -0,0 +1,238 @@
+package summary
+
+import (
+	"math"
+	"time"
+)
+
+const (
+	// DetectorMSER5 is the steady-state detector: the marginal standard
+	// error rule applied to batch means of 5 observations.
+	DetectorMSER5 = "mser-5"
+	// mserBatch is the batch size of MSER-5.
+	mserBatch = 5
+	// mserMinBatches is the shortest signal MSER-5 is run on.
+	mserMinBatches = 4
+)
+
+// Steady-state signals of a stage.
+const (
+	SignalThroughput = "throughput"
+	SignalLatencyP50 = "latency-p50"
+	SignalLatencyP99 = "latency-p99"
+	SignalServiceCPU = "service-cpu"
+)
+
+// SteadyState is the steady-state detection of one stage. Each signal is
+// truncated separately; the stage is steady once every signal is.
+type SteadyState struct {
+	Detector string `json:"detector"`
+	// Detected is false when a signal did not settle within the first half
+	// of the stage, or was too short to tell.
+	Detected bool `json:"detected"`
+	// Undetermined is set when the stage has no request series, so the
+	// service CPU alone cannot tell when the requests settled; Reason
+	// says why.
+	Undetermined bool   `json:"undetermined,omitempty"`
+	Reason       string `json:"reason,omitempty"`
+	// DecidedBy names the signals the decision was based on.
+	DecidedBy []string `json:"decidedBy"`
+	// TimeToSteadyStateSeconds runs from the stage start to SteadyFrom.
+	TimeToSteadyStateSeconds float64             `json:"timeToSteadyStateSeconds,omitempty"`
+	SteadyFrom               *time.Time          `json:"steadyFrom,omitempty"`
+	Signals                  []SteadyStateSignal `json:"signals"`
+	// Summary covers only the steady part of the stage.
+	Summary *SteadyStateSummary `json:"summary,omitempty"`
+}
+
+// SteadyStateSignal is the MSER-5 truncation of one signal.
+type SteadyStateSignal struct {
+	Name     string `json:"name"`
+	Samples  int    `json:"samples"`
+	Detected bool   `json:"detected"`
+	// Truncated is the number of leading samples MSER-5 dropped, and
+	// SecondsFromStart the stage time of the first sample kept.
+	Truncated        int     `json:"truncated"`
+	SecondsFromStart float64 `json:"secondsFromStart"`
+}
+
+// SteadyStateSummary is the steady part of a stage. Latency is merged from
+// the interval histograms, so it is only present when the executor timed
+// every request.
+type SteadyStateSummary struct {
+	DurationSeconds   float64         `json:"durationSeconds"`
+	Requests          int64           `json:"requests"`
+	RequestsPerSecond float64         `json:"requestsPerSecond"`
+	Non2xx3xx         int64           `json:"non2xx3xxResponses"`
+	SocketErrors      int64           `json:"socketErrors"`
+	Latency           *LatencySummary `json:"latency,omitempty"`
+	// Service CPU and memory over the stats samples of the steady part.
+	CPUPercentMean  float64 `json:"cpuPercentMean,omitempty"`
+	MemoryPeakBytes uint64  `json:"memoryPeakBytes,omitempty"`
+}
+
+// steadyStatePercentiles are the percentiles of SteadyStateSummary.Latency.
+var steadyStatePercentiles = []float64{50, 75, 90, 99, 99.9, 100}
+
+// MSER5 returns how many leading values to drop so that the rest has the
+// smallest marginal standard error, computed on batch means of 5. The
+// truncation is only trusted within the first half of the batches; ok is
+// false otherwise, or when there are fewer than 20 values.
+func MSER5(values []float64) (truncate int, ok bool) {
+	batches := len(values) / mserBatch
+	if batches < mserMinBatches {
+		return 0, false
+	}
+	means := make([]float64, batches)
+	for i := range means {
+		var sum float64
+		for _, v := range values[i*mserBatch : (i+1)*mserBatch] {
+			sum += v
+		}
+		means[i] = sum / mserBatch
+	}
+	best, bestD := math.Inf(1), 0
+	for d := 0; d <= batches-2; d++ {
+		rest := means[d:]
+		var sum float64
+		for _, v := range rest {
+			sum += v
+		}
+		mean := sum / float64(len(rest))
+		var squares float64
+		for _, v := range rest {
+			squares += (v - mean) * (v - mean)
+		}
+		k := float64(len(rest))
+		if stat := squares / (k * k); stat < best {
+			best, bestD = stat, d
+		}
+	}
+	if bestD > batches/2 {
+		return 0, false
+	}
+	return bestD * mserBatch, true
+}
+
+// DetectSteadyState runs MSER-5 on the throughput and latency series of the
+// stage that started at started and on the service CPU of the stats samples
+// taken during it. It returns nil when there is no signal at all. Without a
+// request series the CPU signal is still reported, but the result is
+// undetermined.
+func DetectSteadyState(started time.Time, series []SeriesSample, stats []StatsSample) *SteadyState {
+	type signal struct {
+		name   string
+		times  []time.Time
+		values []float64
+	}
+	var signals []signal
+	throughput := signal{name: SignalThroughput}
+	p50 := signal{name: SignalLatencyP50}
+	p99 := signal{name: SignalLatencyP99}
+	for _, sample := range series {
+		throughput.times = append(throughput.times, sample.TimestampUTC)
+		throughput.values = append(throughput.values, sample.RequestsPerSecond)
+		if sample.Latency != nil {
+			p50.times = append(p50.times, sample.TimestampUTC)
+			p50.values = append(p50.values, sample.Latency.P50Millis)
+			p99.times = append(p99.times, sample.TimestampUTC)
+			p99.values = append(p99.values, sample.Latency.P99Millis)
+		}
+	}
+	cpu := signal{name: SignalServiceCPU}
+	for _, sample := range stats {
+		if sample.Role != "" && sample.Role != RoleService {
+			continue
+		}
+		cpu.times = append(cpu.times, sample.TimestampUTC)
+		cpu.values = append(cpu.values, sample.CPUPercent)
+	}
+	for _, s := range []signal{throughput, p50, p99, cpu} {
+		if len(s.values) > 0 {
+			signals = append(signals, s)
+		}
+	}
+	if len(signals) == 0 {
+		return nil
+	}
+
+	result := &SteadyState{Detector: DetectorMSER5, Detected: true, DecidedBy: []string{}}
+	if len(throughput.values) == 0 {
+		result.Detected = false
+		result.Undetermined = true
+		result.Reason = "no request series; the service CPU alone does not show when requests settle"
+	}
+	var steadyFrom time.Time
+	for _, s := range signals {
+		truncate, ok := MSER5(s.values)
+		entry := SteadyStateSignal{Name: s.name, Samples: len(s.values), Detected: ok}
+		if ok {
+			entry.Truncated = truncate
+			entry.SecondsFromStart = max(s.times[truncate].Sub(started).Seconds(), 0)
+			if s.times[truncate].After(steadyFrom) {
+				steadyFrom = s.times[truncate]
+			}
+		} else {
+			result.Detected = false
+		}
+		result.Signals = append(result.Signals, entry)
+		if !result.Undetermined {
+			result.DecidedBy = append(result.DecidedBy, s.name)
+		}
+	}
+	if !result.Detected {
+		return result
+	}
+	if steadyFrom.Before(started) {
+		steadyFrom = started
+	}
+	result.SteadyFrom = &steadyFrom
+	result.TimeToSteadyStateSeconds = steadyFrom.Sub(started).Seconds()
+	result.Summary = summarizeSteadyState(steadyFrom, series, stats)
+	return result
+}
+
+func summarizeSteadyState(from time.Time, series []SeriesSample, stats []StatsSample) *SteadyStateSummary {
+	summary := &SteadyStateSummary{}
+	var latency LatencyHistogram
+	maxMillis := 0.0
+	for _, sample := range series {
+		if sample.TimestampUTC.Before(from) {
+			continue
+		}
+		summary.DurationSeconds += sample.DurationSeconds
+		summary.Requests += sample.Requests
+		summary.Non2xx3xx += sample.Non2xx3xx
+		summary.SocketErrors += sample.SocketErrors
+		for _, bucket := range sample.Histogram {
+			latency.RecordBucket(bucket)
+		}
+		if sample.Latency != nil {
+			maxMillis = max(maxMillis, sample.Latency.MaxMillis)
+		}
+	}
+	if summary.DurationSeconds > 0 {
+		summary.RequestsPerSecond = float64(summary.Requests) / summary.DurationSeconds
+	}
+	if latency.Count() > 0 {
+		merged := latency.Summary(steadyStatePercentiles)
+		// The buckets lose the exact maximum; the intervals kept it.
+		merged.MaxMillis = maxMillis
+		merged.Percentiles[len(merged.Percentiles)-1].LatencyMillis = maxMillis
+		summary.Latency = &merged
+	}
+	var cpu float64
+	var samples int
+	for _, sample := range stats {
+		if sample.TimestampUTC.Before(from) || (sample.Role != "" && sample.Role != RoleService) {
+			continue
+		}
+		cpu += sample.CPUPercent
+		samples++
+		summary.MemoryPeakBytes = max(summary.MemoryPeakBytes, sample.MemoryUsageBytes)
+	}
+	if samples > 0 {
+		summary.CPUPercentMean = cpu / float64(samples)
+	}
+	return summary
+}
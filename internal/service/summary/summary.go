@@ -0,0 +1,280 @@
+// Package summary defines the machine-readable result files written by the
+// harness (stage-summary.json, run-summary.json, cold-start.json) and parses
+// wrk2 output into them.
+//
+// All files carry a schemaVersion. Fields are only ever added within a
+// version; renaming or removing a field, or changing its unit, bumps the
+// version.
+package summary
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+)
+
+const (
+	// StageSummarySchema identifies the stage-summary.json layout.
+	StageSummarySchema = "slsbench.stage-summary/v1"
+	// RunSummarySchema identifies the run-summary.json layout.
+	RunSummarySchema = "slsbench.run-summary/v1"
+
+	// StageSummaryFile is written into every stage output directory.
+	StageSummaryFile = "stage-summary.json"
+	// RunSummaryFile is written into the harness run directory.
+	RunSummaryFile = "run-summary.json"
+)
+
+// Run status values.
+const (
+	StatusCompleted = "completed"
+	StatusFailed    = "failed"
+)
+
+// StageSummary is the result of one stage.
+type StageSummary struct {
+	SchemaVersion string `json:"schemaVersion"`
+	Stage         string `json:"stage"`
+	Executor      string `json:"executor"`
+	Wrk2Params    string `json:"wrk2params"`
+	TargetRate    int    `json:"targetRate"`
+	TargetSeconds int    `json:"targetDurationSeconds"`
+	// WarmupSeconds ran before TargetSeconds; its requests are not measured.
+	WarmupSeconds int `json:"warmupSeconds,omitempty"`
+	// RateSegments is set for a load that changes its rate, in which case
+	// TargetRate is the mean rate.
+	RateSegments []RateSegment `json:"rateSegments,omitempty"`
+	StartedAt    time.Time     `json:"startedAt"`
+	FinishedAt   time.Time     `json:"finishedAt"`
+	ExitCode     int64         `json:"exitCode"`
+	// Status is StatusFailed when wrk2 exited non-zero or a guard rail
+	// stopped the stage early; Error says which.
+	Status string `json:"status,omitempty"`
+	Error  string `json:"error,omitempty"`
+	// Wrk2 holds the parsed wrk2 report; nil when the output could not be
+	// parsed, in which case ParseError explains why.
+	Wrk2       *Wrk2Result `json:"wrk2,omitempty"`
+	ParseError string      `json:"parseError,omitempty"`
+	// ExecutorStats holds every JSON document the flow executor wrote to its
+	// stats directory, keyed by file name.
+	ExecutorStats map[string]json.RawMessage `json:"executorStats,omitempty"`
+	// ExecutorFiles lists the other (non-JSON) files found there.
+	ExecutorFiles []string `json:"executorFiles,omitempty"`
+	// Activator is set when the stage ran behind the scale-to-zero
+	// activator.
+	Activator *ActivatorSummary `json:"activator,omitempty"`
+	// Invalid is set when a Docker event policy rejected the stage, e.g.
+	// because a container was OOM-killed during it; InvalidReasons lists
+	// the events.
+	Invalid        bool     `json:"invalid,omitempty"`
+	InvalidReasons []string `json:"invalidReasons,omitempty"`
+	// SteadyState is the time to steady state and the steady part of the
+	// stage, detected from series.jsonl and the service stats.
+	SteadyState *SteadyState `json:"steadyState,omitempty"`
+	// ThinkTime is set for stages with think time between flow steps.
+	ThinkTime *ThinkTimeSummary `json:"thinkTime,omitempty"`
+}
+
+// RateSegment is one part of a load profile, rising or falling linearly
+// from FromRate to ToRate requests per second.
+type RateSegment struct {
+	DurationSeconds float64 `json:"durationSeconds"`
+	FromRate        int     `json:"fromRate"`
+	ToRate          int     `json:"toRate"`
+}
+
+// ThinkTimeSummary is the pacing of a stage with think time. TargetRate
+// stays the rate requests are scheduled at, but a connection does not send
+// the next step of an iteration before the pause after the previous
+// response, so the offered rate can stay below it.
+type ThinkTimeSummary struct {
+	Seed int64 `json:"seed"`
+	// Pauses counts the drawn think times and MeanMillis is their mean.
+	Pauses     int64   `json:"pauses"`
+	MeanMillis float64 `json:"meanMillis"`
+	// OfferedRequestsPerSecond is the rate requests were due at.
+	OfferedRequestsPerSecond float64 `json:"offeredRequestsPerSecond"`
+}
+
+// FirstResponse summarizes the time-to-first-response measurement.
+type FirstResponse struct {
+	TargetURL        string    `json:"targetUrl"`
+	StartedAt        time.Time `json:"startedAt"`
+	FinishedAt       time.Time `json:"finishedAt"`
+	DurationSeconds  float64   `json:"durationSeconds"`
+	DurationMillis   int64     `json:"durationMillis"`
+	Attempts         int       `json:"attempts"`
+	StatusCode       int       `json:"statusCode"`
+	ResolvedPathUsed string    `json:"resolvedPathUsed"`
+}
+
+// RunSummary is the result of a whole harness run. Stages are listed in
+// execution order.
+type RunSummary struct {
+	SchemaVersion string         `json:"schemaVersion"`
+	Status        string         `json:"status"`
+	Error         string         `json:"error,omitempty"`
+	StartedAt     time.Time      `json:"startedAt"`
+	FinishedAt    time.Time      `json:"finishedAt"`
+	FlowPath      string         `json:"flowPath"`
+	ServiceName   string         `json:"serviceName"`
+	Port          int            `json:"port"`
+	ComposeFile   string         `json:"composeFile"`
+	ComposeName   string         `json:"composeProject"`
+	ProbeBodies   string         `json:"probeBodiesPath"`
+	FirstResponse *FirstResponse `json:"firstResponse,omitempty"`
+	Stages        []StageSummary `json:"stages"`
+	// Activator covers the whole run when the activator was enabled.
+	Activator *ActivatorSummary `json:"activator,omitempty"`
+}
+
+// Stage returns the summary of the named stage, or nil.
+func (r *RunSummary) Stage(name string) *StageSummary {
+	for i := range r.Stages {
+		if r.Stages[i].Stage == name {
+			return &r.Stages[i]
+		}
+	}
+	return nil
+}
+
+// WriteJSON writes v as indented JSON to path.
+func WriteJSON(path string, v any) error {
+	serialized, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, serialized, 0o644)
+}
+
+// LoadRunSummary reads run-summary.json from a harness run directory.
+func LoadRunSummary(runDir string) (*RunSummary, error) {
+	path := filepath.Join(runDir, RunSummaryFile)
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %q: %w", path, err)
+	}
+	var run RunSummary
+	if err := json.Unmarshal(raw, &run); err != nil {
+		return nil, fmt.Errorf("failed to parse %q: %w", path, err)
+	}
+	if run.SchemaVersion != RunSummarySchema {
+		return nil, fmt.Errorf("%q has unsupported schemaVersion %q (want %q)", path, run.SchemaVersion, RunSummarySchema)
+	}
+	return &run, nil
+}
+
+// CollectExecutorStats reads the files an executor left in dir. JSON files
+// are returned verbatim by name; everything else is only listed. Files named
+// in skip (the harness' own artifacts) are ignored.
+func CollectExecutorStats(dir string, skip ...string) (map[string]json.RawMessage, []string, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, nil, err
+	}
+	skipped := make(map[string]struct{}, len(skip))
+	for _, name := range skip {
+		skipped[name] = struct{}{}
+	}
+	stats := map[string]json.RawMessage{}
+	var files []string
+	for _, entry := range entries {
+		if entry.IsDir() {
+			continue
+		}
+		name := entry.Name()
+		if _, ok := skipped[name]; ok {
+			continue
+		}
+		if filepath.Ext(name) == ".json" {
+			raw, err := os.ReadFile(filepath.Join(dir, name))
+			if err == nil && json.Valid(raw) {
+				stats[name] = json.RawMessage(raw)
+				continue
+			}
+		}
+		files = append(files, name)
+	}
+	if len(stats) == 0 {
+		stats = nil
+	}
+	return stats, files, nil
+}
+
+// IsRunDir reports whether dir looks like a harness result directory.
+func IsRunDir(dir string) bool {
+	for _, name := range []string{RunSummaryFile, "first_request_result.json", "wrk2-results"} {
+		if _, err := os.Stat(filepath.Join(dir, name)); err == nil {
+			return true
+		}
+	}
+	return false
+}
+
+// LoadRunDir reads run-summary.json from a harness result directory. Result
+// directories written before run summaries existed are reconstructed from
+// first_request_result.json and the per-stage stage-summary.json or
+// wrk2-output.txt files; stage timings are unknown for those.
+func LoadRunDir(dir string) (*RunSummary, error) {
+	run, err := LoadRunSummary(dir)
+	if err == nil {
+		return run, nil
+	}
+	if !errors.Is(err, os.ErrNotExist) {
+		return nil, err
+	}
+
+	run = &RunSummary{SchemaVersion: RunSummarySchema}
+	if raw, err := os.ReadFile(filepath.Join(dir, "first_request_result.json")); err == nil {
+		var first FirstResponse
+		if err := json.Unmarshal(raw, &first); err == nil {
+			run.FirstResponse = &first
+		}
+	}
+	stageDirs, _ := filepath.Glob(filepath.Join(dir, "wrk2-results", "*"))
+	sort.Strings(stageDirs)
+	for _, stageDir := range stageDirs {
+		stage := StageSummary{Stage: filepath.Base(stageDir)}
+		if raw, err := os.ReadFile(filepath.Join(stageDir, StageSummaryFile)); err == nil {
+			if err := json.Unmarshal(raw, &stage); err != nil {
+				return nil, fmt.Errorf("failed to parse %s: %w", filepath.Join(stageDir, StageSummaryFile), err)
+			}
+		} else if raw, err := os.ReadFile(filepath.Join(stageDir, "wrk2-output.txt")); err == nil {
+			if stage.Wrk2, err = ParseWrk2Output(string(raw)); err != nil {
+				stage.ParseError = err.Error()
+			}
+		} else {
+			continue
+		}
+		run.Stages = append(run.Stages, stage)
+	}
+	return run, nil
+}
+
+// FindRunDirs returns every harness result directory at or below root, in
+// lexical order. Directories inside a result directory are not searched.
+func FindRunDirs(root string) ([]string, error) {
+	var dirs []string
+	err := filepath.WalkDir(root, func(path string, entry os.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if !entry.IsDir() {
+			return nil
+		}
+		if IsRunDir(path) {
+			dirs = append(dirs, path)
+			return filepath.SkipDir
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to search %q for harness results: %w", root, err)
+	}
+	sort.Strings(dirs)
+	return dirs, nil
+}
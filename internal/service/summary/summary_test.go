@@ -0,0 +1,340 @@
+package summary
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+	"time"
+)
+
+func TestParseWrk2Output_LatencyReport(t *testing.T) {
+	raw, err := os.ReadFile(filepath.Join("testdata", "wrk2-latency.txt"))
+	if err != nil {
+		t.Fatalf("failed to read fixture: %v", err)
+	}
+	result, err := ParseWrk2Output(string(raw))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if result.Threads != 2 || result.Connections != 16 {
+		t.Fatalf("unexpected threads/connections: %d/%d", result.Threads, result.Connections)
+	}
+	if result.Requests != 29986 || result.DurationSeconds != 30 {
+		t.Fatalf("unexpected totals: %d requests in %.2fs", result.Requests, result.DurationSeconds)
+	}
+	if result.BytesRead != 11366563 { // 10.84MB
+		t.Fatalf("unexpected bytes read: %d", result.BytesRead)
+	}
+	if result.RequestsPerSecond != 999.52 || result.TransferBytesPerSecond != 370.12*1024 {
+		t.Fatalf("unexpected rates: %.2f req/s %.2f B/s", result.RequestsPerSecond, result.TransferBytesPerSecond)
+	}
+	if result.SocketErrors != (SocketErrors{Read: 2, Timeout: 7}) || result.SocketErrors.Total() != 9 {
+		t.Fatalf("unexpected socket errors: %+v", result.SocketErrors)
+	}
+	if result.Non2xx3xx != 41 {
+		t.Fatalf("unexpected non-2xx count: %d", result.Non2xx3xx)
+	}
+
+	latency := result.Latency
+	// The HdrHistogram footer overrides the rounded thread stats line.
+	if latency.MeanMillis != 1.368 || latency.StdDevMillis != 0.613 || latency.MaxMillis != 18.208 || latency.TotalCount != 29984 {
+		t.Fatalf("unexpected latency summary: %+v", latency)
+	}
+	if len(latency.Percentiles) != 8 || len(latency.Spectrum) != 8 {
+		t.Fatalf("expected 8 percentiles and 8 spectrum rows, got %d and %d", len(latency.Percentiles), len(latency.Spectrum))
+	}
+	if p99, ok := latency.Percentile(99); !ok || p99 != 3.41 {
+		t.Fatalf("unexpected p99: %v %v", p99, ok)
+	}
+	// 95 is not in the distribution block and falls back to the spectrum.
+	if p95, ok := latency.Percentile(95); !ok || p95 != 3.411 {
+		t.Fatalf("unexpected p95: %v %v", p95, ok)
+	}
+	if result.UncorrectedLatency != nil {
+		t.Fatalf("expected no uncorrected latency without -U")
+	}
+}
+
+func TestParseWrk2Output_RejectsOutputWithoutSummary(t *testing.T) {
+	if _, err := ParseWrk2Output("unable to connect to petstore:8080 Connection refused\n"); err == nil {
+		t.Fatalf("expected an error")
+	}
+}
+
+func TestParseLatencyMillis_Units(t *testing.T) {
+	cases := map[string]float64{"500.00us": 0.5, "1.50ms": 1.5, "2.00s": 2000, "1.00m": 60000}
+	for input, want := range cases {
+		got, err := parseLatencyMillis(input)
+		if err != nil || got != want {
+			t.Fatalf("parseLatencyMillis(%q) = %v, %v; want %v", input, got, err, want)
+		}
+	}
+}
+
+func TestCollectExecutorStats_SplitsJSONFromOtherFiles(t *testing.T) {
+	dir := t.TempDir()
+	mustWrite(t, filepath.Join(dir, "flow-stats.json"), `{"iterations": 12}`)
+	mustWrite(t, filepath.Join(dir, "broken.json"), `{`)
+	mustWrite(t, filepath.Join(dir, "non2xx.log"), "GET /x 500\n")
+	mustWrite(t, filepath.Join(dir, StageSummaryFile), `{}`)
+
+	stats, files, err := CollectExecutorStats(dir, StageSummaryFile)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(stats) != 1 || string(stats["flow-stats.json"]) != `{"iterations": 12}` {
+		t.Fatalf("unexpected stats: %v", stats)
+	}
+	if len(files) != 2 || files[0] != "broken.json" || files[1] != "non2xx.log" {
+		t.Fatalf("unexpected files: %v", files)
+	}
+}
+
+func TestLoadRunSummary_ChecksSchemaVersion(t *testing.T) {
+	dir := t.TempDir()
+	run := RunSummary{SchemaVersion: RunSummarySchema, Status: StatusCompleted, Stages: []StageSummary{{Stage: "s1"}}}
+	if err := WriteJSON(filepath.Join(dir, RunSummaryFile), run); err != nil {
+		t.Fatalf("failed to write summary: %v", err)
+	}
+	loaded, err := LoadRunSummary(dir)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if loaded.Stage("s1") == nil || loaded.Stage("s2") != nil {
+		t.Fatalf("unexpected stages: %+v", loaded.Stages)
+	}
+
+	run.SchemaVersion = "slsbench.run-summary/v0"
+	serialized, _ := json.Marshal(run)
+	mustWrite(t, filepath.Join(dir, RunSummaryFile), string(serialized))
+	if _, err := LoadRunSummary(dir); err == nil {
+		t.Fatalf("expected an error for an unsupported schema version")
+	}
+}
+
+func TestLoadRunDir_ReconstructsFromStageOutput(t *testing.T) {
+	dir := t.TempDir()
+	stageDir := filepath.Join(dir, "wrk2-results", "stage1")
+	if err := os.MkdirAll(stageDir, 0o755); err != nil {
+		t.Fatalf("failed to create stage dir: %v", err)
+	}
+	output := "  50 requests in 5.00s, 2.00KB read\nRequests/sec:     10.00\nTransfer/sec:    409.60B\n"
+	if err := os.WriteFile(filepath.Join(stageDir, "wrk2-output.txt"), []byte(output), 0o644); err != nil {
+		t.Fatalf("failed to write wrk2 output: %v", err)
+	}
+	run, err := LoadRunDir(dir)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(run.Stages) != 1 || run.Stages[0].Wrk2 == nil || run.Stages[0].Wrk2.Requests != 50 {
+		t.Fatalf("unexpected reconstructed run: %+v", run.Stages)
+	}
+}
+
+func TestFindRunDirs_StopsAtResultDirectories(t *testing.T) {
+	root := t.TempDir()
+	for _, dir := range []string{"rep-002/harness-result-b/wrk2-results/s1", "rep-001/harness-result-a/wrk2-results", "notes"} {
+		if err := os.MkdirAll(filepath.Join(root, dir), 0o755); err != nil {
+			t.Fatalf("failed to create %s: %v", dir, err)
+		}
+	}
+	dirs, err := FindRunDirs(root)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	want := []string{filepath.Join(root, "rep-001/harness-result-a"), filepath.Join(root, "rep-002/harness-result-b")}
+	if len(dirs) != 2 || dirs[0] != want[0] || dirs[1] != want[1] {
+		t.Fatalf("FindRunDirs = %v; want %v", dirs, want)
+	}
+}
+
+func TestSummarizePhases_PercentilesPerPhase(t *testing.T) {
+	var samples []ColdStartSample
+	for i := 1; i <= 11; i++ {
+		phases := map[string]float64{PhaseTotal: float64(i * 100)}
+		if i%2 == 0 {
+			phases[RequestPhase(1)] = float64(i)
+		}
+		samples = append(samples, ColdStartSample{Index: i, PhasesMillis: phases})
+	}
+	phases := SummarizePhases(samples, []string{PhaseCreate, PhaseTotal, RequestPhase(1)})
+	if len(phases) != 2 {
+		t.Fatalf("expected phases without samples to be left out, got %+v", phases)
+	}
+	total := phases[0]
+	if total.Phase != PhaseTotal || total.N != 11 || total.P50Millis != 600 || total.P90Millis != 1000 || total.MaxMillis != 1100 {
+		t.Fatalf("unexpected total phase: %+v", total)
+	}
+	if request := phases[1]; request.Phase != "request-1" || request.N != 5 || request.MeanMillis != 6 {
+		t.Fatalf("unexpected request phase: %+v", request)
+	}
+	if q := Quantile([]float64{10, 20}, 0.25); q != 12.5 {
+		t.Fatalf("Quantile interpolates to %v, want 12.5", q)
+	}
+}
+
+func TestSummarizeResources_StageWindowAndLeakSlope(t *testing.T) {
+	base := time.Date(2026, 5, 1, 12, 0, 0, 0, time.UTC)
+	var samples []StatsSample
+	// Ten minutes of "soak", memory growing 2 MiB per minute from 100 MiB,
+	// followed by idle samples that must not count.
+	for i := 0; i <= 10; i++ {
+		samples = append(samples, StatsSample{
+			TimestampUTC:     base.Add(time.Duration(i) * time.Minute),
+			Service:          "api",
+			Role:             RoleService,
+			Phase:            TimelineStage,
+			Stage:            "soak",
+			CPUPercent:       float64(i * 10),
+			MemoryUsageBytes: uint64(100+2*i) << 20,
+			NetworkRxBytes:   uint64(i * 1000),
+			NetworkTxBytes:   uint64(i * 500),
+			Pids:             uint64(20 + i%2),
+		})
+	}
+	samples = append(samples, StatsSample{TimestampUTC: base.Add(11 * time.Minute), Phase: TimelineIdle, MemoryUsageBytes: 1 << 30})
+
+	soak := SummarizeResources(samples, "soak", 1000)
+	if soak == nil || soak.Samples != 11 || soak.Service != "api" || soak.DurationSeconds != 600 {
+		t.Fatalf("unexpected window: %+v", soak)
+	}
+	if soak.CPUPercentMean != 50 || soak.CPUPercentMax != 100 || soak.CPUPercentP95 != 95 {
+		t.Fatalf("cpu mean/p95/max = %v/%v/%v", soak.CPUPercentMean, soak.CPUPercentP95, soak.CPUPercentMax)
+	}
+	if soak.MemoryPeakBytes != 120<<20 || soak.PidsMax != 21 {
+		t.Fatalf("peak memory/pids = %d/%d", soak.MemoryPeakBytes, soak.PidsMax)
+	}
+	if want := float64(2<<20) / 60; soak.MemoryGrowthBytesPerSecond < want*0.999 || soak.MemoryGrowthBytesPerSecond > want*1.001 {
+		t.Fatalf("slope = %v, want %v", soak.MemoryGrowthBytesPerSecond, want)
+	}
+	if !soak.PossibleLeak {
+		t.Fatalf("20 MiB growth over ten minutes not flagged")
+	}
+	if soak.NetworkRxBytes != 10000 || soak.NetworkBytesPerRequest != 15 {
+		t.Fatalf("network rx/bytes-per-request = %d/%v", soak.NetworkRxBytes, soak.NetworkBytesPerRequest)
+	}
+
+	idle := SummarizeResources(samples, TimelineIdle, 0)
+	if idle == nil || idle.Samples != 1 || idle.PossibleLeak || idle.NetworkBytesPerRequest != 0 {
+		t.Fatalf("unexpected idle window: %+v", idle)
+	}
+	if SummarizeResources(samples, "missing", 0) != nil {
+		t.Fatalf("expected nil for a period without samples")
+	}
+}
+
+func mustWrite(t *testing.T, path, content string) {
+	t.Helper()
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatalf("failed to write %s: %v", path, err)
+	}
+}
+
+func TestLatencyHistogram_ThreeSignificantDigits(t *testing.T) {
+	var h LatencyHistogram
+	for i := 1; i <= 100; i++ {
+		h.Record(time.Duration(i) * time.Millisecond)
+	}
+	h.Record(12345678 * time.Microsecond)
+
+	if got := h.Percentile(50); got != 51 {
+		t.Fatalf("p50 = %v, want 51 (nearest rank of 101)", got)
+	}
+	// 12345.678ms falls into the 12300ms bucket; the maximum stays exact.
+	buckets := h.Buckets()
+	if last := buckets[len(buckets)-1]; last.LatencyMillis != 12300 || last.Count != 1 {
+		t.Fatalf("last bucket = %+v", last)
+	}
+	latency := h.Summary([]float64{99, 100})
+	if latency.TotalCount != 101 || latency.MaxMillis != 12345.678 || latency.Percentiles[0].LatencyMillis != 100 || latency.Percentiles[1].LatencyMillis != 12345.678 {
+		t.Fatalf("summary = %+v", latency)
+	}
+	if latency.MeanMillis <= 50 || latency.StdDevMillis <= 0 {
+		t.Fatalf("mean=%v stddev=%v", latency.MeanMillis, latency.StdDevMillis)
+	}
+}
+
+func TestMSER5_TruncatesWarmup(t *testing.T) {
+	// 30 s of warm-up decaying from 100 ms to a noisy 10 ms plateau.
+	var values []float64
+	for i := 0; i < 120; i++ {
+		v := 10 + float64(i%3)
+		if i < 30 {
+			v += 90 * float64(30-i) / 30
+		}
+		values = append(values, v)
+	}
+	truncate, ok := MSER5(values)
+	if !ok || truncate < 20 || truncate > 35 {
+		t.Fatalf("truncate=%d ok=%v, want about 30", truncate, ok)
+	}
+	if _, ok := MSER5(values[:15]); ok {
+		t.Fatal("15 values are too few for MSER-5")
+	}
+	// A signal that keeps rising never settles within its first half.
+	var rising []float64
+	for i := 0; i < 60; i++ {
+		rising = append(rising, float64(i*i))
+	}
+	if truncate, ok := MSER5(rising); ok {
+		t.Fatalf("rising signal truncated at %d", truncate)
+	}
+}
+
+func TestDetectSteadyState_SummarizesSteadyPart(t *testing.T) {
+	start := time.Date(2026, 5, 1, 12, 0, 0, 0, time.UTC)
+	var series []SeriesSample
+	var stats []StatsSample
+	for i := 0; i < 60; i++ {
+		at := start.Add(time.Duration(i) * time.Second)
+		p99 := 20.0 + float64(i%2)
+		rps := 100.0
+		if i < 10 {
+			p99 += 200
+			rps = 40
+		}
+		series = append(series, SeriesSample{
+			TimestampUTC: at, Stage: "steady", DurationSeconds: 1, Requests: int64(rps), RequestsPerSecond: rps,
+			Latency:   &SeriesLatency{P50Millis: p99 / 2, P90Millis: p99, P99Millis: p99, MaxMillis: p99 + 5},
+			Histogram: []HistogramBucket{{LatencyMillis: p99 / 2, Count: int64(rps) - 1}, {LatencyMillis: p99, Count: 1}},
+		})
+		stats = append(stats, StatsSample{TimestampUTC: at, Role: RoleService, CPUPercent: 50, MemoryUsageBytes: uint64(100 + i)})
+		stats = append(stats, StatsSample{TimestampUTC: at, Role: RoleDependency, CPUPercent: 99})
+	}
+
+	steady := DetectSteadyState(start, series, stats)
+	if steady == nil || !steady.Detected || len(steady.Signals) != 4 {
+		t.Fatalf("steady state = %+v", steady)
+	}
+	if steady.TimeToSteadyStateSeconds < 10 || steady.TimeToSteadyStateSeconds > 15 {
+		t.Fatalf("time to steady state = %vs, want 10-15s", steady.TimeToSteadyStateSeconds)
+	}
+	got := steady.Summary
+	if got == nil || got.RequestsPerSecond != 100 || got.CPUPercentMean != 50 || got.MemoryPeakBytes != 159 {
+		t.Fatalf("steady summary = %+v", got)
+	}
+	if p99, ok := got.Latency.Percentile(99); !ok || p99 > 21 || got.Latency.MaxMillis != 26 {
+		t.Fatalf("steady latency = %+v", got.Latency)
+	}
+
+	if steady := DetectSteadyState(start, series[:12], nil); steady == nil || steady.Detected || steady.Summary != nil {
+		t.Fatalf("12 samples must not be steady: %+v", steady)
+	}
+	if !slices.Equal(steady.DecidedBy, []string{SignalThroughput, SignalLatencyP50, SignalLatencyP99, SignalServiceCPU}) {
+		t.Fatalf("decided by %v, want all four signals", steady.DecidedBy)
+	}
+	// wrk2-flow stages have stats but no request series.
+	cpuOnly := DetectSteadyState(start, nil, stats)
+	if cpuOnly == nil || cpuOnly.Detected || !cpuOnly.Undetermined || cpuOnly.Reason == "" || len(cpuOnly.DecidedBy) != 0 || cpuOnly.SteadyFrom != nil || cpuOnly.Summary != nil {
+		t.Fatalf("steady state from CPU alone = %+v, want undetermined", cpuOnly)
+	}
+	if len(cpuOnly.Signals) != 1 || cpuOnly.Signals[0].Name != SignalServiceCPU {
+		t.Fatalf("signals = %+v, want the CPU signal reported", cpuOnly.Signals)
+	}
+	if DetectSteadyState(start, nil, nil) != nil {
+		t.Fatal("no signal must yield nil")
+	}
+}
@@ -0,0 +1,39 @@
+package summary
+
+import "time"
+
+const (
+	// TimelineSchema identifies the timeline.json layout.
+	TimelineSchema = "slsbench.timeline/v1"
+	// TimelineFile is written into the harness run directory.
+	TimelineFile = "timeline.json"
+)
+
+// Timeline phases. Stats samples taken outside every phase are tagged
+// TimelineIdle.
+const (
+	TimelineComposeCreate  = "compose-create"
+	TimelineComposeStart   = "compose-start"
+	TimelineReadiness      = "readiness"
+	TimelineActivatorStart = "activator-start"
+	TimelineStage          = "stage"
+	TimelineArtifactCopy   = "artifact-copy"
+	TimelineTeardown       = "teardown"
+	TimelineIdle           = "idle"
+)
+
+// Timeline lists the phases of a harness run in the order they began.
+type Timeline struct {
+	SchemaVersion string          `json:"schemaVersion"`
+	Phases        []TimelineEntry `json:"phases"`
+}
+
+// TimelineEntry is one phase. Stage is only set for TimelineStage entries.
+// FinishedAt is zero when the run ended inside the phase.
+type TimelineEntry struct {
+	Phase      string    `json:"phase"`
+	Stage      string    `json:"stage,omitempty"`
+	StartedAt  time.Time `json:"startedAt"`
+	FinishedAt time.Time `json:"finishedAt,omitzero"`
+	Error      string    `json:"error,omitempty"`
+}
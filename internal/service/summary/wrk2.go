@@ -0,0 +1,270 @@
+package summary
+
+import (
+	"bufio"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// Wrk2Result is the parsed report printed by wrk2 when run with --latency.
+// Latencies are in milliseconds, sizes in bytes.
+type Wrk2Result struct {
+	Threads                int            `json:"threads"`
+	Connections            int            `json:"connections"`
+	Requests               int64          `json:"requests"`
+	DurationSeconds        float64        `json:"durationSeconds"`
+	BytesRead              int64          `json:"bytesRead"`
+	RequestsPerSecond      float64        `json:"requestsPerSecond"`
+	TransferBytesPerSecond float64        `json:"transferBytesPerSecond"`
+	Latency                LatencySummary `json:"latency"`
+	// UncorrectedLatency is only printed by wrk2 -U and ignores coordinated
+	// omission.
+	UncorrectedLatency *LatencySummary `json:"uncorrectedLatency,omitempty"`
+	SocketErrors       SocketErrors    `json:"socketErrors"`
+	Non2xx3xx          int64           `json:"non2xx3xxResponses"`
+}
+
+// LatencySummary is one latency distribution. Mean, StdDev, Max and
+// TotalCount come from the HdrHistogram footer when present and from the
+// thread stats line otherwise.
+type LatencySummary struct {
+	MeanMillis   float64       `json:"meanMillis"`
+	StdDevMillis float64       `json:"stdDevMillis"`
+	MaxMillis    float64       `json:"maxMillis"`
+	TotalCount   int64         `json:"totalCount"`
+	Percentiles  []Percentile  `json:"percentiles"`
+	Spectrum     []SpectrumRow `json:"spectrum,omitempty"`
+}
+
+// Percentile is one line of the "Latency Distribution" block, with
+// Percentile in the 0-100 range.
+type Percentile struct {
+	Percentile    float64 `json:"percentile"`
+	LatencyMillis float64 `json:"latencyMillis"`
+}
+
+// SpectrumRow is one line of the HdrHistogram "Detailed Percentile spectrum",
+// with Quantile in the 0-1 range.
+type SpectrumRow struct {
+	LatencyMillis float64 `json:"latencyMillis"`
+	Quantile      float64 `json:"quantile"`
+	TotalCount    int64   `json:"totalCount"`
+}
+
+// SocketErrors are the counters of wrk2's "Socket errors" line.
+type SocketErrors struct {
+	Connect int64 `json:"connect"`
+	Read    int64 `json:"read"`
+	Write   int64 `json:"write"`
+	Timeout int64 `json:"timeout"`
+}
+
+// Total returns the sum of all socket error counters.
+func (s SocketErrors) Total() int64 {
+	return s.Connect + s.Read + s.Write + s.Timeout
+}
+
+// Percentile returns the latency at percentile p (0-100). Exact entries of
+// the distribution block win; otherwise the spectrum is searched for the
+// first row at or above p.
+func (l *LatencySummary) Percentile(p float64) (float64, bool) {
+	for _, entry := range l.Percentiles {
+		if entry.Percentile == p {
+			return entry.LatencyMillis, true
+		}
+	}
+	for _, row := range l.Spectrum {
+		if row.Quantile*100 >= p {
+			return row.LatencyMillis, true
+		}
+	}
+	return 0, false
+}
+
+var (
+	wrk2ThreadsPattern      = regexp.MustCompile(`^(\d+) threads and (\d+) connections`)
+	wrk2ThreadLatency       = regexp.MustCompile(`^Latency\s+(\S+)\s+(\S+)\s+(\S+)`)
+	wrk2DistributionPattern = regexp.MustCompile(`^Latency Distribution \(HdrHistogram - (\w+) Latency`)
+	wrk2PercentilePattern   = regexp.MustCompile(`^([\d.]+)%\s+(\S+)$`)
+	wrk2SpectrumPattern     = regexp.MustCompile(`^([\d.]+)\s+([\d.]+)\s+(\d+)\s+\S+$`)
+	wrk2MeanPattern         = regexp.MustCompile(`^#\[Mean\s*=\s*([\d.]+),\s*StdDeviation\s*=\s*([\d.]+)\]`)
+	wrk2MaxPattern          = regexp.MustCompile(`^#\[Max\s*=\s*([\d.]+),\s*Total count\s*=\s*(\d+)\]`)
+	wrk2RequestsPattern     = regexp.MustCompile(`^(\d+) requests in (\S+), (\S+) read`)
+	wrk2SocketPattern       = regexp.MustCompile(`^Socket errors: connect (\d+), read (\d+), write (\d+), timeout (\d+)`)
+	wrk2Non2xxPattern       = regexp.MustCompile(`^Non-2xx or 3xx responses: (\d+)`)
+	wrk2RatePattern         = regexp.MustCompile(`^Requests/sec:\s+([\d.]+)`)
+	wrk2TransferPattern     = regexp.MustCompile(`^Transfer/sec:\s+(\S+)`)
+)
+
+// ParseWrk2Output parses the stdout of a wrk2 --latency run. Lines it does
+// not recognize (such as output of the flow executor's Lua script) are
+// skipped.
+func ParseWrk2Output(output string) (*Wrk2Result, error) {
+	result := &Wrk2Result{}
+	var current *LatencySummary
+	inSpectrum := false
+	sawTotals := false
+
+	scanner := bufio.NewScanner(strings.NewReader(output))
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" {
+			continue
+		}
+		var err error
+		switch {
+		case wrk2ThreadsPattern.MatchString(line):
+			m := wrk2ThreadsPattern.FindStringSubmatch(line)
+			result.Threads, _ = strconv.Atoi(m[1])
+			result.Connections, _ = strconv.Atoi(m[2])
+		case wrk2DistributionPattern.MatchString(line):
+			inSpectrum = false
+			if wrk2DistributionPattern.FindStringSubmatch(line)[1] == "Uncorrected" {
+				result.UncorrectedLatency = &LatencySummary{}
+				current = result.UncorrectedLatency
+			} else {
+				current = &result.Latency
+			}
+		case wrk2ThreadLatency.MatchString(line):
+			m := wrk2ThreadLatency.FindStringSubmatch(line)
+			if result.Latency.MeanMillis, err = parseLatencyMillis(m[1]); err != nil {
+				return nil, err
+			}
+			if result.Latency.StdDevMillis, err = parseLatencyMillis(m[2]); err != nil {
+				return nil, err
+			}
+			if result.Latency.MaxMillis, err = parseLatencyMillis(m[3]); err != nil {
+				return nil, err
+			}
+		case strings.HasPrefix(line, "Detailed Percentile spectrum"):
+			inSpectrum = current != nil
+		case current != nil && !inSpectrum && wrk2PercentilePattern.MatchString(line):
+			m := wrk2PercentilePattern.FindStringSubmatch(line)
+			p, _ := strconv.ParseFloat(m[1], 64)
+			latency, err := parseLatencyMillis(m[2])
+			if err != nil {
+				return nil, err
+			}
+			current.Percentiles = append(current.Percentiles, Percentile{Percentile: p, LatencyMillis: latency})
+		case inSpectrum && wrk2SpectrumPattern.MatchString(line):
+			m := wrk2SpectrumPattern.FindStringSubmatch(line)
+			latency, _ := strconv.ParseFloat(m[1], 64)
+			quantile, _ := strconv.ParseFloat(m[2], 64)
+			count, _ := strconv.ParseInt(m[3], 10, 64)
+			current.Spectrum = append(current.Spectrum, SpectrumRow{LatencyMillis: latency, Quantile: quantile, TotalCount: count})
+		case inSpectrum && wrk2MeanPattern.MatchString(line):
+			m := wrk2MeanPattern.FindStringSubmatch(line)
+			current.MeanMillis, _ = strconv.ParseFloat(m[1], 64)
+			current.StdDevMillis, _ = strconv.ParseFloat(m[2], 64)
+		case inSpectrum && wrk2MaxPattern.MatchString(line):
+			m := wrk2MaxPattern.FindStringSubmatch(line)
+			current.MaxMillis, _ = strconv.ParseFloat(m[1], 64)
+			current.TotalCount, _ = strconv.ParseInt(m[2], 10, 64)
+		case strings.HasPrefix(line, "#[") || strings.HasPrefix(line, "---"):
+			// Bucket configuration and separators carry no results; the
+			// separator also ends the spectrum.
+			if strings.HasPrefix(line, "---") {
+				inSpectrum = false
+				current = nil
+			}
+		case wrk2RequestsPattern.MatchString(line):
+			m := wrk2RequestsPattern.FindStringSubmatch(line)
+			result.Requests, _ = strconv.ParseInt(m[1], 10, 64)
+			if result.DurationSeconds, err = parseDurationSeconds(m[2]); err != nil {
+				return nil, err
+			}
+			bytesRead, err := parseBytes(m[3])
+			if err != nil {
+				return nil, err
+			}
+			result.BytesRead = int64(bytesRead)
+			sawTotals = true
+		case wrk2SocketPattern.MatchString(line):
+			m := wrk2SocketPattern.FindStringSubmatch(line)
+			result.SocketErrors.Connect, _ = strconv.ParseInt(m[1], 10, 64)
+			result.SocketErrors.Read, _ = strconv.ParseInt(m[2], 10, 64)
+			result.SocketErrors.Write, _ = strconv.ParseInt(m[3], 10, 64)
+			result.SocketErrors.Timeout, _ = strconv.ParseInt(m[4], 10, 64)
+		case wrk2Non2xxPattern.MatchString(line):
+			result.Non2xx3xx, _ = strconv.ParseInt(wrk2Non2xxPattern.FindStringSubmatch(line)[1], 10, 64)
+		case wrk2RatePattern.MatchString(line):
+			result.RequestsPerSecond, _ = strconv.ParseFloat(wrk2RatePattern.FindStringSubmatch(line)[1], 64)
+			sawTotals = true
+		case wrk2TransferPattern.MatchString(line):
+			if result.TransferBytesPerSecond, err = parseBytes(wrk2TransferPattern.FindStringSubmatch(line)[1]); err != nil {
+				return nil, err
+			}
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read wrk2 output: %w", err)
+	}
+	if !sawTotals {
+		return nil, fmt.Errorf("no wrk2 summary found in output")
+	}
+	if result.Latency.TotalCount == 0 {
+		result.Latency.TotalCount = result.Requests
+	}
+	return result, nil
+}
+
+// parseLatencyMillis converts a wrk2 latency such as "812.00us", "1.23ms",
+// "2.50s" or "1.02m" to milliseconds.
+func parseLatencyMillis(value string) (float64, error) {
+	units := []struct {
+		suffix string
+		millis float64
+	}{
+		{"us", 0.001},
+		{"ms", 1},
+		{"s", 1000},
+		{"m", 60 * 1000},
+		{"h", 60 * 60 * 1000},
+	}
+	for _, unit := range units {
+		if number, ok := strings.CutSuffix(value, unit.suffix); ok {
+			parsed, err := strconv.ParseFloat(number, 64)
+			if err != nil {
+				return 0, fmt.Errorf("invalid wrk2 latency %q", value)
+			}
+			return parsed * unit.millis, nil
+		}
+	}
+	return 0, fmt.Errorf("invalid wrk2 latency %q", value)
+}
+
+func parseDurationSeconds(value string) (float64, error) {
+	millis, err := parseLatencyMillis(value)
+	if err != nil {
+		return 0, fmt.Errorf("invalid wrk2 duration %q", value)
+	}
+	return millis / 1000, nil
+}
+
+// parseBytes converts a wrk2 size such as "12.34MB" or "421.12KB" (binary
+// multiples, as printed by wrk) to bytes.
+func parseBytes(value string) (float64, error) {
+	units := []struct {
+		suffix string
+		factor float64
+	}{
+		{"TB", 1 << 40},
+		{"GB", 1 << 30},
+		{"MB", 1 << 20},
+		{"KB", 1 << 10},
+		{"B", 1},
+	}
+	for _, unit := range units {
+		if number, ok := strings.CutSuffix(value, unit.suffix); ok {
+			parsed, err := strconv.ParseFloat(number, 64)
+			if err != nil {
+				return 0, fmt.Errorf("invalid wrk2 size %q", value)
+			}
+			return parsed * unit.factor, nil
+		}
+	}
+	return 0, fmt.Errorf("invalid wrk2 size %q", value)
+}
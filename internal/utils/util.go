@@ -5,6 +5,8 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"sort"
+	"strings"
 	"time"
 )
 
@@ -43,3 +45,24 @@ func CreateResultSubdirWithPrefix(path, prefix string) (string, error) {
 
 	return resultDir, nil
 }
+
+// LatestResultSubdir returns the most recent "<prefix>-<timestamp>" directory
+// directly under path, as created by CreateResultSubdirWithPrefix.
+func LatestResultSubdir(path, prefix string) (string, error) {
+	entries, err := os.ReadDir(path)
+	if err != nil {
+		return "", fmt.Errorf("failed to read directory %q: %w", path, err)
+	}
+	var names []string
+	for _, entry := range entries {
+		if entry.IsDir() && strings.HasPrefix(entry.Name(), prefix+"-") {
+			names = append(names, entry.Name())
+		}
+	}
+	if len(names) == 0 {
+		return "", fmt.Errorf("no %s-* directory found in %q", prefix, path)
+	}
+	// The timestamp layout sorts lexicographically in chronological order.
+	sort.Strings(names)
+	return filepath.Join(path, names[len(names)-1]), nil
+}